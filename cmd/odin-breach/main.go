@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/joho/godotenv"
+
+	"github.com/fzalvarez/odin-iam/internal/breached"
+)
+
+const usage = `odin-breach genera y consulta el archivo de contraseñas filtradas.
+
+Uso:
+  odin-breach build -in pwned-passwords-sha1.txt -out breached.bin [-prefix-bytes 8] [-min-count 0]
+  odin-breach check [-file breached.bin] < contraseña
+
+build convierte el corpus de Pwned Passwords (HIBP) ordenado por hash, una
+línea "SHA1:COUNT" por contraseña. Con -in - se lee de la entrada estándar.
+check usa BREACHED_PASSWORDS_FILE si no se indica -file.
+`
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using system environment variables")
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	var err error
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "build":
+		err = build(args)
+	case "check":
+		err = check(args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ %s: %v", cmd, err)
+	}
+}
+
+func build(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	in := fs.String("in", "", "corpus de HIBP ordenado por hash (- = entrada estándar)")
+	out := fs.String("out", "", "archivo a generar")
+	prefixBytes := fs.Int("prefix-bytes", breached.DefaultPrefix, "bytes de SHA-1 por contraseña (4-20); más bytes, menos falsos positivos")
+	minCount := fs.Int64("min-count", 0, "descartar contraseñas vistas menos veces en filtraciones")
+	fs.Parse(args)
+
+	if *in == "" || *out == "" {
+		return fmt.Errorf("-in and -out are required")
+	}
+
+	var r io.Reader = os.Stdin
+	if *in != "-" {
+		f, err := os.Open(*in)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	stats, err := breached.Build(bufio.NewReaderSize(r, 1<<20), *out, breached.BuildOptions{
+		PrefixLen: *prefixBytes,
+		MinCount:  *minCount,
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ %s: %d hashes de %d líneas (%d descartados)", *out, stats.Written, stats.Lines, stats.Skipped)
+	return nil
+}
+
+func check(args []string) error {
+	fs := flag.NewFlagSet("check", flag.ExitOnError)
+	file := fs.String("file", os.Getenv("BREACHED_PASSWORDS_FILE"), "archivo generado con build")
+	fs.Parse(args)
+
+	if *file == "" {
+		return fmt.Errorf("-file or BREACHED_PASSWORDS_FILE is required")
+	}
+
+	checker, err := breached.Open(*file)
+	if err != nil {
+		return err
+	}
+	defer checker.Close()
+
+	// La contraseña se lee de la entrada estándar para que no quede en el historial del shell
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	password := strings.TrimRight(line, "\r\n")
+
+	found, err := checker.Contains(password)
+	if err != nil {
+		return err
+	}
+	if found {
+		fmt.Println("breached")
+		os.Exit(1)
+	}
+	fmt.Println("not found")
+	return nil
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/audit"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/lockout"
+	"github.com/fzalvarez/odin-iam/internal/mfa"
+	"github.com/fzalvarez/odin-iam/internal/oauth"
+	"github.com/fzalvarez/odin-iam/internal/passwordless"
+	"github.com/fzalvarez/odin-iam/internal/passwordreset"
+	"github.com/fzalvarez/odin-iam/internal/ratelimit"
+	"github.com/fzalvarez/odin-iam/internal/scheduler"
+	"github.com/fzalvarez/odin-iam/internal/sessions"
+	"github.com/fzalvarez/odin-iam/internal/tenants"
+	"github.com/fzalvarez/odin-iam/internal/verification"
+	"github.com/fzalvarez/odin-iam/internal/webauthn"
+)
+
+// jobDeps agrupa los servicios que usan las tareas periódicas.
+type jobDeps struct {
+	sessions      *sessions.Service
+	tenants       *tenants.Service
+	oauth         *oauth.Service
+	passwordless  *passwordless.Service
+	passwordReset *passwordreset.Service
+	verification  *verification.Service
+	lockout       *lockout.Service
+	rateLimits    ratelimit.Store
+	mfa           *mfa.Service
+	webauthn      *webauthn.Service
+	denylist      *auth.Denylist
+	keyRing       *auth.KeyRing
+	audit         *audit.Service
+}
+
+// registerJobs registra las tareas de mantenimiento en el scheduler.
+func registerJobs(s *scheduler.Scheduler, d jobDeps) {
+	// Sesiones expiradas (incluye las consumidas por la rotación de refresh tokens)
+	s.Add(scheduler.Job{
+		Name:     "sessions.purge_expired",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			n, err := d.sessions.CleanupExpired(ctx)
+			if err == nil && n > 0 {
+				log.Printf("🧹 %d sesiones expiradas eliminadas", n)
+			}
+			return err
+		},
+	})
+
+	// Tenants con trial vencido pasan a suspended
+	s.Add(scheduler.Job{
+		Name:     "tenants.expire_trials",
+		Interval: 15 * time.Minute,
+		Run: func(ctx context.Context) error {
+			ids, err := d.tenants.ExpireTrials(ctx)
+			if err != nil {
+				return err
+			}
+			for _, id := range ids {
+				d.audit.Record(ctx, audit.Event{Type: audit.EventTenantTrialExpired, TenantID: id})
+			}
+			return nil
+		},
+	})
+
+	// Denylist, códigos de autorización, magic links, códigos OTP, desafíos MFA,
+	// ceremonias WebAuthn y tokens de recuperación y de verificación que ya no pueden usarse
+	s.Add(scheduler.Job{
+		Name:     "maintenance.purge_expired_tokens",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			tokens, tokensErr := d.denylist.PurgeExpired(ctx)
+			codes, codesErr := d.oauth.PurgeExpiredCodes(ctx)
+			links, linksErr := d.passwordless.PurgeExpired(ctx)
+			challenges, challengesErr := d.mfa.PurgeExpired(ctx)
+			ceremonies, ceremoniesErr := d.webauthn.PurgeExpired(ctx)
+			resets, resetsErr := d.passwordReset.PurgeExpired(ctx)
+			verifications, verificationsErr := d.verification.PurgeExpired(ctx)
+			if tokens+codes+links+challenges+ceremonies+resets+verifications > 0 {
+				log.Printf("🧹 Eliminados %d tokens revocados, %d códigos de autorización, %d magic links/OTP, %d desafíos MFA, %d ceremonias WebAuthn, %d tokens de recuperación y %d de verificación expirados", tokens, codes, links, challenges, ceremonies, resets, verifications)
+			}
+			return errors.Join(tokensErr, codesErr, linksErr, challengesErr, ceremoniesErr, resetsErr, verificationsErr)
+		},
+	})
+
+	// Contadores de logins fallidos sin actividad reciente ni bloqueo vigente
+	s.Add(scheduler.Job{
+		Name:     "lockout.purge_stale",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			n, err := d.lockout.PurgeStale(ctx)
+			if err == nil && n > 0 {
+				log.Printf("🧹 %d contadores de logins fallidos eliminados", n)
+			}
+			return err
+		},
+	})
+
+	// Buckets de rate limit llenos (solo con RATE_LIMIT_BACKEND=postgres; el
+	// backend en memoria se limpia solo)
+	if store, ok := d.rateLimits.(*ratelimit.PostgresStore); ok {
+		s.Add(scheduler.Job{
+			Name:     "ratelimit.purge_expired",
+			Interval: 15 * time.Minute,
+			Run: func(ctx context.Context) error {
+				n, err := store.PurgeExpired(ctx)
+				if err == nil && n > 0 {
+					log.Printf("🧹 %d buckets de rate limit eliminados", n)
+				}
+				return err
+			},
+		})
+	}
+
+	// Rotación de llaves de firma (equivale a `odin-keys rotate -if-due`)
+	s.Add(scheduler.Job{
+		Name:     "keys.rotate_if_due",
+		Interval: time.Hour,
+		Run: func(ctx context.Context) error {
+			rotated, err := d.keyRing.RotateIfDue(ctx)
+			if rotated {
+				log.Println("🔑 Llave de firma rotada por el scheduler")
+			}
+			return err
+		},
+	})
+}
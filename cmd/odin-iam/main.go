@@ -8,15 +8,30 @@ import (
 	"github.com/joho/godotenv"
 
 	"github.com/fzalvarez/odin-iam/internal/api"
+	"github.com/fzalvarez/odin-iam/internal/api/middlewares"
 	"github.com/fzalvarez/odin-iam/internal/apikeys"
+	"github.com/fzalvarez/odin-iam/internal/audit"
 	"github.com/fzalvarez/odin-iam/internal/auth"
 	"github.com/fzalvarez/odin-iam/internal/bootstrap"
+	"github.com/fzalvarez/odin-iam/internal/breached"
 	dbconn "github.com/fzalvarez/odin-iam/internal/db"
 
+	"github.com/fzalvarez/odin-iam/internal/lockout"
+	"github.com/fzalvarez/odin-iam/internal/mfa"
+	"github.com/fzalvarez/odin-iam/internal/notify"
+	"github.com/fzalvarez/odin-iam/internal/oauth"
+	"github.com/fzalvarez/odin-iam/internal/passwordless"
+	"github.com/fzalvarez/odin-iam/internal/passwordpolicy"
+	"github.com/fzalvarez/odin-iam/internal/passwordreset"
+	"github.com/fzalvarez/odin-iam/internal/ratelimit"
 	"github.com/fzalvarez/odin-iam/internal/roles"
+	"github.com/fzalvarez/odin-iam/internal/scheduler"
+	"github.com/fzalvarez/odin-iam/internal/secrets"
 	"github.com/fzalvarez/odin-iam/internal/sessions"
 	"github.com/fzalvarez/odin-iam/internal/tenants"
 	"github.com/fzalvarez/odin-iam/internal/users"
+	"github.com/fzalvarez/odin-iam/internal/verification"
+	"github.com/fzalvarez/odin-iam/internal/webauthn"
 
 	_ "github.com/fzalvarez/odin-iam/docs"
 )
@@ -51,12 +66,70 @@ func main() {
 	}
 	defer conn.Close()
 
+	ctx := context.Background()
+
 	// 2. Inicializar sistema (crear admin inicial si es necesario)
-	if err := bootstrap.InitializeSystem(context.Background(), conn); err != nil {
+	if err := bootstrap.InitializeSystem(ctx, conn); err != nil {
 		log.Printf("⚠️  Error al inicializar sistema: %v", err)
 	}
 
-	// 3. Inicializar repositorios
+	// 3. Cargar llaves de firma JWT (crea la primera si la BD está vacía)
+	keyRingCfg, err := auth.KeyRingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid signing key config: %v", err)
+	}
+	keyRing := auth.NewKeyRing(auth.NewSigningKeysRepository(conn), keyRingCfg)
+	if err := keyRing.Load(ctx); err != nil {
+		log.Fatalf("❌ failed to load signing keys: %v", err)
+	}
+	if err := keyRing.EnsureSigningKey(ctx); err != nil {
+		log.Fatalf("❌ failed to create signing key: %v", err)
+	}
+	keyRing.Start(ctx, 0)
+	auth.UseKeyRing(keyRing)
+
+	// Denylist de access tokens revocados (jti y "tokens valid after" por usuario)
+	denylistRefresh, err := auth.DenylistRefreshFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid TOKEN_DENYLIST_REFRESH: %v", err)
+	}
+	denylist := auth.NewDenylist(auth.NewRevocationsRepository(conn))
+	if err := denylist.Load(ctx); err != nil {
+		log.Fatalf("❌ failed to load token denylist: %v", err)
+	}
+	denylist.Start(ctx, denylistRefresh)
+	auth.UseDenylist(denylist)
+
+	// Hashes de Firebase importados: parámetros scrypt del proyecto de origen
+	firebaseScrypt, err := auth.FirebaseScryptFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid Firebase scrypt config: %v", err)
+	}
+	auth.UseFirebaseScrypt(firebaseScrypt)
+
+	// Pool de hashes de contraseña: cada Argon2 reserva 64 MB
+	hashPoolConfig, err := auth.HashPoolConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid hash pool config: %v", err)
+	}
+	hashPool := auth.NewHashPool(hashPoolConfig)
+	auth.UseHashPool(hashPool)
+	log.Printf("🔐 Hash pool: %d workers, cola de %d", hashPoolConfig.Workers, hashPoolConfig.MaxQueue)
+
+	// Proxies cuyo X-Forwarded-For se acepta para obtener la IP del cliente
+	trustedProxies, err := middlewares.TrustedProxiesFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid TRUSTED_PROXIES: %v", err)
+	}
+	middlewares.UseTrustedProxies(trustedProxies)
+
+	// Rate limiting de endpoints públicos: en memoria o compartido en Postgres
+	rateLimitStore, err := ratelimit.StoreFromEnv(conn)
+	if err != nil {
+		log.Fatalf("❌ invalid RATE_LIMIT_BACKEND: %v", err)
+	}
+
+	// 4. Inicializar repositorios
 	// Nota: db/gen debe haber sido regenerado con sqlc antes de compilar
 	credRepo := auth.NewCredentialsRepository(conn)
 	sessionRepo := sessions.NewRepository(conn)
@@ -64,30 +137,144 @@ func main() {
 	userRepo := users.NewRepository(conn)
 	roleRepo := roles.NewRepository(conn)
 	apikeyRepo := apikeys.NewRepository(conn)
+	oauthRepo := oauth.NewRepository(conn)
+	auditRepo := audit.NewRepository(conn)
+	passwordlessRepo := passwordless.NewRepository(conn)
+	mfaRepo := mfa.NewRepository(conn)
+	webauthnRepo := webauthn.NewRepository(conn)
+	passwordResetRepo := passwordreset.NewRepository(conn)
+	verificationRepo := verification.NewRepository(conn)
+	lockoutRepo := lockout.NewRepository(conn)
+	passwordPolicyRepo := passwordpolicy.NewRepository(conn)
+
+	// Refresh tokens: solo se guarda su HMAC. Las sesiones antiguas con el
+	// token en claro se migran al arrancar, sin invalidarlas.
+	refreshTokenKey, err := secrets.DeriveKey("refresh-tokens")
+	if err != nil {
+		log.Fatalf("❌ failed to derive refresh token key: %v", err)
+	}
+	sessions.UseTokenKey(refreshTokenKey)
+	if n, err := sessionRepo.MigrateLegacyTokens(ctx); err != nil {
+		log.Fatalf("❌ failed to migrate refresh tokens: %v", err)
+	} else if n > 0 {
+		log.Printf("🔐 %d refresh tokens migrados a hash", n)
+	}
 
-	// 4. Crear servicios
+	// 5. Crear servicios
+	// Envío de links y códigos (log o archivo en desarrollo)
+	notifier, err := notify.DispatcherFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid notification config: %v", err)
+	}
+	auditService := audit.NewService(auditRepo)
 	// userRepo implementa AuthEmailsRepository (AddEmail, GetByEmail)
 	authService := auth.NewService(
 		userRepo,
 		userRepo, // Usamos userRepo para emails
 		credRepo,
 		sessionRepo,
+		denylist,
+		auditService,
 	)
 	userService := users.NewService(userRepo)
 	tenantService := tenants.NewService(tenantRepo)
 	roleService := roles.NewRoleService(roleRepo)
 	apikeyService := apikeys.NewService(apikeyRepo) // Nuevo servicio
+	sessionService := sessions.NewService(sessionRepo)
+	// Login por tenant: key o ID del tenant y pertenencia del usuario (su tenant o un rol en él)
+	authService.UseTenantDirectory(tenantService)
+	// Política de contraseñas por tenant: composición, historial y caducidad
+	passwordPolicyService := passwordpolicy.NewService(passwordPolicyRepo, tenantService)
+	authService.UsePasswordPolicy(passwordPolicyService)
+	// Contraseñas filtradas (corpus de HIBP convertido con odin-breach build)
+	breachedPasswords, err := breached.OpenFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid BREACHED_PASSWORDS_FILE: %v", err)
+	}
+	if breachedPasswords != nil {
+		defer breachedPasswords.Close()
+		passwordPolicyService.UseBreachedPasswords(breachedPasswords)
+		log.Printf("🔐 %d hashes de contraseñas filtradas cargados", breachedPasswords.Count())
+	}
+	// Fuerza bruta: fallos de contraseña por cuenta e IP, con retardos y bloqueos temporales
+	lockoutService := lockout.NewService(lockoutRepo, userRepo, tenantService, auditService)
+	authService.UseLoginGuard(lockoutService)
+	// Verificación de email: los tenants con "email_verification_required" bloquean el login hasta verificar
+	verificationService := verification.NewService(verificationRepo, userRepo, tenantService, notifier, auditService)
+	authService.UseEmailVerifier(verificationService)
+	// MFA: los logins piden segundo factor a usuarios enrolados o de tenants que lo exigen
+	mfaService := mfa.NewService(mfaRepo, authService, userRepo, tenantService, auditService)
+	authService.UseSecondFactor(mfaService)
+	// Passkeys: login sin contraseña y segundo factor
+	relyingParty, err := webauthn.RelyingPartyFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid WebAuthn config: %v", err)
+	}
+	webauthnService := webauthn.NewService(webauthnRepo, relyingParty, authService, userRepo, mfaService, auditService)
+	mfaService.UsePasskeys(webauthnService)
+	passwordlessService := passwordless.NewService(passwordlessRepo, authService, userRepo, tenantService, notifier)
+	passwordResetService := passwordreset.NewService(passwordResetRepo, authService, userRepo, notifier)
+	oauthService := oauth.NewService(oauthRepo, authService, userRepo, apikeyService, sessionRepo, roleService)
+
+	// Tareas periódicas (una sola réplica ejecuta cada tarea gracias a advisory locks)
+	schedulerEnabled, err := scheduler.EnabledFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid SCHEDULER_ENABLED: %v", err)
+	}
+	if schedulerEnabled {
+		sched := scheduler.New(conn)
+		registerJobs(sched, jobDeps{
+			sessions:      sessionService,
+			tenants:       tenantService,
+			oauth:         oauthService,
+			passwordless:  passwordlessService,
+			passwordReset: passwordResetService,
+			verification:  verificationService,
+			lockout:       lockoutService,
+			rateLimits:    rateLimitStore,
+			mfa:           mfaService,
+			webauthn:      webauthnService,
+			denylist:      denylist,
+			keyRing:       keyRing,
+			audit:         auditService,
+		})
+		sched.Start(ctx)
+	}
 
-	// 5. Crear router con dependencias
+	// 6. Crear router con dependencias
 	r := api.NewRouter(api.RouterParams{
-		AuthService:   authService,
-		UserService:   userService,
-		TenantService: tenantService,
-		RoleService:   roleService,
-		APIKeyService: apikeyService, // Inyección
+		AuthService:          authService,
+		UserService:          userService,
+		TenantService:        tenantService,
+		RoleService:          roleService,
+		APIKeyService:        apikeyService, // Inyección
+		KeyRing:              keyRing,
+		OAuthService:         oauthService,
+		SessionService:       sessionService,
+		PasswordlessService:  passwordlessService,
+		PasswordResetService: passwordResetService,
+		VerificationService:  verificationService,
+		LockoutService:       lockoutService,
+		RateLimitStore:       rateLimitStore,
+		MFAService:           mfaService,
+		WebAuthnService:      webauthnService,
 	})
 
-	// 6. Iniciar servidor
+	// 7. Métricas para Prometheus en un listener interno, fuera del router público
+	metricsAddr, err := api.MetricsAddrFromEnv()
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if metricsAddr != "" {
+		go func() {
+			log.Printf("📈 Metrics running on %s", metricsAddr)
+			if err := http.ListenAndServe(metricsAddr, api.NewMetricsRouter(hashPool)); err != nil {
+				log.Fatalf("❌ metrics server error: %v", err)
+			}
+		}()
+	}
+
+	// 8. Iniciar servidor
 	log.Println("🚀 IAM service running on :8080")
 	if err := http.ListenAndServe(":8080", r); err != nil {
 		log.Fatalf("❌ server error: %v", err)
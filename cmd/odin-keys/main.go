@@ -0,0 +1,172 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/joho/godotenv"
+
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbconn "github.com/fzalvarez/odin-iam/internal/db"
+)
+
+const usage = `odin-keys administra las llaves de firma JWT.
+
+Uso:
+  odin-keys list
+  odin-keys create [-alg ES256] [-activate-at 2025-01-01T00:00:00Z]
+  odin-keys rotate [-if-due]
+  odin-keys retire -kid <kid>
+
+La configuración se toma de las mismas variables que el servicio
+(DATABASE_URL, SECRETS_KEY, JWT_SIGNING_ALG, JWT_KEY_*).
+`
+
+func main() {
+	if err := godotenv.Load(); err != nil {
+		log.Println("⚠️  No .env file found, using system environment variables")
+	}
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	conn, err := dbconn.Connect()
+	if err != nil {
+		log.Fatalf("❌ failed to connect database: %v", err)
+	}
+	defer conn.Close()
+
+	cfg, err := auth.KeyRingConfigFromEnv()
+	if err != nil {
+		log.Fatalf("❌ invalid key ring config: %v", err)
+	}
+
+	repo := auth.NewSigningKeysRepository(conn)
+	ring := auth.NewKeyRing(repo, cfg)
+	ctx := context.Background()
+
+	if err := ring.Load(ctx); err != nil {
+		log.Fatalf("❌ failed to load signing keys: %v", err)
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+	switch cmd {
+	case "list":
+		err = listKeys(ctx, repo)
+	case "create":
+		err = createKey(ctx, ring, cfg, args)
+	case "rotate":
+		err = rotateKeys(ctx, ring, args)
+	case "retire":
+		err = retireKey(ctx, ring, args)
+	default:
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	if err != nil {
+		log.Fatalf("❌ %s: %v", cmd, err)
+	}
+}
+
+func listKeys(ctx context.Context, repo *auth.SigningKeysRepository) error {
+	keys, err := repo.ListAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "KID\tALG\tACTIVATES_AT\tRETIRED_AT\tEXPIRES_AT")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+			k.ID,
+			k.Algorithm,
+			k.ActivatesAt.Format(time.RFC3339),
+			formatNullTime(k.RetiredAt.Time, k.RetiredAt.Valid),
+			formatNullTime(k.ExpiresAt.Time, k.ExpiresAt.Valid),
+		)
+	}
+	return w.Flush()
+}
+
+func createKey(ctx context.Context, ring *auth.KeyRing, cfg auth.KeyRingConfig, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ExitOnError)
+	alg := fs.String("alg", cfg.Algorithm, "algoritmo: RS256, ES256 o EdDSA")
+	activateAt := fs.String("activate-at", "", "momento (RFC3339) en que la llave empieza a firmar; por defecto ahora")
+	fs.Parse(args)
+
+	activatesAt := time.Now().UTC()
+	if *activateAt != "" {
+		t, err := time.Parse(time.RFC3339, *activateAt)
+		if err != nil {
+			return fmt.Errorf("invalid -activate-at: %w", err)
+		}
+		activatesAt = t
+	}
+
+	key, err := ring.Generate(ctx, *alg, activatesAt)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Llave creada: %s (%s), firma desde %s", key.ID, key.Algorithm, key.ActivatesAt.Format(time.RFC3339))
+	return nil
+}
+
+func rotateKeys(ctx context.Context, ring *auth.KeyRing, args []string) error {
+	fs := flag.NewFlagSet("rotate", flag.ExitOnError)
+	ifDue := fs.Bool("if-due", false, "rotar solo si la llave actual superó JWT_KEY_ROTATION_INTERVAL (para cron)")
+	fs.Parse(args)
+
+	if *ifDue {
+		rotated, err := ring.RotateIfDue(ctx)
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			log.Println("✅ La llave actual todavía no requiere rotación")
+		} else {
+			log.Println("✅ Llave rotada")
+		}
+		return nil
+	}
+
+	key, err := ring.Rotate(ctx)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("✅ Llave rotada: %s (%s), firma desde %s", key.ID, key.Algorithm, key.ActivatesAt.Format(time.RFC3339))
+	return nil
+}
+
+func retireKey(ctx context.Context, ring *auth.KeyRing, args []string) error {
+	fs := flag.NewFlagSet("retire", flag.ExitOnError)
+	kid := fs.String("kid", "", "kid de la llave a retirar")
+	fs.Parse(args)
+
+	if *kid == "" {
+		return fmt.Errorf("-kid is required")
+	}
+
+	if err := ring.Retire(ctx, *kid); err != nil {
+		return err
+	}
+
+	log.Printf("✅ Llave %s retirada; seguirá verificando durante el periodo de gracia", *kid)
+	return nil
+}
+
+func formatNullTime(t time.Time, valid bool) string {
+	if !valid {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
@@ -5,13 +5,15 @@ type LogoutRequest struct {
 }
 
 type CreateAPIKeyRequest struct {
-	Name string `json:"name"`
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"` // Permisos que la key puede pedir en client_credentials
 }
 
 type APIKeyResponse struct {
-	ID        string `json:"id"`
-	Name      string `json:"name"`
-	Prefix    string `json:"prefix"`
-	CreatedAt string `json:"created_at"`
-	RawKey    string `json:"raw_key,omitempty"` // Solo al crear
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"created_at"`
+	RawKey    string   `json:"raw_key,omitempty"` // Solo al crear
 }
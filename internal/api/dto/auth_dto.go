@@ -8,14 +8,18 @@ type RegisterRequest struct {
 
 type RegisterResponse struct {
 	UserID       string `json:"user_id"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	TenantID     string `json:"tenant_id"`
+	// Sin tokens: el tenant exige verificar el email antes del primer login
+	EmailVerificationRequired bool `json:"email_verification_required,omitempty"`
 }
 
 type LoginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// Key o ID del tenant del token; vacío = el tenant del usuario
+	Tenant string `json:"tenant,omitempty"`
 }
 
 type LoginResponse RegisterResponse
@@ -30,3 +34,62 @@ type TokenResponse struct {
 	AccessToken  string `json:"access_token"`
 	RefreshToken string `json:"refresh_token"`
 }
+
+type MagicLinkRequest struct {
+	Email string `json:"email"`
+}
+
+type MagicLinkVerifyRequest struct {
+	Token string `json:"token"`
+}
+
+type OTPRequest struct {
+	Email   string `json:"email"`
+	Channel string `json:"channel,omitempty"` // email (por defecto), sms, whatsapp
+}
+
+type OTPVerifyRequest struct {
+	Email string `json:"email"`
+	Code  string `json:"code"`
+}
+
+type EmailVerifyRequest struct {
+	Token string `json:"token"`
+}
+
+type EmailVerifyResendRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordForgotRequest struct {
+	Email string `json:"email"`
+}
+
+type PasswordResetRequest struct {
+	Token       string `json:"token"`
+	NewPassword string `json:"new_password"`
+}
+
+type PasswordChangeRequest struct {
+	Email           string `json:"email"`
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+	Tenant          string `json:"tenant,omitempty"` // como en LoginRequest
+}
+
+type SwitchTenantRequest struct {
+	Tenant       string `json:"tenant"`        // key o ID
+	RefreshToken string `json:"refresh_token"` // el de la sesión actual; se rota
+}
+
+type PasswordViolation struct {
+	Code    string `json:"code"` // too_short, missing_digit, reused...
+	Message string `json:"message"`
+}
+
+// PasswordPolicyErrorResponse es la respuesta 400 cuando la contraseña nueva
+// incumple la política del tenant.
+type PasswordPolicyErrorResponse struct {
+	Error      string              `json:"error"` // "password_policy"
+	Violations []PasswordViolation `json:"violations"`
+}
@@ -0,0 +1,40 @@
+package dto
+
+// MFAChallengeResponse es la respuesta 401 de los logins cuando falta el segundo factor.
+type MFAChallengeResponse struct {
+	Error              string   `json:"error"` // "mfa_required"
+	MFAToken           string   `json:"mfa_token"`
+	Methods            []string `json:"methods"`
+	EnrollmentRequired bool     `json:"enrollment_required"`
+	ExpiresIn          int      `json:"expires_in"`
+}
+
+type MFAVerifyRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"` // TOTP o código de recuperación
+}
+
+type MFAEnrollRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type MFAEnrollConfirmRequest struct {
+	MFAToken string `json:"mfa_token"`
+	Code     string `json:"code"`
+}
+
+type MFAEnrollConfirmResponse struct {
+	UserID        string   `json:"user_id"`
+	AccessToken   string   `json:"access_token"`
+	RefreshToken  string   `json:"refresh_token"`
+	TenantID      string   `json:"tenant_id"`
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code"`
+}
+
+type MFARecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
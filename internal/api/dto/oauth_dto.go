@@ -0,0 +1,25 @@
+package dto
+
+import "errors"
+
+type CreateOAuthClientRequest struct {
+	Name         string   `json:"name"`
+	TenantID     string   `json:"tenant_id"` // Opcional: vacío = tenant del llamador; otro tenant solo desde System
+	RedirectURIs []string `json:"redirect_uris"`
+	GrantTypes   []string `json:"grant_types"`
+	Scopes       []string `json:"scopes"`
+	Confidential bool     `json:"confidential"` // true = se genera client_secret
+}
+
+func (r *CreateOAuthClientRequest) Validate() error {
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// OAuthErrorResponse es el cuerpo de error de /oauth/token (RFC 6749 §5.2).
+type OAuthErrorResponse struct {
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
@@ -0,0 +1,26 @@
+package dto
+
+import "github.com/fzalvarez/odin-iam/internal/webauthn"
+
+type PasskeyRegisterRequest struct {
+	Name       string                          `json:"name"`
+	Credential webauthn.RegistrationCredential `json:"credential"`
+}
+
+type PasskeyRegisterResponse struct {
+	Passkey       *webauthn.Passkey `json:"passkey"`
+	RecoveryCodes []string          `json:"recovery_codes,omitempty"` // solo con la primera passkey sin TOTP
+}
+
+type PasskeyLoginRequest struct {
+	Credential webauthn.AssertionCredential `json:"credential"`
+}
+
+type PasskeyMFABeginRequest struct {
+	MFAToken string `json:"mfa_token"`
+}
+
+type PasskeyMFAFinishRequest struct {
+	MFAToken   string                       `json:"mfa_token"`
+	Credential webauthn.AssertionCredential `json:"credential"`
+}
@@ -0,0 +1,5 @@
+package dto
+
+type RevokeSessionsResponse struct {
+	RevokedSessions int64 `json:"revoked_sessions"`
+}
@@ -2,6 +2,7 @@ package dto
 
 import (
 	"errors"
+	"fmt"
 	"strings"
 	"time"
 )
@@ -44,8 +45,47 @@ type ResetPasswordRequest struct {
 }
 
 func (r *ResetPasswordRequest) Validate() error {
-	if len(r.NewPassword) < 8 {
-		return errors.New("password must be at least 8 characters")
+	// El resto de reglas depende de la política del tenant del usuario
+	if r.NewPassword == "" {
+		return errors.New("new_password is required")
 	}
 	return nil
 }
+
+type ImportUsersRequest struct {
+	TenantID string           `json:"tenant_id"`
+	Users    []ImportUserItem `json:"users"`
+}
+
+// ImportUserItem es un usuario de otro sistema. password_hash admite bcrypt,
+// scrypt, PBKDF2 (PHC o Django), Firebase scrypt y Argon2id.
+type ImportUserItem struct {
+	Email         string `json:"email"`
+	DisplayName   string `json:"display_name"`
+	PasswordHash  string `json:"password_hash"`
+	EmailVerified bool   `json:"email_verified"`
+}
+
+// MaxImportUsers limita el tamaño de cada lote de importación.
+const MaxImportUsers = 1000
+
+func (r *ImportUsersRequest) Validate() error {
+	if len(r.Users) == 0 {
+		return errors.New("users is required")
+	}
+	if len(r.Users) > MaxImportUsers {
+		return fmt.Errorf("at most %d users per request", MaxImportUsers)
+	}
+	return nil
+}
+
+type ImportUserError struct {
+	Index int    `json:"index"`
+	Email string `json:"email"`
+	Error string `json:"error"`
+}
+
+type ImportUsersResponse struct {
+	Imported int               `json:"imported"`
+	Failed   []ImportUserError `json:"failed"`
+}
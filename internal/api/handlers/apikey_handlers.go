@@ -2,25 +2,28 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"github.com/fzalvarez/odin-iam/internal/api/dto"
 	"github.com/fzalvarez/odin-iam/internal/api/middlewares" // Corregido
 	"github.com/fzalvarez/odin-iam/internal/apikeys"
+	"github.com/fzalvarez/odin-iam/internal/roles"
 	"github.com/go-chi/chi/v5"
 )
 
 type APIKeyHandler struct {
-	service *apikeys.Service
+	service     *apikeys.Service
+	roleService *roles.RoleService
 }
 
-func NewAPIKeyHandler(s *apikeys.Service) *APIKeyHandler {
-	return &APIKeyHandler{service: s}
+func NewAPIKeyHandler(s *apikeys.Service, roleService *roles.RoleService) *APIKeyHandler {
+	return &APIKeyHandler{service: s, roleService: roleService}
 }
 
 // Create godoc
 // @Summary      Create API Key
-// @Description  Create a new API Key for the tenant
+// @Description  Create a new API Key for the caller's tenant. Scopes must be permissions the caller holds in that tenant.
 // @Tags         apikeys
 // @Accept       json
 // @Produce      json
@@ -28,6 +31,7 @@ func NewAPIKeyHandler(s *apikeys.Service) *APIKeyHandler {
 // @Param        request body dto.CreateAPIKeyRequest true "Create API Key Request"
 // @Success      201  {object}  dto.APIKeyResponse
 // @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
 // @Router       /apikeys [post]
 func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
 	tenantID := middlewares.GetTenantID(r.Context())
@@ -42,7 +46,19 @@ func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.service.Create(r.Context(), tenantID, req.Name)
+	grantable, err := middlewares.GrantablePermissions(r.Context(), h.roleService)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	res, err := h.service.Create(r.Context(), tenantID, req.Name, req.Scopes, grantable)
+	if errors.Is(err, apikeys.ErrScopeNotAllowed) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
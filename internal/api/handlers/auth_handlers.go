@@ -2,29 +2,41 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
-	"github.com/fzalvarez/odin-iam/internal/auth"
 	"github.com/fzalvarez/odin-iam/internal/api/dto"
+	"github.com/fzalvarez/odin-iam/internal/api/middlewares"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/notify"
+	"github.com/fzalvarez/odin-iam/internal/passwordless"
+	"github.com/fzalvarez/odin-iam/internal/passwordreset"
+	"github.com/fzalvarez/odin-iam/internal/verification"
 )
 
 type AuthHandler struct {
-	auth *auth.AuthService
+	auth          *auth.AuthService
+	passwordless  *passwordless.Service
+	passwordReset *passwordreset.Service
+	verification  *verification.Service
 }
 
-func NewAuthHandler(a *auth.AuthService) *AuthHandler {
-	return &AuthHandler{auth: a}
+func NewAuthHandler(a *auth.AuthService, pl *passwordless.Service, pr *passwordreset.Service, v *verification.Service) *AuthHandler {
+	return &AuthHandler{auth: a, passwordless: pl, passwordReset: pr, verification: v}
 }
 
 // Register godoc
 // @Summary      Register a new user
-// @Description  Register a new user with the provided details
+// @Description  Register a new user with the provided details and email a verification link. If the tenant requires a verified email the response has no tokens and email_verification_required=true.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request body dto.RegisterRequest true "Register Request"
 // @Success      201  {object}  dto.RegisterResponse
-// @Failure      400  {object}  map[string]string
+// @Failure      400  {object}  dto.PasswordPolicyErrorResponse
+// @Failure      503  {object}  map[string]string
 // @Router       /auth/register [post]
 func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 	var req dto.RegisterRequest
@@ -35,7 +47,10 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.auth.Register(r.Context(), req.Name, req.Email, req.Password)
+	res, err := h.auth.Register(r.Context(), req.Name, req.Email, req.Password, clientInfo(r))
+	if writePasswordPolicyError(w, err) || writeHashPoolBusy(w, err) || writeTenantError(w, err) {
+		return
+	}
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusBadRequest)
@@ -50,14 +65,17 @@ func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
 
 // Login godoc
 // @Summary      Login user
-// @Description  Authenticate user and return access and refresh tokens
+// @Description  Authenticate user and return access and refresh tokens for the requested tenant (key or ID; empty = the user's tenant). The user must belong to it, by its own tenant or by a role in it; otherwise 403 tenant_access_denied. If the user has MFA (or the tenant requires it) the response is 401 with an mfa_token to complete in /auth/mfa/verify. An expired password returns 403 password_expired: change it in /auth/password/change.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
 // @Param        request body dto.LoginRequest true "Login Request"
 // @Success      200  {object}  dto.LoginResponse
 // @Failure      400  {object}  map[string]string
-// @Failure      401  {object}  map[string]string
+// @Failure      401  {object}  dto.MFAChallengeResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 	var req dto.LoginRequest
@@ -68,7 +86,10 @@ func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.auth.Login(r.Context(), req.Email, req.Password)
+	res, err := h.auth.Login(r.Context(), req.Email, req.Password, req.Tenant, clientInfo(r))
+	if writeMFAChallenge(w, err) || writeEmailNotVerified(w, err) || writeLoginThrottled(w, err) || writePasswordExpired(w, err) || writeHashPoolBusy(w, err) || writeTenantError(w, err) {
+		return
+	}
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
@@ -100,7 +121,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	res, err := h.auth.Refresh(r.Context(), req.RefreshToken)
+	res, err := h.auth.Refresh(r.Context(), req.RefreshToken, clientInfo(r))
 	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
@@ -114,7 +135,7 @@ func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
 
 // Logout godoc
 // @Summary      Logout user
-// @Description  Invalidate refresh token and session
+// @Description  Invalidate refresh token and session. If an access token is sent in the Authorization header it is revoked too.
 // @Tags         auth
 // @Accept       json
 // @Produce      json
@@ -131,7 +152,7 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.auth.Logout(r.Context(), req.RefreshToken); err != nil {
+	if err := h.auth.Logout(r.Context(), req.RefreshToken, middlewares.BearerToken(r)); err != nil {
 		// Incluso si falla, solemos devolver 200 para no filtrar info, o 500 si es error de servidor
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
@@ -143,3 +164,553 @@ func (h *AuthHandler) Logout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"message": "logged out successfully"})
 }
+
+// LogoutAll godoc
+// @Summary      Logout everywhere
+// @Description  Close every session of the authenticated user and revoke their access tokens
+// @Tags         auth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  dto.RevokeSessionsResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserID(r.Context())
+	if userID == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user context required"})
+		return
+	}
+
+	n, err := h.auth.LogoutAll(r.Context(), userID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.RevokeSessionsResponse{RevokedSessions: n})
+}
+
+// MagicLink godoc
+// @Summary      Request a magic link
+// @Description  Email a single-use sign-in link. The response is the same whether or not the email has an account.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.MagicLinkRequest true "Magic Link Request"
+// @Success      202  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/magic-link [post]
+func (h *AuthHandler) MagicLink(w http.ResponseWriter, r *http.Request) {
+	var req dto.MagicLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "email is required"})
+		return
+	}
+
+	if err := h.passwordless.RequestMagicLink(r.Context(), req.Email, clientInfo(r)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, passwordless.ErrRateLimited) {
+			status = http.StatusTooManyRequests
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the email is registered, a sign-in link has been sent"})
+}
+
+// MagicLinkVerify godoc
+// @Summary      Verify a magic link
+// @Description  Exchange the token from a magic link for access and refresh tokens
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.MagicLinkVerifyRequest true "Magic Link Verify Request"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/magic-link/verify [post]
+func (h *AuthHandler) MagicLinkVerify(w http.ResponseWriter, r *http.Request) {
+	var req dto.MagicLinkVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, err := h.passwordless.VerifyMagicLink(r.Context(), req.Token, clientInfo(r))
+	if writeMFAChallenge(w, err) || writeEmailNotVerified(w, err) || writeTenantError(w, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, passwordless.ErrInvalidMagicLink) {
+			status = http.StatusUnauthorized
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// OTP godoc
+// @Summary      Request a one-time code
+// @Description  Send a 6-digit sign-in code valid for 5 minutes. The response is the same whether or not the email has an account.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.OTPRequest true "OTP Request"
+// @Success      202  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/otp [post]
+func (h *AuthHandler) OTP(w http.ResponseWriter, r *http.Request) {
+	var req dto.OTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "email is required"})
+		return
+	}
+
+	if err := h.passwordless.RequestOTP(r.Context(), req.Email, req.Channel, clientInfo(r)); err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, passwordless.ErrRateLimited):
+			status = http.StatusTooManyRequests
+		case errors.Is(err, notify.ErrUnsupportedChannel):
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the email is registered, a code has been sent"})
+}
+
+// OTPVerify godoc
+// @Summary      Verify a one-time code
+// @Description  Exchange a one-time code for access and refresh tokens. Each code allows 5 attempts.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.OTPVerifyRequest true "OTP Verify Request"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/otp/verify [post]
+func (h *AuthHandler) OTPVerify(w http.ResponseWriter, r *http.Request) {
+	var req dto.OTPVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, err := h.passwordless.VerifyOTP(r.Context(), req.Email, req.Code, clientInfo(r))
+	if writeMFAChallenge(w, err) || writeEmailNotVerified(w, err) || writeTenantError(w, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, passwordless.ErrInvalidOTP) {
+			status = http.StatusUnauthorized
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// EmailVerify godoc
+// @Summary      Verify email
+// @Description  Mark the user's email as verified with the token from a verification link
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.EmailVerifyRequest true "Email Verify Request"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Router       /auth/email/verify [post]
+func (h *AuthHandler) EmailVerify(w http.ResponseWriter, r *http.Request) {
+	var req dto.EmailVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	if err := h.verification.Verify(r.Context(), req.Token, clientInfo(r)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, verification.ErrInvalidToken) {
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "email verified"})
+}
+
+// EmailVerifyResend godoc
+// @Summary      Resend verification email
+// @Description  Send a new email verification link. At most one per minute and 5 per hour per email. The response is the same whether or not the email has an account or is already verified.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.EmailVerifyResendRequest true "Email Verify Resend Request"
+// @Success      202  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/email/verify/resend [post]
+func (h *AuthHandler) EmailVerifyResend(w http.ResponseWriter, r *http.Request) {
+	var req dto.EmailVerifyResendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "email is required"})
+		return
+	}
+
+	if err := h.verification.Resend(r.Context(), req.Email); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, verification.ErrRateLimited) {
+			status = http.StatusTooManyRequests
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the email is registered and not yet verified, a verification link has been sent"})
+}
+
+// PasswordForgot godoc
+// @Summary      Request a password reset
+// @Description  Email a single-use password reset link valid for 30 minutes. The response is the same whether or not the email has an account.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.PasswordForgotRequest true "Password Forgot Request"
+// @Success      202  {object}  map[string]string
+// @Failure      400  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Router       /auth/password/forgot [post]
+func (h *AuthHandler) PasswordForgot(w http.ResponseWriter, r *http.Request) {
+	var req dto.PasswordForgotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Email == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "email is required"})
+		return
+	}
+
+	if err := h.passwordReset.RequestReset(r.Context(), req.Email, clientInfo(r)); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, passwordreset.ErrRateLimited) {
+			status = http.StatusTooManyRequests
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	json.NewEncoder(w).Encode(map[string]string{"message": "if the email is registered, a reset link has been sent"})
+}
+
+// PasswordReset godoc
+// @Summary      Reset password
+// @Description  Set a new password with the token from a reset link. All of the user's sessions are closed.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.PasswordResetRequest true "Password Reset Request"
+// @Success      200  {object}  map[string]string
+// @Failure      400  {object}  dto.PasswordPolicyErrorResponse
+// @Failure      503  {object}  map[string]string
+// @Router       /auth/password/reset [post]
+func (h *AuthHandler) PasswordReset(w http.ResponseWriter, r *http.Request) {
+	var req dto.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	err := h.passwordReset.ResetPassword(r.Context(), req.Token, req.NewPassword, clientInfo(r))
+	if writePasswordPolicyError(w, err) || writeHashPoolBusy(w, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, passwordreset.ErrInvalidToken) {
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"message": "password updated, please sign in again"})
+}
+
+// PasswordChange godoc
+// @Summary      Change password
+// @Description  Change the password with the current one, also when it has expired. The user's other sessions are closed and the response is the same as /auth/login.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.PasswordChangeRequest true "Password Change Request"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      400  {object}  dto.PasswordPolicyErrorResponse
+// @Failure      401  {object}  dto.MFAChallengeResponse
+// @Failure      403  {object}  map[string]string
+// @Failure      429  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
+// @Router       /auth/password/change [post]
+func (h *AuthHandler) PasswordChange(w http.ResponseWriter, r *http.Request) {
+	var req dto.PasswordChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, err := h.auth.ChangePassword(r.Context(), req.Email, req.CurrentPassword, req.NewPassword, req.Tenant, clientInfo(r))
+	if writePasswordPolicyError(w, err) || writeMFAChallenge(w, err) || writeEmailNotVerified(w, err) || writeLoginThrottled(w, err) || writeHashPoolBusy(w, err) || writeTenantError(w, err) {
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidCredentials) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid credentials"})
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// SwitchTenant godoc
+// @Summary      Switch tenant
+// @Description  Move the current session to another tenant (key or ID) the authenticated user belongs to, by its own tenant or by a role in it. The refresh token of the current session is rotated within its family, so it stops working. The target tenant's verified-email and MFA requirements apply as in /auth/login.
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.SwitchTenantRequest true "Switch Tenant Request"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  dto.MFAChallengeResponse
+// @Failure      403  {object}  map[string]string
+// @Router       /auth/switch-tenant [post]
+func (h *AuthHandler) SwitchTenant(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserID(r.Context())
+	if userID == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user context required"})
+		return
+	}
+
+	var req dto.SwitchTenantRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Tenant == "" || req.RefreshToken == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "tenant and refresh_token are required"})
+		return
+	}
+
+	res, err := h.auth.SwitchTenant(r.Context(), userID, middlewares.GetTenantID(r.Context()), req.RefreshToken, req.Tenant, clientInfo(r))
+	if writeMFAChallenge(w, err) || writeEmailNotVerified(w, err) || writeTenantError(w, err) {
+		return
+	}
+	if errors.Is(err, auth.ErrInvalidRefreshToken) || errors.Is(err, auth.ErrRefreshTokenReused) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid refresh token"})
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// writeMFAChallenge responde 401 con el mfa_token si el login necesita segundo factor.
+func writeMFAChallenge(w http.ResponseWriter, err error) bool {
+	var challenge *auth.MFAChallenge
+	if !errors.As(err, &challenge) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(dto.MFAChallengeResponse{
+		Error:              "mfa_required",
+		MFAToken:           challenge.Token,
+		Methods:            challenge.Methods,
+		EnrollmentRequired: challenge.EnrollmentRequired,
+		ExpiresIn:          challenge.ExpiresIn,
+	})
+	return true
+}
+
+// writeEmailNotVerified responde 403 si el tenant exige email verificado y el
+// del usuario aún no lo está.
+func writeEmailNotVerified(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, auth.ErrEmailNotVerified) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": "email_not_verified"})
+	return true
+}
+
+// writeLoginThrottled responde 429 con Retry-After si la cuenta o la IP
+// acumulan demasiados fallos de contraseña.
+func writeLoginThrottled(w http.ResponseWriter, err error) bool {
+	var throttled *auth.LoginThrottled
+	if !errors.As(err, &throttled) {
+		return false
+	}
+
+	code := "too_many_attempts"
+	if throttled.Locked {
+		code = "account_locked"
+	}
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	w.WriteHeader(http.StatusTooManyRequests)
+	json.NewEncoder(w).Encode(map[string]any{"error": code, "retry_after": retryAfter})
+	return true
+}
+
+// writePasswordPolicyError responde 400 con las reglas de la política del
+// tenant que incumple la contraseña nueva.
+func writePasswordPolicyError(w http.ResponseWriter, err error) bool {
+	var policyErr *auth.PasswordPolicyError
+	if !errors.As(err, &policyErr) {
+		return false
+	}
+
+	violations := make([]dto.PasswordViolation, len(policyErr.Violations))
+	for i, v := range policyErr.Violations {
+		violations[i] = dto.PasswordViolation{Code: v.Code, Message: v.Message}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	json.NewEncoder(w).Encode(dto.PasswordPolicyErrorResponse{
+		Error:      "password_policy",
+		Violations: violations,
+	})
+	return true
+}
+
+// writePasswordExpired responde 403 si la contraseña superó la edad máxima
+// del tenant; el cliente debe cambiarla en /auth/password/change.
+func writePasswordExpired(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, auth.ErrPasswordExpired) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": "password_expired"})
+	return true
+}
+
+// writeTenantError responde 403 si el usuario no pertenece al tenant pedido
+// o el tenant no está activo.
+func writeTenantError(w http.ResponseWriter, err error) bool {
+	var code string
+	switch {
+	case errors.Is(err, auth.ErrTenantAccessDenied):
+		code = "tenant_access_denied"
+	case errors.Is(err, auth.ErrTenantInactive):
+		code = "tenant_inactive"
+	default:
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(map[string]string{"error": code})
+	return true
+}
+
+// writeHashPoolBusy responde 503 con Retry-After si el pool de hashes de
+// contraseña está saturado. Es transitorio: el cliente puede reintentar.
+func writeHashPoolBusy(w http.ResponseWriter, err error) bool {
+	if !errors.Is(err, auth.ErrHashPoolBusy) {
+		return false
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", "1")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	json.NewEncoder(w).Encode(map[string]string{"error": "server_busy"})
+	return true
+}
+
+// clientInfo extrae el dispositivo (user agent e IP real) que se guarda en la sesión.
+func clientInfo(r *http.Request) auth.ClientInfo {
+	return auth.ClientInfo{
+		UserAgent: r.UserAgent(),
+		IP:        middlewares.ClientIP(r),
+	}
+}
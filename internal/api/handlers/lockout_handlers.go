@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fzalvarez/odin-iam/internal/api/middlewares"
+	"github.com/fzalvarez/odin-iam/internal/lockout"
+	"github.com/go-chi/chi/v5"
+)
+
+type LockoutHandler struct {
+	lockout *lockout.Service
+}
+
+func NewLockoutHandler(l *lockout.Service) *LockoutHandler {
+	return &LockoutHandler{lockout: l}
+}
+
+// List godoc
+// @Summary      List lockouts
+// @Description  Accounts (by email) and IPs currently locked after repeated failed logins
+// @Tags         lockouts
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   lockout.Lock
+// @Router       /lockouts [get]
+func (h *LockoutHandler) List(w http.ResponseWriter, r *http.Request) {
+	locks, err := h.lockout.ListLocked(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(locks)
+}
+
+// UnlockUser godoc
+// @Summary      Unlock a user
+// @Description  Remove the lockout and the failed login count of a user's account
+// @Tags         lockouts
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Router       /users/{id}/unlock [post]
+func (h *LockoutHandler) UnlockUser(w http.ResponseWriter, r *http.Request) {
+	actorID := middlewares.GetUserID(r.Context())
+
+	if err := h.lockout.UnlockUser(r.Context(), actorID, chi.URLParam(r, "id")); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, sql.ErrNoRows) {
+			status = http.StatusNotFound
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UnlockIP godoc
+// @Summary      Unlock an IP
+// @Description  Remove the lockout and the failed login count of a client IP
+// @Tags         lockouts
+// @Security     BearerAuth
+// @Param        ip   path      string  true  "Client IP"
+// @Success      204
+// @Failure      400  {object}  map[string]string
+// @Router       /lockouts/ips/{ip} [delete]
+func (h *LockoutHandler) UnlockIP(w http.ResponseWriter, r *http.Request) {
+	actorID := middlewares.GetUserID(r.Context())
+
+	if err := h.lockout.UnlockIP(r.Context(), actorID, chi.URLParam(r, "ip")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, lockout.ErrInvalidIP) {
+			status = http.StatusBadRequest
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/fzalvarez/odin-iam/internal/auth"
+)
+
+type MetricsHandler struct {
+	hashPool *auth.HashPool
+}
+
+func NewMetricsHandler(p *auth.HashPool) *MetricsHandler {
+	return &MetricsHandler{hashPool: p}
+}
+
+// Metrics godoc
+// @Summary      Prometheus metrics
+// @Description  Password hashing pool metrics in Prometheus text format: workers, in-flight hashes, queue depth, rejections and wait/run latency histograms. Served only on the internal METRICS_ADDR listener, not on the API port.
+// @Tags         metrics
+// @Produce      plain
+// @Success      200  {string}  string
+// @Router       /metrics [get]
+func (h *MetricsHandler) Metrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+
+	if h.hashPool != nil {
+		writeHashPoolMetrics(w, h.hashPool.Stats())
+	}
+}
+
+func writeHashPoolMetrics(w io.Writer, s auth.HashPoolStats) {
+	gauge := func(name, help string, v int64) {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s gauge\n%s %d\n", name, help, name, name, v)
+	}
+	gauge("odin_password_hash_workers", "Password hashes that can run at the same time.", int64(s.Workers))
+	gauge("odin_password_hash_queue_limit", "Requests that can wait for a hashing worker.", int64(s.MaxQueue))
+	gauge("odin_password_hash_in_flight", "Password hashes running now.", int64(s.InFlight))
+	gauge("odin_password_hash_queue_depth", "Requests waiting for a hashing worker.", s.Queued)
+
+	const total = "odin_password_hash_requests_total"
+	fmt.Fprintf(w, "# HELP %s Password hash requests by result.\n# TYPE %s counter\n", total, total)
+	fmt.Fprintf(w, "%s{result=\"completed\"} %d\n", total, s.Completed)
+	fmt.Fprintf(w, "%s{result=\"rejected\"} %d\n", total, s.Rejected)
+	fmt.Fprintf(w, "%s{result=\"canceled\"} %d\n", total, s.Canceled)
+
+	writeHistogram(w, "odin_password_hash_wait_seconds", "Time spent waiting for a hashing worker.", s.Wait)
+	writeHistogram(w, "odin_password_hash_duration_seconds", "Time spent computing a password hash.", s.Run)
+}
+
+func writeHistogram(w io.Writer, name, help string, h auth.HistogramSnapshot) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s histogram\n", name, help, name)
+	for i, le := range auth.HashPoolBuckets {
+		fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, strconv.FormatFloat(le, 'g', -1, 64), h.Buckets[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{le=\"+Inf\"} %d\n", name, h.Count)
+	fmt.Fprintf(w, "%s_sum %s\n", name, strconv.FormatFloat(h.Sum.Seconds(), 'g', -1, 64))
+	fmt.Fprintf(w, "%s_count %d\n", name, h.Count)
+}
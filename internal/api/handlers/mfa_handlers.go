@@ -0,0 +1,330 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fzalvarez/odin-iam/internal/api/dto"
+	"github.com/fzalvarez/odin-iam/internal/api/middlewares"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/mfa"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+)
+
+type MFAHandler struct {
+	mfa *mfa.Service
+}
+
+func NewMFAHandler(s *mfa.Service) *MFAHandler {
+	return &MFAHandler{mfa: s}
+}
+
+// Verify godoc
+// @Summary      Complete a login with a second factor
+// @Description  Exchange the mfa_token returned by a login and a TOTP or recovery code for access and refresh tokens. Each token allows 5 attempts.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.MFAVerifyRequest true "MFA Verify Request"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/mfa/verify [post]
+func (h *MFAHandler) Verify(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFAVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, err := h.mfa.VerifyChallenge(r.Context(), req.MFAToken, req.Code, clientInfo(r))
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// Enroll godoc
+// @Summary      Start TOTP enrollment during login
+// @Description  For users whose tenant requires MFA: generate a TOTP secret using the mfa_token returned by the login
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.MFAEnrollRequest true "MFA Enroll Request"
+// @Success      200  {object}  mfa.Enrollment
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /auth/mfa/enroll [post]
+func (h *MFAHandler) Enroll(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFAEnrollRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	enrollment, err := h.mfa.EnrollWithChallenge(r.Context(), req.MFAToken)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// EnrollConfirm godoc
+// @Summary      Confirm TOTP enrollment during login
+// @Description  Confirm the TOTP secret with a first code and complete the login. Recovery codes are returned only once.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.MFAEnrollConfirmRequest true "MFA Enroll Confirm Request"
+// @Success      200  {object}  dto.MFAEnrollConfirmResponse
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /auth/mfa/enroll/confirm [post]
+func (h *MFAHandler) EnrollConfirm(w http.ResponseWriter, r *http.Request) {
+	var req dto.MFAEnrollConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, codes, err := h.mfa.ConfirmWithChallenge(r.Context(), req.MFAToken, req.Code, clientInfo(r))
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MFAEnrollConfirmResponse{
+		UserID:        res.UserID,
+		AccessToken:   res.AccessToken,
+		RefreshToken:  res.RefreshToken,
+		TenantID:      res.TenantID,
+		RecoveryCodes: codes,
+	})
+}
+
+// GetMine godoc
+// @Summary      Get my MFA status
+// @Description  Whether TOTP is enabled, how many recovery codes remain and whether the tenant requires MFA
+// @Tags         mfa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  mfa.Status
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/mfa [get]
+func (h *MFAHandler) GetMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	status, err := h.mfa.GetStatus(r.Context(), userID)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// StartTOTP godoc
+// @Summary      Start TOTP enrollment
+// @Description  Generate a TOTP secret and its otpauth URI (to show as a QR code). It must be confirmed with a first code.
+// @Tags         mfa
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  mfa.Enrollment
+// @Failure      409  {object}  map[string]string
+// @Router       /users/me/mfa/totp [post]
+func (h *MFAHandler) StartTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.mfa.StartEnrollment(r.Context(), userID)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(enrollment)
+}
+
+// ConfirmTOTP godoc
+// @Summary      Confirm TOTP enrollment
+// @Description  Enable TOTP with a first code from the authenticator app. Recovery codes are returned only once.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.MFACodeRequest true "TOTP code"
+// @Success      200  {object}  dto.MFARecoveryCodesResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/mfa/totp/confirm [post]
+func (h *MFAHandler) ConfirmTOTP(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	codes, err := h.mfa.ConfirmEnrollment(r.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// RegenerateRecoveryCodes godoc
+// @Summary      Regenerate recovery codes
+// @Description  Invalidate the current recovery codes and return new ones. Requires a TOTP or recovery code.
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.MFACodeRequest true "TOTP or recovery code"
+// @Success      200  {object}  dto.MFARecoveryCodesResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/mfa/recovery-codes [post]
+func (h *MFAHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	codes, err := h.mfa.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.MFARecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary      Disable MFA
+// @Description  Remove TOTP and recovery codes of the authenticated user. Requires a TOTP or recovery code.
+// @Tags         mfa
+// @Accept       json
+// @Security     BearerAuth
+// @Param        request body dto.MFACodeRequest true "TOTP or recovery code"
+// @Success      204
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/mfa [delete]
+func (h *MFAHandler) Disable(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.MFACodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	if err := h.mfa.Disable(r.Context(), userID, req.Code); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Reset godoc
+// @Summary      Reset a user's MFA
+// @Description  Remove TOTP and recovery codes of a user (e.g. lost device). The user enrolls again on next login if the tenant requires MFA.
+// @Tags         mfa
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Router       /users/{id}/mfa/reset [post]
+func (h *MFAHandler) Reset(w http.ResponseWriter, r *http.Request) {
+	actorID := middlewares.GetUserID(r.Context())
+
+	if err := h.mfa.Reset(r.Context(), actorID, chi.URLParam(r, "id")); err != nil {
+		writeMFAError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// currentUserID devuelve el usuario autenticado o responde 401.
+func currentUserID(w http.ResponseWriter, r *http.Request) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(middlewares.GetUserID(r.Context()))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user context required"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+func writeMFAError(w http.ResponseWriter, err error) {
+	if writeTenantError(w, err) {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, mfa.ErrInvalidChallenge), errors.Is(err, auth.ErrInvalidMFACode):
+		status = http.StatusUnauthorized
+	case errors.Is(err, mfa.ErrEnrollmentNotAllowed):
+		status = http.StatusForbidden
+	case errors.Is(err, mfa.ErrAlreadyEnrolled):
+		status = http.StatusConflict
+	case errors.Is(err, mfa.ErrEnrollmentMissing), errors.Is(err, mfa.ErrNotEnrolled):
+		status = http.StatusBadRequest
+	case errors.Is(err, sql.ErrNoRows):
+		status = http.StatusNotFound
+		err = errors.New("user not found")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,474 @@
+package handlers
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+
+	"github.com/fzalvarez/odin-iam/internal/api/dto"
+	"github.com/fzalvarez/odin-iam/internal/api/middlewares"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/oauth"
+	"github.com/fzalvarez/odin-iam/internal/roles"
+	"github.com/go-chi/chi/v5"
+)
+
+//go:embed templates/authorize.html
+var templatesFS embed.FS
+
+var authorizeTemplate = template.Must(template.ParseFS(templatesFS, "templates/authorize.html"))
+
+type OAuthHandler struct {
+	service     *oauth.Service
+	roleService *roles.RoleService
+}
+
+func NewOAuthHandler(s *oauth.Service, roleService *roles.RoleService) *OAuthHandler {
+	return &OAuthHandler{service: s, roleService: roleService}
+}
+
+type authorizePage struct {
+	ClientName string
+	Scope      string
+	Email      string
+	Error      string
+	MFA        bool
+	Request    oauth.AuthorizeRequest
+}
+
+// Authorize godoc
+// @Summary      OAuth2 authorization endpoint
+// @Description  Validates the authorization request (response_type=code, PKCE S256 required) and renders the login form
+// @Tags         oauth
+// @Produce      html
+// @Param        response_type          query  string  true   "Must be 'code'"
+// @Param        client_id              query  string  true   "Client ID"
+// @Param        redirect_uri           query  string  true   "Registered redirect URI"
+// @Param        scope                  query  string  false  "Space separated scopes (openid profile email)"
+// @Param        state                  query  string  false  "Opaque value returned to the client"
+// @Param        nonce                  query  string  false  "Value copied into the ID token"
+// @Param        code_challenge         query  string  true   "PKCE code challenge"
+// @Param        code_challenge_method  query  string  true   "Must be 'S256'"
+// @Success      200
+// @Failure      302
+// @Failure      400  {string}  string
+// @Router       /oauth/authorize [get]
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	req := authorizeRequestFrom(r.URL.Query())
+
+	client, err := h.service.ValidateAuthorizeRequest(r.Context(), req)
+	if h.authorizeError(w, r, req, err) {
+		return
+	}
+
+	h.renderAuthorize(w, http.StatusOK, authorizePage{
+		ClientName: client.Name,
+		Scope:      req.Scope,
+		Request:    req,
+	})
+}
+
+// AuthorizeSubmit godoc
+// @Summary      OAuth2 authorization login
+// @Description  Authenticates the user from the login form and redirects to redirect_uri with an authorization code
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      html
+// @Param        email     formData  string  true  "User email"
+// @Param        password  formData  string  true  "User password"
+// @Param        mfa_code  formData  string  false "TOTP or recovery code, required when the user has MFA"
+// @Success      302
+// @Failure      400  {string}  string
+// @Router       /oauth/authorize [post]
+func (h *OAuthHandler) AuthorizeSubmit(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+	req := authorizeRequestFrom(r.PostForm)
+	email := r.PostForm.Get("email")
+
+	mfaCode := r.PostForm.Get("mfa_code")
+
+	location, err := h.service.Authorize(r.Context(), req, email, r.PostForm.Get("password"), mfaCode, clientInfo(r))
+	if err != nil {
+		// Credenciales o segundo factor incorrectos: volver a mostrar el
+		// formulario sin redirigir
+		page := authorizePage{
+			Scope:   req.Scope,
+			Email:   email,
+			Request: req,
+		}
+		status := http.StatusUnauthorized
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentials):
+			page.Error = "Email o contraseña incorrectos"
+			page.MFA = mfaCode != ""
+		case errors.Is(err, auth.ErrMFARequired):
+			page.Error = "Introduce el código de tu aplicación de autenticación"
+			page.MFA = true
+		case errors.Is(err, auth.ErrInvalidMFACode):
+			page.Error = "Código de verificación incorrecto"
+			page.MFA = true
+		case errors.Is(err, auth.ErrTooManyAttempts):
+			page.Error = "Demasiados intentos fallidos. Espera unos minutos antes de volver a intentarlo"
+		case errors.Is(err, auth.ErrEmailNotVerified):
+			page.Error = "Verifica tu email con el link que te enviamos antes de iniciar sesión"
+		case errors.Is(err, auth.ErrPasswordExpired):
+			page.Error = "Tu contraseña caducó: cámbiala en la aplicación antes de continuar"
+		case errors.Is(err, auth.ErrMFAEnrollmentRequired):
+			page.Error = "Tu organización exige verificación en dos pasos: configúrala iniciando sesión en la aplicación"
+		case errors.Is(err, auth.ErrHashPoolBusy):
+			page.Error = "El servicio está saturado. Inténtalo de nuevo en unos segundos"
+			status = http.StatusServiceUnavailable
+			w.Header().Set("Retry-After", "1")
+		default:
+			h.authorizeError(w, r, req, err)
+			return
+		}
+
+		client, _ := h.service.GetClient(r.Context(), req.ClientID)
+		page.ClientName = req.ClientID
+		if client != nil {
+			page.ClientName = client.Name
+		}
+		h.renderAuthorize(w, status, page)
+		return
+	}
+
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+// Token godoc
+// @Summary      OAuth2 token endpoint
+// @Description  Exchanges an authorization code (with PKCE verifier), a refresh token or client credentials for tokens. Clients authenticate with client_secret_basic, client_secret_post or, for public clients, client_id only. For client_credentials an API key (sk_live_...) can be sent as client_secret.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        grant_type     formData  string  true   "authorization_code | refresh_token | client_credentials"
+// @Param        code           formData  string  false  "Authorization code"
+// @Param        redirect_uri   formData  string  false  "Same redirect_uri used in /oauth/authorize"
+// @Param        code_verifier  formData  string  false  "PKCE code verifier"
+// @Param        refresh_token  formData  string  false  "Refresh token"
+// @Param        scope          formData  string  false  "Requested scopes (client_credentials)"
+// @Success      200  {object}  oauth.TokenResponse
+// @Failure      400  {object}  dto.OAuthErrorResponse
+// @Failure      401  {object}  dto.OAuthErrorResponse
+// @Router       /oauth/token [post]
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, &oauth.Error{Code: "invalid_request", Description: "invalid form body"})
+		return
+	}
+
+	clientID, clientSecret, basic := clientCredentialsFrom(r)
+
+	grantType := r.PostForm.Get("grant_type")
+
+	// client_credentials autentica también API keys, lo resuelve el servicio
+	var client *oauth.ClientModel
+	var err error
+	if grantType != oauth.GrantClientCredentials {
+		client, err = h.service.AuthenticateClient(r.Context(), clientID, clientSecret)
+	}
+
+	var res *oauth.TokenResponse
+	if err == nil {
+		switch grantType {
+		case oauth.GrantClientCredentials:
+			res, err = h.service.ClientCredentials(r.Context(), clientID, clientSecret, r.PostForm.Get("scope"))
+		case oauth.GrantAuthorizationCode:
+			res, err = h.service.ExchangeCode(r.Context(), client,
+				r.PostForm.Get("code"),
+				r.PostForm.Get("redirect_uri"),
+				r.PostForm.Get("code_verifier"),
+				clientInfo(r),
+			)
+		case oauth.GrantRefreshToken:
+			res, err = h.service.RefreshToken(r.Context(), client, r.PostForm.Get("refresh_token"), clientInfo(r))
+		default:
+			err = &oauth.Error{Code: "unsupported_grant_type", Description: "grant_type is not supported"}
+		}
+	}
+	if err != nil {
+		var oauthErr *oauth.Error
+		if basic && errors.As(err, &oauthErr) && oauthErr.Code == "invalid_client" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// Introspect godoc
+// @Summary      OAuth2 token introspection
+// @Description  RFC 7662. Describes an access token, refresh token or API key. The caller authenticates as a confidential client (client_secret_basic / client_secret_post) or with an API key sent as client_secret. Callers only see tokens of their own tenant, unless they belong to the System tenant and hold the tokens:introspect_all scope.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token to introspect"
+// @Param        token_type_hint  formData  string  false  "access_token | refresh_token | api_key"
+// @Success      200  {object}  oauth.IntrospectionResponse
+// @Failure      401  {object}  dto.OAuthErrorResponse
+// @Router       /oauth/introspect [post]
+func (h *OAuthHandler) Introspect(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, &oauth.Error{Code: "invalid_request", Description: "invalid form body"})
+		return
+	}
+
+	clientID, clientSecret, basic := clientCredentialsFrom(r)
+
+	res, err := h.service.Introspect(r.Context(), clientID, clientSecret, r.PostForm.Get("token"), r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		var oauthErr *oauth.Error
+		if errors.As(err, &oauthErr) {
+			// Cualquier fallo autenticando al llamador es invalid_client (RFC 7662 §2.3)
+			oauthErr = &oauth.Error{Code: "invalid_client", Description: oauthErr.Description}
+			if basic {
+				w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+			}
+			err = oauthErr
+		}
+		writeOAuthError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// Revoke godoc
+// @Summary      OAuth2 token revocation
+// @Description  RFC 7009. Revokes an access token (its jti is added to the denylist) or a refresh token (its session is deleted). Responds 200 also for unknown tokens.
+// @Tags         oauth
+// @Accept       x-www-form-urlencoded
+// @Produce      json
+// @Param        token            formData  string  true   "Token to revoke"
+// @Param        token_type_hint  formData  string  false  "access_token | refresh_token"
+// @Success      200
+// @Failure      400  {object}  dto.OAuthErrorResponse
+// @Failure      401  {object}  dto.OAuthErrorResponse
+// @Router       /oauth/revoke [post]
+func (h *OAuthHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, &oauth.Error{Code: "invalid_request", Description: "invalid form body"})
+		return
+	}
+
+	clientID, clientSecret, basic := clientCredentialsFrom(r)
+
+	err := h.service.Revoke(r.Context(), clientID, clientSecret, r.PostForm.Get("token"), r.PostForm.Get("token_type_hint"))
+	if err != nil {
+		var oauthErr *oauth.Error
+		if basic && errors.As(err, &oauthErr) && oauthErr.Code == "invalid_client" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="oauth"`)
+		}
+		writeOAuthError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// UserInfo godoc
+// @Summary      OpenID Connect UserInfo
+// @Description  Returns claims about the user that owns the access token
+// @Tags         oauth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  oauth.UserInfo
+// @Failure      401  {object}  map[string]string
+// @Router       /userinfo [get]
+func (h *OAuthHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	info, err := h.service.UserInfo(r.Context(), middlewares.GetUserID(r.Context()), middlewares.GetTenantID(r.Context()))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user not found"})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(info)
+}
+
+// CreateClient godoc
+// @Summary      Register OAuth client
+// @Description  Register an OAuth2 / OIDC client. The client_secret is only returned once. Without tenant_id the client belongs to the caller's tenant; only System tenant callers can register clients for other tenants. Scopes other than openid, profile and email must be permissions the caller holds in its tenant.
+// @Tags         oauth
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.CreateOAuthClientRequest true "Client"
+// @Success      201  {object}  oauth.RegisterClientResult
+// @Failure      400  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /oauth/clients [post]
+func (h *OAuthHandler) CreateClient(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateOAuthClientRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	grantable, err := middlewares.GrantablePermissions(r.Context(), h.roleService)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	by := oauth.Registrar{TenantID: middlewares.GetTenantID(r.Context()), Permissions: grantable}
+
+	res, err := h.service.RegisterClient(r.Context(), by, req.Name, req.TenantID, req.RedirectURIs, req.GrantTypes, req.Scopes, req.Confidential)
+	if errors.Is(err, oauth.ErrScopeNotAllowed) || errors.Is(err, oauth.ErrTenantNotAllowed) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(res)
+}
+
+// ListClients godoc
+// @Summary      List OAuth clients
+// @Tags         oauth
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}  oauth.ClientModel
+// @Router       /oauth/clients [get]
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	list, err := h.service.ListClients(r.Context())
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// DeleteClient godoc
+// @Summary      Delete OAuth client
+// @Tags         oauth
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Client ID"
+// @Success      200  {object}  map[string]string
+// @Router       /oauth/clients/{id} [delete]
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	if err := h.service.DeleteClient(r.Context(), chi.URLParam(r, "id")); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"status": "deleted"})
+}
+
+// ---- Helpers ----
+
+// clientCredentialsFrom lee las credenciales del cliente de Basic (client_secret_basic)
+// o del cuerpo (client_secret_post). basic indica si vinieron en la cabecera.
+func clientCredentialsFrom(r *http.Request) (clientID, clientSecret string, basic bool) {
+	clientID, clientSecret, basic = r.BasicAuth()
+	if basic {
+		// RFC 6749 §2.3.1: las credenciales van form-urlencoded dentro de Basic
+		clientID, _ = url.QueryUnescape(clientID)
+		clientSecret, _ = url.QueryUnescape(clientSecret)
+		return clientID, clientSecret, true
+	}
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), false
+}
+
+func authorizeRequestFrom(v url.Values) oauth.AuthorizeRequest {
+	return oauth.AuthorizeRequest{
+		ResponseType:        v.Get("response_type"),
+		ClientID:            v.Get("client_id"),
+		RedirectURI:         v.Get("redirect_uri"),
+		Scope:               v.Get("scope"),
+		State:               v.Get("state"),
+		Nonce:               v.Get("nonce"),
+		CodeChallenge:       v.Get("code_challenge"),
+		CodeChallengeMethod: v.Get("code_challenge_method"),
+	}
+}
+
+// authorizeError responde a un error de /oauth/authorize. Si el client_id o el
+// redirect_uri no son válidos no se redirige (evita open redirects); el resto
+// de errores se devuelven al cliente en el redirect_uri. Devuelve false si err es nil.
+func (h *OAuthHandler) authorizeError(w http.ResponseWriter, r *http.Request, req oauth.AuthorizeRequest, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	if errors.Is(err, oauth.ErrUnknownClient) || errors.Is(err, oauth.ErrInvalidRedirectURI) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return true
+	}
+
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &oauth.Error{Code: "server_error", Description: "internal error"}
+	}
+	http.Redirect(w, r, oauth.ErrorRedirect(req.RedirectURI, req.State, oauthErr), http.StatusFound)
+	return true
+}
+
+func (h *OAuthHandler) renderAuthorize(w http.ResponseWriter, status int, page authorizePage) {
+	// El formulario recibe contraseñas: no se permite embeberlo en iframes.
+	// Sin form-action: el POST termina en un redirect al redirect_uri del cliente.
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	authorizeTemplate.Execute(w, page)
+}
+
+func writeOAuthError(w http.ResponseWriter, err error) {
+	var oauthErr *oauth.Error
+	if !errors.As(err, &oauthErr) {
+		oauthErr = &oauth.Error{Code: "server_error", Description: "internal error"}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(oauthErr.Status())
+	json.NewEncoder(w).Encode(dto.OAuthErrorResponse{
+		Error:            oauthErr.Code,
+		ErrorDescription: oauthErr.Description,
+	})
+}
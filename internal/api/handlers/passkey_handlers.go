@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fzalvarez/odin-iam/internal/api/dto"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/mfa"
+	"github.com/fzalvarez/odin-iam/internal/webauthn"
+	"github.com/go-chi/chi/v5"
+)
+
+type PasskeyHandler struct {
+	webauthn *webauthn.Service
+}
+
+func NewPasskeyHandler(s *webauthn.Service) *PasskeyHandler {
+	return &PasskeyHandler{webauthn: s}
+}
+
+// RegisterBegin godoc
+// @Summary      Start passkey registration
+// @Description  Options for navigator.credentials.create() (PublicKeyCredential.parseCreationOptionsFromJSON). Valid for 5 minutes.
+// @Tags         passkeys
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {object}  webauthn.CreationOptions
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/passkeys/register/begin [post]
+func (h *PasskeyHandler) RegisterBegin(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	options, err := h.webauthn.BeginRegistration(r.Context(), userID)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(options)
+}
+
+// RegisterFinish godoc
+// @Summary      Finish passkey registration
+// @Description  Verify the authenticator response (credential.toJSON()) and store the public key
+// @Tags         passkeys
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.PasskeyRegisterRequest true "Passkey Register Request"
+// @Success      201  {object}  dto.PasskeyRegisterResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      409  {object}  map[string]string
+// @Router       /users/me/passkeys/register/finish [post]
+func (h *PasskeyHandler) RegisterFinish(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req dto.PasskeyRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	passkey, codes, err := h.webauthn.FinishRegistration(r.Context(), userID, req.Name, req.Credential)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	json.NewEncoder(w).Encode(dto.PasskeyRegisterResponse{Passkey: passkey, RecoveryCodes: codes})
+}
+
+// ListMine godoc
+// @Summary      List my passkeys
+// @Tags         passkeys
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   webauthn.Passkey
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/passkeys [get]
+func (h *PasskeyHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	list, err := h.webauthn.ListPasskeys(r.Context(), userID)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// DeleteMine godoc
+// @Summary      Delete one of my passkeys
+// @Tags         passkeys
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Passkey ID"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Router       /users/me/passkeys/{id} [delete]
+func (h *PasskeyHandler) DeleteMine(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.webauthn.DeletePasskey(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// LoginBegin godoc
+// @Summary      Start passkey login
+// @Description  Options for navigator.credentials.get() without a username (discoverable credentials, user verification required)
+// @Tags         passkeys
+// @Produce      json
+// @Success      200  {object}  webauthn.RequestOptions
+// @Router       /auth/passkey/login/begin [post]
+func (h *PasskeyHandler) LoginBegin(w http.ResponseWriter, r *http.Request) {
+	options, err := h.webauthn.BeginLogin(r.Context())
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(options)
+}
+
+// LoginFinish godoc
+// @Summary      Finish passkey login
+// @Description  Verify the passkey assertion and return the same tokens as /auth/login
+// @Tags         passkeys
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.PasskeyLoginRequest true "Passkey Login Request"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/passkey/login/finish [post]
+func (h *PasskeyHandler) LoginFinish(w http.ResponseWriter, r *http.Request) {
+	var req dto.PasskeyLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, err := h.webauthn.FinishLogin(r.Context(), req.Credential, clientInfo(r))
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+// MFABegin godoc
+// @Summary      Start passkey second factor
+// @Description  Options for navigator.credentials.get() restricted to the passkeys of the user of the mfa_token
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.PasskeyMFABeginRequest true "Passkey MFA Begin Request"
+// @Success      200  {object}  webauthn.RequestOptions
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/mfa/passkey/begin [post]
+func (h *PasskeyHandler) MFABegin(w http.ResponseWriter, r *http.Request) {
+	var req dto.PasskeyMFABeginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	options, err := h.webauthn.BeginMFA(r.Context(), req.MFAToken)
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(options)
+}
+
+// MFAFinish godoc
+// @Summary      Finish passkey second factor
+// @Description  Complete a login challenge (mfa_token) with a passkey assertion and return the same tokens as /auth/login
+// @Tags         mfa
+// @Accept       json
+// @Produce      json
+// @Param        request body dto.PasskeyMFAFinishRequest true "Passkey MFA Finish Request"
+// @Success      200  {object}  dto.LoginResponse
+// @Failure      401  {object}  map[string]string
+// @Router       /auth/mfa/passkey/finish [post]
+func (h *PasskeyHandler) MFAFinish(w http.ResponseWriter, r *http.Request) {
+	var req dto.PasskeyMFAFinishRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	res, err := h.webauthn.FinishMFA(r.Context(), req.MFAToken, req.Credential, clientInfo(r))
+	if err != nil {
+		writePasskeyError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
+func writePasskeyError(w http.ResponseWriter, err error) {
+	if writeTenantError(w, err) {
+		return
+	}
+
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, webauthn.ErrInvalidCredential), errors.Is(err, mfa.ErrInvalidChallenge):
+		status = http.StatusUnauthorized
+	case errors.Is(err, webauthn.ErrUnsupportedKey), errors.Is(err, webauthn.ErrNoPasskeys):
+		status = http.StatusBadRequest
+	case errors.Is(err, webauthn.ErrCredentialExists):
+		status = http.StatusConflict
+	case errors.Is(err, webauthn.ErrPasskeyNotFound):
+		status = http.StatusNotFound
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		status = http.StatusForbidden
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
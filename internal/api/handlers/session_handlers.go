@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fzalvarez/odin-iam/internal/api/dto"
+	"github.com/fzalvarez/odin-iam/internal/api/middlewares"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/sessions"
+	"github.com/go-chi/chi/v5"
+)
+
+type SessionHandler struct {
+	sessions *sessions.Service
+	auth     *auth.AuthService
+}
+
+func NewSessionHandler(s *sessions.Service, a *auth.AuthService) *SessionHandler {
+	return &SessionHandler{sessions: s, auth: a}
+}
+
+// ListMine godoc
+// @Summary      List my sessions
+// @Description  List the devices with an open session for the authenticated user
+// @Tags         sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Success      200  {array}   sessions.ActiveSession
+// @Failure      401  {object}  map[string]string
+// @Router       /users/me/sessions [get]
+func (h *SessionHandler) ListMine(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserID(r.Context())
+	if userID == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user context required"})
+		return
+	}
+
+	list, err := h.sessions.ListUserSessions(r.Context(), userID)
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusInternalServerError)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+// RevokeMine godoc
+// @Summary      Sign out a device
+// @Description  Close one of the authenticated user's sessions
+// @Tags         sessions
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Session ID (from GET /users/me/sessions)"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Router       /users/me/sessions/{id} [delete]
+func (h *SessionHandler) RevokeMine(w http.ResponseWriter, r *http.Request) {
+	userID := middlewares.GetUserID(r.Context())
+	if userID == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		json.NewEncoder(w).Encode(map[string]string{"error": "user context required"})
+		return
+	}
+
+	if err := h.sessions.RevokeUserSession(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, sessions.ErrSessionNotFound) {
+			status = http.StatusNotFound
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// RevokeUserSessions godoc
+// @Summary      Revoke user sessions
+// @Description  Close every session of a user and revoke their access tokens
+// @Tags         sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "User ID"
+// @Success      200  {object}  dto.RevokeSessionsResponse
+// @Failure      400  {object}  map[string]string
+// @Router       /users/{id}/sessions/revoke [post]
+func (h *SessionHandler) RevokeUserSessions(w http.ResponseWriter, r *http.Request) {
+	n, err := h.auth.RevokeUserSessions(r.Context(), middlewares.GetUserID(r.Context()), chi.URLParam(r, "id"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.RevokeSessionsResponse{RevokedSessions: n})
+}
+
+// RevokeTenantSessions godoc
+// @Summary      Revoke tenant sessions
+// @Description  Close every session of a tenant and revoke the access tokens of its users
+// @Tags         sessions
+// @Produce      json
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Tenant ID"
+// @Success      200  {object}  dto.RevokeSessionsResponse
+// @Failure      400  {object}  map[string]string
+// @Router       /tenants/{id}/sessions/revoke [post]
+func (h *SessionHandler) RevokeTenantSessions(w http.ResponseWriter, r *http.Request) {
+	n, err := h.auth.RevokeTenantSessions(r.Context(), middlewares.GetUserID(r.Context()), chi.URLParam(r, "id"))
+	if err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(dto.RevokeSessionsResponse{RevokedSessions: n})
+}
+
+// Revoke godoc
+// @Summary      Revoke session
+// @Description  Close a single session (and its refresh token rotation family)
+// @Tags         sessions
+// @Security     BearerAuth
+// @Param        id   path      string  true  "Session ID"
+// @Success      204
+// @Failure      404  {object}  map[string]string
+// @Router       /sessions/{id} [delete]
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	if err := h.sessions.RevokeSession(r.Context(), chi.URLParam(r, "id")); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, sql.ErrNoRows) {
+			status = http.StatusNotFound
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"encoding/json"
 	"net/http"
+	"strings"
 
 	"github.com/fzalvarez/odin-iam/internal/api/dto"
 	"github.com/fzalvarez/odin-iam/internal/api/middlewares" // Importar middlewares
@@ -10,6 +11,7 @@ import (
 	"github.com/fzalvarez/odin-iam/internal/roles"
 	"github.com/fzalvarez/odin-iam/internal/users"
 	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
 type UserHandler struct {
@@ -70,6 +72,67 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(res)
 }
 
+// Import godoc
+// @Summary      Import users
+// @Description  Create users migrated from another system with their original password hash (bcrypt, scrypt, PBKDF2, Firebase scrypt or Argon2id), so they keep their passwords. Hashes are upgraded to Argon2id on the first successful login. Each user is imported independently; failures are listed in the response (Requires users:import permission)
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Security     BearerAuth
+// @Param        request body dto.ImportUsersRequest true "Import Users Request"
+// @Success      200  {object}  dto.ImportUsersResponse
+// @Failure      400  {object}  map[string]string
+// @Failure      401  {object}  map[string]string
+// @Failure      403  {object}  map[string]string
+// @Router       /users/import [post]
+func (h *UserHandler) Import(w http.ResponseWriter, r *http.Request) {
+	var req dto.ImportUsersRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": "invalid request body"})
+		return
+	}
+
+	if err := req.Validate(); err != nil {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Sin tenant_id los usuarios van al tenant System (uuid.Nil)
+	var tenantID uuid.UUID
+	if req.TenantID != "" {
+		var err error
+		if tenantID, err = uuid.Parse(req.TenantID); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid tenant id"})
+			return
+		}
+	}
+
+	res := dto.ImportUsersResponse{Failed: []dto.ImportUserError{}}
+	for i, u := range req.Users {
+		fail := func(msg string) {
+			res.Failed = append(res.Failed, dto.ImportUserError{Index: i, Email: u.Email, Error: msg})
+		}
+		if strings.TrimSpace(u.Email) == "" || strings.TrimSpace(u.DisplayName) == "" {
+			fail("email and display_name are required")
+			continue
+		}
+		if _, err := h.authService.ImportUser(r.Context(), tenantID, u.DisplayName, u.Email, u.PasswordHash, u.EmailVerified); err != nil {
+			fail(err.Error())
+			continue
+		}
+		res.Imported++
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
+
 // GetByID godoc
 // @Summary      Get user by ID
 // @Description  Get user details by ID
@@ -111,7 +174,7 @@ func (h *UserHandler) GetByID(w http.ResponseWriter, r *http.Request) {
 
 // UpdateStatus godoc
 // @Summary      Update user status
-// @Description  Activate or deactivate a user (Requires users:manage_status permission)
+// @Description  Activate or deactivate a user (Requires users:manage_status permission). Deactivating closes all of the user's sessions and revokes their access tokens.
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -146,6 +209,16 @@ func (h *UserHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Desactivar corta el acceso ya emitido: sesiones y access tokens
+	if !req.IsActive {
+		if _, err := h.authService.RevokeUserSessions(r.Context(), middlewares.GetUserID(r.Context()), id); err != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
 	json.NewEncoder(w).Encode(map[string]string{"status": "updated"})
@@ -197,7 +270,7 @@ func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
 
 // ResetPassword godoc
 // @Summary      Reset user password
-// @Description  Admin reset of user password (Requires users:reset_password permission)
+// @Description  Admin reset of user password (Requires users:reset_password permission). The new password must meet the user's tenant password policy.
 // @Tags         users
 // @Accept       json
 // @Produce      json
@@ -207,6 +280,7 @@ func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
 // @Success      200  {object}  map[string]string
 // @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
+// @Failure      503  {object}  map[string]string
 // @Router       /users/{id}/password/reset [post]
 func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
@@ -232,7 +306,11 @@ func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.authService.UpdatePassword(r.Context(), id, req.NewPassword); err != nil {
+	err := h.authService.UpdatePassword(r.Context(), id, req.NewPassword)
+	if writePasswordPolicyError(w, err) || writeHashPoolBusy(w, err) {
+		return
+	}
+	if err != nil {
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/fzalvarez/odin-iam/internal/api/dto"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/oauth"
+)
+
+type WellKnownHandler struct {
+	keys *auth.KeyRing
+}
+
+func NewWellKnownHandler(k *auth.KeyRing) *WellKnownHandler {
+	return &WellKnownHandler{keys: k}
+}
+
+// JWKS godoc
+// @Summary      JSON Web Key Set
+// @Description  Public keys used to verify access tokens. Includes pre-published and retired keys still in their grace period.
+// @Tags         well-known
+// @Produce      json
+// @Success      200  {object}  auth.JWKSet
+// @Success      304
+// @Router       /.well-known/jwks.json [get]
+func (h *WellKnownHandler) JWKS(w http.ResponseWriter, r *http.Request) {
+	etag := fmt.Sprintf(`"%s"`, h.keys.JWKSVersion())
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, must-revalidate", int(h.keys.JWKSMaxAge().Seconds())))
+	w.Header().Set("ETag", etag)
+
+	if r.Header.Get("If-None-Match") == etag {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(h.keys.JWKS())
+}
+
+// OpenIDConfiguration godoc
+// @Summary      OpenID Connect discovery document
+// @Description  Issuer, JWKS location, supported algorithms and endpoint metadata
+// @Tags         well-known
+// @Produce      json
+// @Success      200  {object}  dto.OpenIDConfiguration
+// @Router       /.well-known/openid-configuration [get]
+func (h *WellKnownHandler) OpenIDConfiguration(w http.ResponseWriter, r *http.Request) {
+	issuer := auth.Issuer()
+
+	res := dto.OpenIDConfiguration{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/oauth/authorize",
+		TokenEndpoint:                     issuer + "/oauth/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		IntrospectionEndpoint:             issuer + "/oauth/introspect",
+		RevocationEndpoint:                issuer + "/oauth/revoke",
+		JwksURI:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   oauth.SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               oauth.SupportedGrantTypes,
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  auth.SupportedSigningAlgs,
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "iat", "exp", "jti", "auth_time", "nonce", "name", "email", "user_id", "tenant_id"},
+	}
+
+	w.Header().Set("Cache-Control", "public, max-age=3600")
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(res)
+}
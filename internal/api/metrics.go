@@ -0,0 +1,35 @@
+package api
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"github.com/fzalvarez/odin-iam/internal/api/handlers"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/go-chi/chi/v5"
+)
+
+// Las métricas no llevan autenticación: se sirven en un listener aparte
+// (METRICS_ADDR) que no se expone fuera del clúster, nunca en el router público.
+
+// MetricsAddrFromEnv lee METRICS_ADDR (p. ej. ":9090" o "127.0.0.1:9090").
+// Vacío = métricas desactivadas.
+func MetricsAddrFromEnv() (string, error) {
+	addr := os.Getenv("METRICS_ADDR")
+	if addr == "" {
+		return "", nil
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", fmt.Errorf("invalid METRICS_ADDR %q: %w", addr, err)
+	}
+	return addr, nil
+}
+
+// NewMetricsRouter sirve GET /metrics para Prometheus.
+func NewMetricsRouter(hashPool *auth.HashPool) *chi.Mux {
+	r := chi.NewRouter()
+	metricsHandler := handlers.NewMetricsHandler(hashPool)
+	r.Get("/metrics", metricsHandler.Metrics)
+	return r
+}
@@ -15,23 +15,14 @@ const ClaimsKey contextKey = "claims"
 
 func AuthMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authHeader := r.Header.Get("Authorization")
-		if authHeader == "" {
+		tokenStr := BearerToken(r)
+		if tokenStr == "" {
 			w.Header().Set("Content-Type", "application/json")
 			w.WriteHeader(http.StatusBadRequest)
 			json.NewEncoder(w).Encode(map[string]string{"error": "Token de autorización mal formado o ausente"})
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			w.Header().Set("Content-Type", "application/json")
-			w.WriteHeader(http.StatusBadRequest)
-			json.NewEncoder(w).Encode(map[string]string{"error": "Token de autorización mal formado o ausente"})
-			return
-		}
-
-		tokenStr := parts[1]
 		claims, err := auth.ValidateAccessToken(tokenStr)
 		if err != nil {
 			w.Header().Set("Content-Type", "application/json")
@@ -45,6 +36,16 @@ func AuthMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// BearerToken extracts the token from an "Authorization: Bearer <token>" header.
+// Returns "" if the header is missing or malformed.
+func BearerToken(r *http.Request) string {
+	parts := strings.Split(r.Header.Get("Authorization"), " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return ""
+	}
+	return parts[1]
+}
+
 // GetUserID extracts the user ID from the context
 func GetUserID(ctx context.Context) string {
 	claims, ok := ctx.Value(ClaimsKey).(*auth.Claims)
@@ -62,3 +63,12 @@ func GetTenantID(ctx context.Context) string {
 	}
 	return claims.TenantID
 }
+
+// GetClientID extracts the machine client ID from the context (empty for user tokens)
+func GetClientID(ctx context.Context) string {
+	claims, ok := ctx.Value(ClaimsKey).(*auth.Claims)
+	if !ok {
+		return ""
+	}
+	return claims.ClientID
+}
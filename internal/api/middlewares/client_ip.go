@@ -0,0 +1,93 @@
+package middlewares
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// trustedProxies son las redes cuyos X-Forwarded-For se aceptan, instaladas al
+// arrancar con UseTrustedProxies. Sin proxies de confianza se usa RemoteAddr.
+var trustedProxies []*net.IPNet
+
+// UseTrustedProxies installs the networks whose X-Forwarded-For header is trusted by ClientIP.
+func UseTrustedProxies(nets []*net.IPNet) {
+	trustedProxies = nets
+}
+
+// TrustedProxiesFromEnv lee TRUSTED_PROXIES: lista de CIDRs o IPs separadas por
+// comas (ej. "10.0.0.0/8,127.0.0.1").
+func TrustedProxiesFromEnv() ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	for _, v := range strings.Split(os.Getenv("TRUSTED_PROXIES"), ",") {
+		v = strings.TrimSpace(v)
+		if v == "" {
+			continue
+		}
+		if !strings.Contains(v, "/") {
+			ip := net.ParseIP(v)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q", v)
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			v = fmt.Sprintf("%s/%d", v, bits)
+		}
+		_, n, err := net.ParseCIDR(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", v, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+// ClientIP returns the IP of the client that sent the request.
+// X-Forwarded-For is only honored when the peer is a trusted proxy: the header is
+// walked right to left, skipping trusted proxies, and the first untrusted address wins.
+func ClientIP(r *http.Request) string {
+	ip := remoteIP(r.RemoteAddr)
+	if !isTrustedProxy(ip) {
+		return ip
+	}
+
+	// Cada proxy agrega la IP que le conectó al final: lo de la izquierda lo
+	// controla el cliente y no se puede creer.
+	hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if net.ParseIP(hop) == nil {
+			break
+		}
+		ip = hop
+		if !isTrustedProxy(hop) {
+			break
+		}
+	}
+	return ip
+}
+
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+func isTrustedProxy(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, n := range trustedProxies {
+		if n.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,15 +1,82 @@
 package middlewares
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
+	"strings"
 
 	"github.com/fzalvarez/odin-iam/internal/auth"
 	"github.com/fzalvarez/odin-iam/internal/roles"
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
 )
 
+// systemTenantID es el tenant System (uuid.Nil): sus credenciales administran todos los tenants.
+var systemTenantID = uuid.Nil.String()
+
+// TenantOf devuelve el tenant del recurso al que accede la petición. nil en
+// RequirePermission indica una ruta global (sin tenant), por ejemplo crear tenants.
+type TenantOf func(r *http.Request) (string, error)
+
+// TenantOfToken: la ruta actúa sobre el tenant del propio access token (ej. /apikeys).
+func TenantOfToken(r *http.Request) (string, error) {
+	return GetTenantID(r.Context()), nil
+}
+
+// TenantOfURLParam: el tenant es un parámetro de la ruta (ej. /tenants/{id}/config).
+func TenantOfURLParam(name string) TenantOf {
+	return func(r *http.Request) (string, error) {
+		return chi.URLParam(r, name), nil
+	}
+}
+
+// TenantOfQuery: el tenant va en el query string (ej. GET /users?tenant_id=).
+func TenantOfQuery(name string) TenantOf {
+	return func(r *http.Request) (string, error) {
+		return r.URL.Query().Get(name), nil
+	}
+}
+
+// TenantOfBody: el tenant es el campo "tenant_id" del body JSON; vacío es el
+// tenant System. El body se vuelve a dejar disponible para el handler.
+func TenantOfBody(r *http.Request) (string, error) {
+	if r.Body == nil {
+		return systemTenantID, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRateLimitBody))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+
+	var req struct {
+		TenantID string `json:"tenant_id"`
+	}
+	if json.Unmarshal(body, &req) != nil || req.TenantID == "" {
+		return systemTenantID, nil
+	}
+	return req.TenantID, nil
+}
+
+// TenantOfResource: el tenant es el del recurso {id} de la ruta, que devuelve
+// lookup. Un recurso que no existe no es de ningún tenant.
+func TenantOfResource(lookup func(ctx context.Context, id string) (string, error)) TenantOf {
+	return func(r *http.Request) (string, error) {
+		tenantID, err := lookup(r.Context(), chi.URLParam(r, "id"))
+		if err != nil {
+			return "", nil
+		}
+		return tenantID, nil
+	}
+}
+
 // RequirePermission crea un middleware que verifica si el usuario autenticado tiene un permiso específico.
-func RequirePermission(service *roles.RoleService, permission string) func(next http.Handler) http.Handler {
+// tenantOf indica el tenant del recurso para los tokens de máquina (ver TenantOf).
+func RequirePermission(service *roles.RoleService, permission string, tenantOf TenantOf) func(next http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Obtener claims del contexto (inyectados por AuthMiddleware)
@@ -21,6 +88,34 @@ func RequirePermission(service *roles.RoleService, permission string) func(next
 				return
 			}
 
+			// Tokens de máquina (client_credentials): el permiso debe estar entre los scopes
+			// concedidos y el recurso debe ser de su tenant. Solo los del tenant System
+			// acceden a otros tenants y a las rutas globales.
+			if claims.IsClient() {
+				allowed := claims.HasScope(permission)
+				if allowed && claims.TenantID != systemTenantID {
+					allowed = false
+					if tenantOf != nil {
+						tenantID, err := tenantOf(r)
+						if err != nil {
+							w.Header().Set("Content-Type", "application/json")
+							w.WriteHeader(http.StatusInternalServerError)
+							json.NewEncoder(w).Encode(map[string]string{"error": "error checking permissions"})
+							return
+						}
+						allowed = tenantID == claims.TenantID
+					}
+				}
+				if !allowed {
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusForbidden)
+					json.NewEncoder(w).Encode(map[string]string{"error": "permission denied"})
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+
 			// Verificar permiso usando el servicio de roles
 			// claims.Subject contiene el UserID (estándar JWT)
 			// Corregido: HasPermission -> CheckPermission y claims.Subject -> claims.UserID (según auth_middleware)
@@ -43,3 +138,17 @@ func RequirePermission(service *roles.RoleService, permission string) func(next
 		})
 	}
 }
+
+// GrantablePermissions devuelve los permisos que el llamador puede delegar en
+// una API key o un cliente OAuth: los scopes de un token de máquina, o los
+// permisos que el usuario tiene en el tenant de su access token.
+func GrantablePermissions(ctx context.Context, service *roles.RoleService) ([]string, error) {
+	claims, ok := ctx.Value(ClaimsKey).(*auth.Claims)
+	if !ok {
+		return nil, nil
+	}
+	if claims.IsClient() {
+		return strings.Fields(claims.Scope), nil
+	}
+	return service.GetUserPermissionsInTenant(ctx, claims.UserID, claims.TenantID)
+}
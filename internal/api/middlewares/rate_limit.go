@@ -0,0 +1,138 @@
+package middlewares
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/apikeys"
+	"github.com/fzalvarez/odin-iam/internal/ratelimit"
+)
+
+// Tamaño máximo del body que se lee para obtener el email.
+const maxRateLimitBody = 1 << 20
+
+// KeyFunc devuelve la clave del bucket para la petición, o "" si la política no
+// aplica (por ejemplo, una petición sin email).
+type KeyFunc func(r *http.Request) string
+
+// RateLimitPolicy limita las peticiones que comparten clave.
+type RateLimitPolicy struct {
+	Name  string // distingue los buckets de políticas distintas
+	Limit ratelimit.Limit
+	Key   KeyFunc
+}
+
+// KeyIP agrupa por IP del cliente (ver ClientIP).
+func KeyIP(r *http.Request) string {
+	return ClientIP(r)
+}
+
+// KeyEmail agrupa por el campo "email" del body (JSON o formulario), en
+// minúsculas. Un body JSON se vuelve a dejar disponible para el handler.
+func KeyEmail(r *http.Request) string {
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/x-www-form-urlencoded") {
+		return strings.ToLower(strings.TrimSpace(r.PostFormValue("email")))
+	}
+	if r.Body == nil {
+		return ""
+	}
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxRateLimitBody))
+	r.Body.Close()
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return ""
+	}
+
+	var req struct {
+		Email string `json:"email"`
+	}
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	return strings.ToLower(strings.TrimSpace(req.Email))
+}
+
+// KeyAPIKey agrupa por la API key enviada como client_secret (Basic o form) en
+// /oauth/token. Se usa su hash: la clave del bucket no debe contener el secreto.
+func KeyAPIKey(r *http.Request) string {
+	_, secret, ok := r.BasicAuth()
+	if !ok {
+		secret = r.PostFormValue("client_secret")
+	}
+	if !strings.HasPrefix(secret, apikeys.KeyPrefix) {
+		return ""
+	}
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// KeyTenant agrupa por el tenant del access token. Debe ir después de AuthMiddleware.
+func KeyTenant(r *http.Request) string {
+	return GetTenantID(r.Context())
+}
+
+// RateLimit aplica las políticas en orden; la primera que se agota responde 429
+// con Retry-After. Las respuestas llevan RateLimit-Limit, RateLimit-Remaining y
+// RateLimit-Reset de la política más cercana a agotarse. Un error del store no
+// bloquea la petición.
+func RateLimit(store ratelimit.Store, policies ...RateLimitPolicy) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var tightest *ratelimit.Result
+			var tightestPolicy RateLimitPolicy
+
+			for _, p := range policies {
+				key := p.Key(r)
+				if key == "" {
+					continue
+				}
+
+				res, err := store.Take(r.Context(), p.Name+":"+key, p.Limit)
+				if err != nil {
+					log.Printf("⚠️  Error en rate limit %s: %v", p.Name, err)
+					continue
+				}
+
+				if !res.Allowed {
+					setRateLimitHeaders(w, p, res)
+					w.Header().Set("Retry-After", strconv.Itoa(seconds(res.RetryAfter)))
+					w.Header().Set("Content-Type", "application/json")
+					w.WriteHeader(http.StatusTooManyRequests)
+					json.NewEncoder(w).Encode(map[string]string{"error": "too many requests, try again later"})
+					return
+				}
+				if tightest == nil || res.Remaining < tightest.Remaining {
+					tightest, tightestPolicy = &res, p
+				}
+			}
+
+			if tightest != nil {
+				setRateLimitHeaders(w, tightestPolicy, *tightest)
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// setRateLimitHeaders escribe las cabeceras del draft IETF "RateLimit header
+// fields for HTTP" (RateLimit-Limit / -Remaining / -Reset / -Policy).
+func setRateLimitHeaders(w http.ResponseWriter, p RateLimitPolicy, res ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(seconds(res.Reset)))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", p.Limit.Burst, seconds(p.Limit.Per)))
+}
+
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
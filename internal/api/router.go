@@ -1,15 +1,25 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/fzalvarez/odin-iam/internal/api/handlers"
 	"github.com/fzalvarez/odin-iam/internal/api/middlewares"
 	"github.com/fzalvarez/odin-iam/internal/apikeys"
 	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/lockout"
+	"github.com/fzalvarez/odin-iam/internal/mfa"
+	"github.com/fzalvarez/odin-iam/internal/oauth"
+	"github.com/fzalvarez/odin-iam/internal/passwordless"
+	"github.com/fzalvarez/odin-iam/internal/passwordreset"
+	"github.com/fzalvarez/odin-iam/internal/ratelimit"
 	"github.com/fzalvarez/odin-iam/internal/roles"
+	"github.com/fzalvarez/odin-iam/internal/sessions"
 	"github.com/fzalvarez/odin-iam/internal/tenants"
 	"github.com/fzalvarez/odin-iam/internal/users"
+	"github.com/fzalvarez/odin-iam/internal/verification"
+	"github.com/fzalvarez/odin-iam/internal/webauthn"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	httpSwagger "github.com/swaggo/http-swagger"
@@ -18,11 +28,21 @@ import (
 )
 
 type RouterParams struct {
-	AuthService   *auth.AuthService
-	UserService   *users.Service
-	TenantService *tenants.Service
-	RoleService   *roles.RoleService
-	APIKeyService *apikeys.Service // Nuevo servicio
+	AuthService          *auth.AuthService
+	UserService          *users.Service
+	TenantService        *tenants.Service
+	RoleService          *roles.RoleService
+	APIKeyService        *apikeys.Service // Nuevo servicio
+	KeyRing              *auth.KeyRing
+	OAuthService         *oauth.Service
+	PasswordlessService  *passwordless.Service
+	PasswordResetService *passwordreset.Service
+	SessionService       *sessions.Service
+	MFAService           *mfa.Service
+	WebAuthnService      *webauthn.Service
+	VerificationService  *verification.Service
+	LockoutService       *lockout.Service
+	RateLimitStore       ratelimit.Store // nil = en memoria
 }
 
 func NewRouter(p RouterParams) *chi.Mux {
@@ -41,50 +61,166 @@ func NewRouter(p RouterParams) *chi.Mux {
 	})
 
 	// Handlers
-	authHandler := handlers.NewAuthHandler(p.AuthService)
+	authHandler := handlers.NewAuthHandler(p.AuthService, p.PasswordlessService, p.PasswordResetService, p.VerificationService)
 	userHandler := handlers.NewUserHandler(p.UserService, p.AuthService, p.RoleService) // Actualizado
 	tenantHandler := handlers.NewTenantHandler(p.TenantService)
 	roleHandler := handlers.NewRoleHandler(p.RoleService)
-	apikeyHandler := handlers.NewAPIKeyHandler(p.APIKeyService) // Nuevo handler
+	apikeyHandler := handlers.NewAPIKeyHandler(p.APIKeyService, p.RoleService) // Nuevo handler
+	wellKnownHandler := handlers.NewWellKnownHandler(p.KeyRing)
+	oauthHandler := handlers.NewOAuthHandler(p.OAuthService, p.RoleService)
+	sessionHandler := handlers.NewSessionHandler(p.SessionService, p.AuthService)
+	mfaHandler := handlers.NewMFAHandler(p.MFAService)
+	passkeyHandler := handlers.NewPasskeyHandler(p.WebAuthnService)
+	lockoutHandler := handlers.NewLockoutHandler(p.LockoutService)
+
+	// Rate limiting de los endpoints públicos: cada login cuesta un hash Argon2
+	// de 64 MB y varios endpoints envían emails
+	limits := p.RateLimitStore
+	if limits == nil {
+		limits = ratelimit.NewMemoryStore()
+	}
+	var (
+		ipStrict  = middlewares.RateLimitPolicy{Name: "ip_strict", Limit: ratelimit.PerMinute(10), Key: middlewares.KeyIP}
+		ipAuth    = middlewares.RateLimitPolicy{Name: "ip_auth", Limit: ratelimit.PerMinute(30), Key: middlewares.KeyIP}
+		ipRefresh = middlewares.RateLimitPolicy{Name: "ip_refresh", Limit: ratelimit.PerMinute(60), Key: middlewares.KeyIP}
+		ipSignup  = middlewares.RateLimitPolicy{Name: "ip_signup", Limit: ratelimit.PerHour(20), Key: middlewares.KeyIP}
+		email     = middlewares.RateLimitPolicy{Name: "email", Limit: ratelimit.PerMinute(10), Key: middlewares.KeyEmail}
+		apiKey    = middlewares.RateLimitPolicy{Name: "api_key", Limit: ratelimit.PerMinute(60), Key: middlewares.KeyAPIKey}
+		tenant    = middlewares.RateLimitPolicy{Name: "tenant", Limit: ratelimit.PerMinute(1200), Key: middlewares.KeyTenant}
+	)
+	limit := func(policies ...middlewares.RateLimitPolicy) func(http.Handler) http.Handler {
+		return middlewares.RateLimit(limits, policies...)
+	}
+
+	// Descubrimiento / verificación local de tokens por otros servicios
+	r.Get("/.well-known/jwks.json", wellKnownHandler.JWKS)
+	r.Get("/.well-known/openid-configuration", wellKnownHandler.OpenIDConfiguration)
 
 	// Public endpoints
-	r.Post("/auth/register", authHandler.Register)
-	r.Post("/auth/login", authHandler.Login)
-	r.Post("/auth/refresh", authHandler.Refresh)
+	r.With(limit(ipSignup, email)).Post("/auth/register", authHandler.Register)
+	r.With(limit(ipAuth, email)).Post("/auth/login", authHandler.Login)
+	r.With(limit(ipRefresh)).Post("/auth/refresh", authHandler.Refresh)
 	r.Post("/auth/logout", authHandler.Logout) // Nueva ruta
+	r.With(limit(ipStrict, email)).Post("/auth/magic-link", authHandler.MagicLink)
+	r.With(limit(ipAuth)).Post("/auth/magic-link/verify", authHandler.MagicLinkVerify)
+	r.With(limit(ipStrict, email)).Post("/auth/otp", authHandler.OTP)
+	r.With(limit(ipAuth, email)).Post("/auth/otp/verify", authHandler.OTPVerify)
+	r.With(limit(ipAuth)).Post("/auth/email/verify", authHandler.EmailVerify)
+	r.With(limit(ipStrict, email)).Post("/auth/email/verify/resend", authHandler.EmailVerifyResend)
+	r.With(limit(ipStrict, email)).Post("/auth/password/forgot", authHandler.PasswordForgot)
+	r.With(limit(ipAuth)).Post("/auth/password/reset", authHandler.PasswordReset)
+	r.With(limit(ipAuth, email)).Post("/auth/password/change", authHandler.PasswordChange)
+	r.With(limit(ipAuth)).Post("/auth/mfa/verify", mfaHandler.Verify)
+	r.With(limit(ipAuth)).Post("/auth/mfa/enroll", mfaHandler.Enroll)
+	r.With(limit(ipAuth)).Post("/auth/mfa/enroll/confirm", mfaHandler.EnrollConfirm)
+	r.With(limit(ipAuth)).Post("/auth/mfa/passkey/begin", passkeyHandler.MFABegin)
+	r.With(limit(ipAuth)).Post("/auth/mfa/passkey/finish", passkeyHandler.MFAFinish)
+	r.With(limit(ipAuth)).Post("/auth/passkey/login/begin", passkeyHandler.LoginBegin)
+	r.With(limit(ipAuth)).Post("/auth/passkey/login/finish", passkeyHandler.LoginFinish)
+
+	// OAuth2 / OpenID Connect
+	r.Get("/oauth/authorize", oauthHandler.Authorize)
+	r.With(limit(ipAuth, email)).Post("/oauth/authorize", oauthHandler.AuthorizeSubmit)
+	r.With(limit(ipRefresh, apiKey)).Post("/oauth/token", oauthHandler.Token)
+	r.With(limit(ipAuth, apiKey)).Post("/oauth/introspect", oauthHandler.Introspect) // Autenticado con credenciales de cliente o API key
+	r.With(limit(ipAuth, apiKey)).Post("/oauth/revoke", oauthHandler.Revoke)
 
 	// Swagger UI
 	r.Get("/swagger/*", httpSwagger.WrapHandler)
 
+	// Tenant del recurso de cada ruta: los tokens de máquina solo acceden a los de su tenant
+	var (
+		userTenant = middlewares.TenantOfResource(func(ctx context.Context, id string) (string, error) {
+			u, err := p.UserService.GetUserByID(ctx, id)
+			if err != nil {
+				return "", err
+			}
+			return u.TenantID, nil
+		})
+		tenantParam   = middlewares.TenantOfURLParam("id")
+		sessionTenant = middlewares.TenantOfResource(func(ctx context.Context, id string) (string, error) {
+			sess, err := p.SessionService.GetSessionByID(ctx, id)
+			if err != nil {
+				return "", err
+			}
+			return sess.TenantID, nil
+		})
+		apiKeyTenant = middlewares.TenantOfResource(func(ctx context.Context, id string) (string, error) {
+			k, err := p.APIKeyService.Get(ctx, id)
+			if err != nil {
+				return "", err
+			}
+			return k.TenantID, nil
+		})
+		clientTenant = middlewares.TenantOfResource(func(ctx context.Context, id string) (string, error) {
+			c, err := p.OAuthService.GetClient(ctx, id)
+			if err != nil {
+				return "", err
+			}
+			return c.TenantID, nil
+		})
+	)
+
 	// Protected endpoints
 	r.Group(func(r chi.Router) {
 		r.Use(middlewares.AuthMiddleware)
+		r.Use(limit(tenant))
+
+		r.Post("/auth/logout-all", authHandler.LogoutAll)
+		r.Post("/auth/switch-tenant", authHandler.SwitchTenant)
 
 		// Users
 		// Ejemplo: Solo usuarios con permiso 'users:create' pueden crear usuarios
-		r.With(middlewares.RequirePermission(p.RoleService, "users:create")).Post("/users", userHandler.Create)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:create", middlewares.TenantOfBody)).Post("/users", userHandler.Create)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:import", middlewares.TenantOfBody)).Post("/users/import", userHandler.Import)
 		r.Get("/users/me/permissions", userHandler.GetPermissions) // Nueva ruta
-		r.With(middlewares.RequirePermission(p.RoleService, "users:list")).Get("/users", userHandler.List)
+		r.Get("/users/me/sessions", sessionHandler.ListMine)
+		r.Delete("/users/me/sessions/{id}", sessionHandler.RevokeMine)
+		r.Get("/users/me/mfa", mfaHandler.GetMine)
+		r.Delete("/users/me/mfa", mfaHandler.Disable)
+		r.Post("/users/me/mfa/totp", mfaHandler.StartTOTP)
+		r.Post("/users/me/mfa/totp/confirm", mfaHandler.ConfirmTOTP)
+		r.Post("/users/me/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+		r.Get("/users/me/passkeys", passkeyHandler.ListMine)
+		r.Post("/users/me/passkeys/register/begin", passkeyHandler.RegisterBegin)
+		r.Post("/users/me/passkeys/register/finish", passkeyHandler.RegisterFinish)
+		r.Delete("/users/me/passkeys/{id}", passkeyHandler.DeleteMine)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:list", middlewares.TenantOfQuery("tenant_id"))).Get("/users", userHandler.List)
 		r.Get("/users/{id}", userHandler.GetByID)
-		r.With(middlewares.RequirePermission(p.RoleService, "users:manage_status")).Put("/users/{id}/status", userHandler.UpdateStatus)
-		r.With(middlewares.RequirePermission(p.RoleService, "users:reset_password")).Post("/users/{id}/password/reset", userHandler.ResetPassword)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:manage_status", userTenant)).Put("/users/{id}/status", userHandler.UpdateStatus)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:reset_password", userTenant)).Post("/users/{id}/password/reset", userHandler.ResetPassword)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:reset_mfa", userTenant)).Post("/users/{id}/mfa/reset", mfaHandler.Reset)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:unlock", userTenant)).Post("/users/{id}/unlock", lockoutHandler.UnlockUser)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:unlock", nil)).Get("/lockouts", lockoutHandler.List)
+		r.With(middlewares.RequirePermission(p.RoleService, "users:unlock", nil)).Delete("/lockouts/ips/{ip}", lockoutHandler.UnlockIP)
 
 		// Tenants
-		r.With(middlewares.RequirePermission(p.RoleService, "tenants:create")).Post("/tenants", tenantHandler.Create)
-		r.With(middlewares.RequirePermission(p.RoleService, "tenants:list")).Get("/tenants", tenantHandler.List)
+		r.With(middlewares.RequirePermission(p.RoleService, "tenants:create", nil)).Post("/tenants", tenantHandler.Create)
+		r.With(middlewares.RequirePermission(p.RoleService, "tenants:list", nil)).Get("/tenants", tenantHandler.List)
 		r.Get("/tenants/{id}", tenantHandler.GetByID)
-		r.With(middlewares.RequirePermission(p.RoleService, "tenants:manage_status")).Put("/tenants/{id}/status", tenantHandler.UpdateStatus)
-		r.With(middlewares.RequirePermission(p.RoleService, "tenants:manage_config")).Put("/tenants/{id}/config", tenantHandler.UpdateConfig)
+		r.With(middlewares.RequirePermission(p.RoleService, "tenants:manage_status", tenantParam)).Put("/tenants/{id}/status", tenantHandler.UpdateStatus)
+		r.With(middlewares.RequirePermission(p.RoleService, "tenants:manage_config", tenantParam)).Put("/tenants/{id}/config", tenantHandler.UpdateConfig)
 
 		// Roles (RBAC)
-		r.With(middlewares.RequirePermission(p.RoleService, "roles:create")).Post("/roles", roleHandler.Create)
+		r.With(middlewares.RequirePermission(p.RoleService, "roles:create", middlewares.TenantOfBody)).Post("/roles", roleHandler.Create)
 		r.Get("/roles/{id}", roleHandler.GetByID)
-		r.With(middlewares.RequirePermission(p.RoleService, "roles:assign")).Post("/users/{id}/roles", roleHandler.AssignToUser)
+		r.With(middlewares.RequirePermission(p.RoleService, "roles:assign", userTenant)).Post("/users/{id}/roles", roleHandler.AssignToUser)
 
 		// API Keys
-		r.With(middlewares.RequirePermission(p.RoleService, "apikeys:create")).Post("/apikeys", apikeyHandler.Create)
-		r.With(middlewares.RequirePermission(p.RoleService, "apikeys:list")).Get("/apikeys", apikeyHandler.List)
-		r.With(middlewares.RequirePermission(p.RoleService, "apikeys:delete")).Delete("/apikeys/{id}", apikeyHandler.Delete)
+		r.With(middlewares.RequirePermission(p.RoleService, "apikeys:create", middlewares.TenantOfToken)).Post("/apikeys", apikeyHandler.Create)
+		r.With(middlewares.RequirePermission(p.RoleService, "apikeys:list", middlewares.TenantOfToken)).Get("/apikeys", apikeyHandler.List)
+		r.With(middlewares.RequirePermission(p.RoleService, "apikeys:delete", apiKeyTenant)).Delete("/apikeys/{id}", apikeyHandler.Delete)
+
+		// Sesiones (revocación administrativa)
+		r.With(middlewares.RequirePermission(p.RoleService, "sessions:revoke", userTenant)).Post("/users/{id}/sessions/revoke", sessionHandler.RevokeUserSessions)
+		r.With(middlewares.RequirePermission(p.RoleService, "sessions:revoke", tenantParam)).Post("/tenants/{id}/sessions/revoke", sessionHandler.RevokeTenantSessions)
+		r.With(middlewares.RequirePermission(p.RoleService, "sessions:revoke", sessionTenant)).Delete("/sessions/{id}", sessionHandler.Revoke)
+
+		// OAuth / OIDC
+		r.Get("/userinfo", oauthHandler.UserInfo)
+		r.With(middlewares.RequirePermission(p.RoleService, "oauth_clients:create", middlewares.TenantOfToken)).Post("/oauth/clients", oauthHandler.CreateClient)
+		r.With(middlewares.RequirePermission(p.RoleService, "oauth_clients:list", nil)).Get("/oauth/clients", oauthHandler.ListClients)
+		r.With(middlewares.RequirePermission(p.RoleService, "oauth_clients:delete", clientTenant)).Delete("/oauth/clients/{id}", oauthHandler.DeleteClient)
 	})
 
 	return r
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"strings"
 	"time"
 
 	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
@@ -18,7 +19,7 @@ func NewRepository(db gen.DBTX) *Repository {
 	return &Repository{q: gen.New(db)}
 }
 
-func (r *Repository) Create(ctx context.Context, name string, tenantID uuid.UUID, keyHash, prefix string, expiresAt *time.Time) (*gen.ApiKey, error) {
+func (r *Repository) Create(ctx context.Context, name string, tenantID uuid.UUID, keyHash, prefix string, scopes []string, expiresAt *time.Time) (*gen.ApiKey, error) {
 	apikey, err := r.q.CreateAPIKey(ctx, gen.CreateAPIKeyParams{
 		ID:        uuid.New(),
 		Name:      name,
@@ -27,6 +28,7 @@ func (r *Repository) Create(ctx context.Context, name string, tenantID uuid.UUID
 		Prefix:    prefix,
 		IsActive:  true,
 		CreatedAt: time.Now(),
+		Scopes:    strings.Join(scopes, " "),
 		// ExpiresAt: expiresAt, // Ajustar según tipo generado
 	})
 	if err != nil {
@@ -39,6 +41,26 @@ func (r *Repository) ListByTenant(ctx context.Context, tenantID uuid.UUID) ([]ge
 	return r.q.ListAPIKeysByTenant(ctx, tenantID)
 }
 
+func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*gen.ApiKey, error) {
+	apikey, err := r.q.GetAPIKeyByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &apikey, nil
+}
+
+func (r *Repository) GetByHash(ctx context.Context, keyHash string) (*gen.ApiKey, error) {
+	apikey, err := r.q.GetAPIKeyByHash(ctx, keyHash)
+	if err != nil {
+		return nil, err
+	}
+	return &apikey, nil
+}
+
+func (r *Repository) MarkUsed(ctx context.Context, id uuid.UUID) error {
+	return r.q.UpdateAPIKeyLastUsed(ctx, id)
+}
+
 func (r *Repository) Delete(ctx context.Context, id uuid.UUID) error {
 	return r.q.DeleteAPIKey(ctx, id)
 }
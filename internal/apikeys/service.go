@@ -4,12 +4,25 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
 	"github.com/google/uuid"
 )
 
+// KeyPrefix identifica las API keys en claro (sk_live_<random>).
+const KeyPrefix = "sk_live_"
+
+// ErrInvalidKey se devuelve si la key no existe, está desactivada o expiró.
+var ErrInvalidKey = errors.New("invalid api key")
+
+// ErrScopeNotAllowed: se pidió un scope que quien crea la key no tiene.
+var ErrScopeNotAllowed = errors.New("scope not allowed")
+
 type Service struct {
 	repo *Repository
 }
@@ -19,10 +32,13 @@ func NewService(repo *Repository) *Service {
 }
 
 type APIKeyModel struct {
-	ID        string    `json:"id"`
-	Name      string    `json:"name"`
-	Prefix    string    `json:"prefix"`
-	CreatedAt time.Time `json:"created_at"`
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	TenantID  string     `json:"tenant_id"`
+	Prefix    string     `json:"prefix"`
+	Scopes    []string   `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
 	// No devolvemos el hash ni la key completa en listados
 }
 
@@ -31,34 +47,37 @@ type CreateAPIKeyResponse struct {
 	RawKey string `json:"raw_key"` // Solo se devuelve una vez al crear
 }
 
-func (s *Service) Create(ctx context.Context, tenantID string, name string) (*CreateAPIKeyResponse, error) {
+// Create crea una API key del tenant. grantable son los permisos de quien la
+// crea en ese tenant: una key no puede tener scopes que su creador no tiene.
+func (s *Service) Create(ctx context.Context, tenantID string, name string, scopes, grantable []string) (*CreateAPIKeyResponse, error) {
 	tid, err := uuid.Parse(tenantID)
 	if err != nil {
 		return nil, err
 	}
 
+	for _, scope := range scopes {
+		if !slices.Contains(grantable, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+
 	// Generar Key: sk_live_<random>
 	randomBytes := make([]byte, 32)
 	rand.Read(randomBytes)
 	randomStr := hex.EncodeToString(randomBytes)
-	rawKey := fmt.Sprintf("sk_live_%s", randomStr)
-	prefix := "sk_live_" + randomStr[:4]
+	rawKey := fmt.Sprintf("%s%s", KeyPrefix, randomStr)
+	prefix := KeyPrefix + randomStr[:4]
 
 	keyHash := HashKey(rawKey)
 
-	apiKey, err := s.repo.Create(ctx, name, tid, keyHash, prefix, nil)
+	apiKey, err := s.repo.Create(ctx, name, tid, keyHash, prefix, scopes, nil)
 	if err != nil {
 		return nil, err
 	}
 
 	return &CreateAPIKeyResponse{
-		APIKeyModel: APIKeyModel{
-			ID:        apiKey.ID.String(),
-			Name:      apiKey.Name,
-			Prefix:    apiKey.Prefix,
-			CreatedAt: apiKey.CreatedAt,
-		},
-		RawKey: rawKey,
+		APIKeyModel: toModel(apiKey),
+		RawKey:      rawKey,
 	}, nil
 }
 
@@ -74,17 +93,63 @@ func (s *Service) List(ctx context.Context, tenantID string) ([]APIKeyModel, err
 	}
 
 	var result []APIKeyModel
-	for _, k := range keys {
-		result = append(result, APIKeyModel{
-			ID:        k.ID.String(),
-			Name:      k.Name,
-			Prefix:    k.Prefix,
-			CreatedAt: k.CreatedAt,
-		})
+	for i := range keys {
+		result = append(result, toModel(&keys[i]))
 	}
 	return result, nil
 }
 
+// Get busca una API key por su ID.
+func (s *Service) Get(ctx context.Context, id string) (*APIKeyModel, error) {
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, err
+	}
+
+	apiKey, err := s.repo.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	m := toModel(apiKey)
+	return &m, nil
+}
+
+// Lookup busca una API key en claro y comprueba que siga activa y vigente.
+func (s *Service) Lookup(ctx context.Context, rawKey string) (*APIKeyModel, error) {
+	if !strings.HasPrefix(rawKey, KeyPrefix) {
+		return nil, ErrInvalidKey
+	}
+
+	apiKey, err := s.repo.GetByHash(ctx, HashKey(rawKey))
+	if err != nil {
+		return nil, ErrInvalidKey
+	}
+
+	if !apiKey.IsActive {
+		return nil, ErrInvalidKey
+	}
+	if apiKey.ExpiresAt.Valid && time.Now().After(apiKey.ExpiresAt.Time) {
+		return nil, ErrInvalidKey
+	}
+
+	m := toModel(apiKey)
+	return &m, nil
+}
+
+// Verify es Lookup para autenticar con la key: además registra el uso (last_used_at).
+func (s *Service) Verify(ctx context.Context, rawKey string) (*APIKeyModel, error) {
+	key, err := s.Lookup(ctx, rawKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.MarkUsed(ctx, uuid.MustParse(key.ID)); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
 func (s *Service) Delete(ctx context.Context, id string) error {
 	uid, err := uuid.Parse(id)
 	if err != nil {
@@ -92,3 +157,18 @@ func (s *Service) Delete(ctx context.Context, id string) error {
 	}
 	return s.repo.Delete(ctx, uid)
 }
+
+func toModel(k *gen.ApiKey) APIKeyModel {
+	m := APIKeyModel{
+		ID:        k.ID.String(),
+		Name:      k.Name,
+		TenantID:  k.TenantID.String(),
+		Prefix:    k.Prefix,
+		Scopes:    strings.Fields(k.Scopes),
+		CreatedAt: k.CreatedAt,
+	}
+	if k.ExpiresAt.Valid {
+		m.ExpiresAt = &k.ExpiresAt.Time
+	}
+	return m
+}
@@ -0,0 +1,34 @@
+package audit
+
+// Tipos de evento de auditoría.
+const (
+	EventRefreshTokenReuse  = "session.refresh_reuse"
+	EventLogoutAll          = "session.logout_all"
+	EventSessionsRevoked    = "session.revoked_by_admin"
+	EventTenantTrialExpired = "tenant.trial_expired"
+	EventTenantSwitched     = "tenant.switched"
+	EventMFAEnabled         = "mfa.enabled"
+	EventMFADisabled        = "mfa.disabled"
+	EventMFAReset           = "mfa.reset"
+	EventPasskeyRegistered  = "passkey.registered"
+	EventPasskeyDeleted     = "passkey.deleted"
+	EventPasskeyCloned      = "passkey.clone_suspected"
+	EventPasswordReset      = "password.reset"
+	EventPasswordChanged    = "password.changed"
+	EventEmailVerified      = "email.verified"
+	EventLoginFailed        = "login.failed"
+	EventAccountLocked      = "account.locked"
+	EventIPLocked           = "ip.locked"
+	EventAccountUnlocked    = "account.unlocked"
+	EventIPUnlocked         = "ip.unlocked"
+)
+
+// Event es un evento de seguridad. Los campos vacíos se guardan como NULL.
+type Event struct {
+	Type      string
+	TenantID  string
+	UserID    string
+	ClientIP  string
+	UserAgent string
+	Metadata  map[string]any
+}
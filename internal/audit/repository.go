@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+func (r *Repository) Create(ctx context.Context, e Event) error {
+	metadata := []byte("{}")
+	if len(e.Metadata) > 0 {
+		var err error
+		metadata, err = json.Marshal(e.Metadata)
+		if err != nil {
+			return err
+		}
+	}
+
+	return r.q.CreateAuditEvent(ctx, gen.CreateAuditEventParams{
+		ID:        uuid.New(),
+		EventType: e.Type,
+		TenantID:  nullUUID(e.TenantID),
+		UserID:    nullUUID(e.UserID),
+		ClientIp:  sql.NullString{String: e.ClientIP, Valid: e.ClientIP != ""},
+		UserAgent: sql.NullString{String: e.UserAgent, Valid: e.UserAgent != ""},
+		Metadata:  metadata,
+		CreatedAt: time.Now(),
+	})
+}
+
+func nullUUID(s string) uuid.NullUUID {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.NullUUID{}
+	}
+	return uuid.NullUUID{UUID: id, Valid: true}
+}
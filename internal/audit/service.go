@@ -0,0 +1,30 @@
+package audit
+
+import (
+	"context"
+	"log"
+)
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Record guarda el evento y lo deja en el log. Un fallo al guardar no debe
+// romper el flujo que lo emite, así que solo se registra.
+func (s *Service) Record(ctx context.Context, e Event) {
+	log.Printf("🔐 audit %s user=%s tenant=%s ip=%s %v", e.Type, e.UserID, e.TenantID, e.ClientIP, e.Metadata)
+
+	if err := s.repo.Create(ctx, e); err != nil {
+		log.Printf("⚠️  Error guardando evento de auditoría %s: %v", e.Type, err)
+	}
+}
+
+// Alert es Record para eventos que indican un posible compromiso de credenciales.
+func (s *Service) Alert(ctx context.Context, e Event) {
+	log.Printf("🚨 ALERTA de seguridad: %s user=%s tenant=%s", e.Type, e.UserID, e.TenantID)
+	s.Record(ctx, e)
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
 	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
@@ -9,11 +10,12 @@ import (
 )
 
 type CredentialsRepository struct {
-	q *gen.Queries
+	db gen.DBTX
+	q  *gen.Queries
 }
 
 func NewCredentialsRepository(db gen.DBTX) *CredentialsRepository {
-	return &CredentialsRepository{q: gen.New(db)}
+	return &CredentialsRepository{db: db, q: gen.New(db)}
 }
 
 // InsertCredential → devuelve un struct (no puntero) del tipo sqlc
@@ -65,3 +67,68 @@ func (r *CredentialsRepository) GetByUserID(ctx context.Context, userID string)
 	}
 	return &cred, nil
 }
+
+// RehashPassword sustituye el hash por otro de la misma contraseña sin tocar
+// updated_at (no cuenta como cambio para la caducidad). Solo se aplica si el
+// hash sigue siendo oldHash: un cambio de contraseña concurrente gana.
+func (r *CredentialsRepository) RehashPassword(ctx context.Context, userID uuid.UUID, oldHash, newHash string) (bool, error) {
+	n, err := r.q.RehashCredentialPassword(ctx, gen.RehashCredentialPasswordParams{
+		NewHash: newHash,
+		UserID:  userID,
+		OldHash: oldHash,
+	})
+	return n > 0, err
+}
+
+// ImportUser crea un usuario con un hash traído de otro sistema (bcrypt,
+// scrypt, PBKDF2, Firebase scrypt o Argon2id), que se guarda tal cual y se
+// rehashea en el primer login. Usuario y credencial se crean en una misma
+// transacción: si falla la credencial no queda un usuario sin contraseña.
+func (r *CredentialsRepository) ImportUser(ctx context.Context, tenantID uuid.UUID, displayName, email, passwordHash string) (*gen.User, error) {
+	if err := ValidatePasswordHash(passwordHash); err != nil {
+		return nil, err
+	}
+
+	// Si el repositorio ya trabaja sobre una transacción, se usa esa
+	q := r.q
+	db, ok := r.db.(*sql.DB)
+	var tx *sql.Tx
+	if ok {
+		var err error
+		tx, err = db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback()
+		q = r.q.WithTx(tx)
+	}
+
+	now := time.Now()
+	user, err := q.CreateUser(ctx, gen.CreateUserParams{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		DisplayName: displayName,
+		Email:       email,
+		IsActive:    true,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	})
+	if err != nil {
+		return nil, err
+	}
+	err = q.CreateCredential(ctx, gen.CreateCredentialParams{
+		UserID:       user.ID,
+		PasswordHash: passwordHash,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+	}
+	return &user, nil
+}
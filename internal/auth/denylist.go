@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Frecuencia por defecto con la que cada réplica recarga la denylist.
+const defaultDenylistRefresh = 5 * time.Second
+
+var ErrTokenRevoked = errors.New("token has been revoked")
+
+// Denylist mantiene en memoria los access tokens revocados (por jti) y la marca
+// "tokens valid after" de cada usuario, para que AuthMiddleware los rechace sin
+// consultar la base de datos en cada request.
+//
+// Solo hace falta recordar lo revocado durante la vida de un access token:
+// pasado AccessTokenTTL los tokens afectados ya expiraron por sí mismos.
+type Denylist struct {
+	repo *RevocationsRepository
+
+	mu     sync.RWMutex
+	tokens map[string]time.Time // jti -> exp
+	users  map[string]time.Time // userID -> tokens valid after
+}
+
+// denylist es la denylist del proceso, instalada al arrancar con UseDenylist.
+var denylist *Denylist
+
+// UseDenylist installs the denylist consulted by ValidateAccessToken.
+func UseDenylist(d *Denylist) {
+	denylist = d
+}
+
+func NewDenylist(repo *RevocationsRepository) *Denylist {
+	return &Denylist{
+		repo:   repo,
+		tokens: map[string]time.Time{},
+		users:  map[string]time.Time{},
+	}
+}
+
+// DenylistRefreshFromEnv lee TOKEN_DENYLIST_REFRESH: cada cuánto una réplica ve
+// las revocaciones hechas en otras.
+func DenylistRefreshFromEnv() (time.Duration, error) {
+	v := os.Getenv("TOKEN_DENYLIST_REFRESH")
+	if v == "" {
+		return defaultDenylistRefresh, nil
+	}
+	return time.ParseDuration(v)
+}
+
+// Load recarga la denylist desde la base de datos.
+func (d *Denylist) Load(ctx context.Context) error {
+	revoked, err := d.repo.ListRevokedTokens(ctx)
+	if err != nil {
+		return err
+	}
+
+	epochs, err := d.repo.ListUserTokenEpochs(ctx, time.Now().Add(-AccessTokenTTL))
+	if err != nil {
+		return err
+	}
+
+	tokens := make(map[string]time.Time, len(revoked))
+	for _, t := range revoked {
+		tokens[t.Jti] = t.ExpiresAt
+	}
+
+	users := make(map[string]time.Time, len(epochs))
+	for _, e := range epochs {
+		users[e.ID.String()] = e.TokensValidAfter.Time
+	}
+
+	d.mu.Lock()
+	d.tokens = tokens
+	d.users = users
+	d.mu.Unlock()
+
+	return nil
+}
+
+// PurgeExpired elimina de la base de datos los jti de tokens ya expirados;
+// Load ya los ignora, esto solo evita que la tabla crezca.
+func (d *Denylist) PurgeExpired(ctx context.Context) (int64, error) {
+	return d.repo.DeleteExpired(ctx)
+}
+
+// Start recarga la denylist periódicamente hasta que ctx se cancele.
+func (d *Denylist) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultDenylistRefresh
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.Load(ctx); err != nil {
+					log.Printf("⚠️  Error recargando denylist de tokens: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// RevokeToken revoca un access token concreto hasta su expiración.
+func (d *Denylist) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	if jti == "" {
+		return errors.New("token has no jti")
+	}
+	if err := d.repo.RevokeToken(ctx, jti, expiresAt); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.tokens[jti] = expiresAt
+	d.mu.Unlock()
+	return nil
+}
+
+// RevokeUserTokens invalida todos los access tokens emitidos al usuario hasta ahora.
+func (d *Denylist) RevokeUserTokens(ctx context.Context, userID uuid.UUID) error {
+	now := time.Now().UTC()
+	if err := d.repo.SetUserTokensValidAfter(ctx, userID, now); err != nil {
+		return err
+	}
+
+	d.mu.Lock()
+	d.users[userID.String()] = now
+	d.mu.Unlock()
+	return nil
+}
+
+// RevokeTenantTokens invalida los access tokens vigentes de todos los usuarios del tenant.
+func (d *Denylist) RevokeTenantTokens(ctx context.Context, tenantID uuid.UUID) error {
+	if err := d.repo.SetTenantTokensValidAfter(ctx, tenantID, time.Now().UTC()); err != nil {
+		return err
+	}
+	// No sabemos qué usuarios se marcaron: recargar para aplicarlo ya en esta réplica
+	return d.Load(ctx)
+}
+
+// IsRevoked indica si el token fue revocado por jti o emitido antes de la
+// marca "tokens valid after" de su usuario.
+func (d *Denylist) IsRevoked(claims *Claims) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if _, ok := d.tokens[claims.ID]; ok && claims.ID != "" {
+		return true
+	}
+
+	if claims.UserID == "" || claims.IssuedAt == nil {
+		return false
+	}
+	validAfter, ok := d.users[claims.UserID]
+	if !ok {
+		return false
+	}
+
+	// iat tiene precisión de segundos: un token emitido en el mismo segundo
+	// que la revocación se sigue aceptando.
+	return claims.IssuedAt.Time.Before(validAfter.Truncate(time.Second))
+}
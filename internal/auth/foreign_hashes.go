@@ -0,0 +1,343 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Hashes importados de otros sistemas. Se verifican tal cual y se sustituyen
+// por Argon2id en el primer login correcto (NeedsRehash).
+//
+// Formatos aceptados:
+//   - bcrypt:          $2a$10$... / $2b$ / $2y$
+//   - scrypt:          $scrypt$ln=15,r=8,p=1$<salt>$<hash>
+//   - PBKDF2:          $pbkdf2-sha256$i=600000$<salt>$<hash> (sha1, sha256, sha512)
+//   - PBKDF2 (Django): pbkdf2_sha256$600000$<salt en claro>$<hash>
+//   - Firebase scrypt: $firebase-scrypt$<salt>$<hash>, con los parámetros del proyecto en FIREBASE_SCRYPT_*
+//
+// salt y hash van en base64 estándar, con o sin padding.
+
+// Límites de coste: un hash importado no debe poder bloquear un login.
+const (
+	maxBcryptCost       = 16
+	maxScryptLogN       = 20
+	maxScryptRP         = 64
+	maxPBKDF2Iterations = 10_000_000
+)
+
+var ErrFirebaseScryptNotConfigured = errors.New("firebase scrypt parameters not configured")
+
+// foreignHash es un hash importado ya decodificado.
+type foreignHash interface {
+	verify(password string) (bool, error)
+}
+
+// parseForeignHash decodifica un hash importado. ok es false si stored no
+// tiene ninguno de los formatos importados (es Argon2id o no es válido).
+func parseForeignHash(stored string) (h foreignHash, ok bool, err error) {
+	switch {
+	case strings.HasPrefix(stored, "$2a$"), strings.HasPrefix(stored, "$2b$"), strings.HasPrefix(stored, "$2y$"):
+		h, err = parseBcrypt(stored)
+	case strings.HasPrefix(stored, "$scrypt$"):
+		h, err = parseScrypt(stored)
+	case strings.HasPrefix(stored, "$pbkdf2-"):
+		h, err = parsePBKDF2(stored)
+	case strings.HasPrefix(stored, "pbkdf2_"):
+		h, err = parseDjangoPBKDF2(stored)
+	case strings.HasPrefix(stored, "$firebase-scrypt$"):
+		h, err = parseFirebaseScrypt(stored)
+	default:
+		return nil, false, nil
+	}
+	return h, true, err
+}
+
+// ValidatePasswordHash comprueba que un hash (propio o importado) tiene un
+// formato soportado y parámetros dentro de los límites, sin verificar nada.
+func ValidatePasswordHash(stored string) error {
+	if _, ok, err := parseForeignHash(stored); ok {
+		return err
+	}
+	_, _, _, err := decodeArgonHash(stored)
+	return err
+}
+
+// ---- bcrypt ----
+
+type bcryptHash []byte
+
+func parseBcrypt(stored string) (foreignHash, error) {
+	cost, err := bcrypt.Cost([]byte(stored))
+	if err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	if cost > maxBcryptCost {
+		return nil, fmt.Errorf("bcrypt cost %d exceeds %d", cost, maxBcryptCost)
+	}
+	return bcryptHash(stored), nil
+}
+
+func (h bcryptHash) verify(password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword(h, []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ---- scrypt ----
+
+type scryptHash struct {
+	logN, r, p int
+	salt, key  []byte
+}
+
+func parseScrypt(stored string) (foreignHash, error) {
+	// $scrypt$ln=15,r=8,p=1$salt$hash
+	parts := strings.Split(stored, "$")
+	if len(parts) != 5 {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var h scryptHash
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &h.logN, &h.r, &h.p); err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	if h.logN < 1 || h.logN > maxScryptLogN || h.r < 1 || h.p < 1 || h.r*h.p > maxScryptRP ||
+		scryptMemory(h.logN, h.r, h.p) > maxHashMemory {
+		return nil, fmt.Errorf("scrypt parameters out of range")
+	}
+
+	var err error
+	if h.salt, err = decodeHashBase64(parts[3]); err != nil {
+		return nil, err
+	}
+	if h.key, err = decodeHashBase64(parts[4]); err != nil {
+		return nil, err
+	}
+	if len(h.key) > maxHashKeyLen {
+		return nil, ErrInvalidPasswordHash
+	}
+	return h, nil
+}
+
+// scryptMemory es la memoria que reserva scrypt.Key: 128·r·N bytes para V y
+// 128·r·p para B. r y p ya vienen acotados por maxScryptRP.
+func scryptMemory(logN, r, p int) int64 {
+	return 128 * int64(r) * (int64(1)<<logN + int64(p))
+}
+
+func (h scryptHash) verify(password string) (bool, error) {
+	key, err := scrypt.Key([]byte(password), h.salt, 1<<h.logN, h.r, h.p, len(h.key))
+	if err != nil {
+		return false, err
+	}
+	return subtleConstantCompare(key, h.key), nil
+}
+
+// ---- PBKDF2 ----
+
+type pbkdf2Hash struct {
+	digest     func() hash.Hash
+	iterations int
+	salt, key  []byte
+}
+
+func pbkdf2Digest(name string) (func() hash.Hash, error) {
+	switch name {
+	case "sha1":
+		return sha1.New, nil
+	case "sha256":
+		return sha256.New, nil
+	case "sha512":
+		return sha512.New, nil
+	}
+	return nil, fmt.Errorf("unsupported pbkdf2 digest %q", name)
+}
+
+func parsePBKDF2(stored string) (foreignHash, error) {
+	// $pbkdf2-sha256$i=600000$salt$hash
+	parts := strings.Split(stored, "$")
+	if len(parts) != 5 {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var h pbkdf2Hash
+	var err error
+	if h.digest, err = pbkdf2Digest(strings.TrimPrefix(parts[1], "pbkdf2-")); err != nil {
+		return nil, err
+	}
+	if _, err := fmt.Sscanf(parts[2], "i=%d", &h.iterations); err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	if h.salt, err = decodeHashBase64(parts[3]); err != nil {
+		return nil, err
+	}
+	if h.key, err = decodeHashBase64(parts[4]); err != nil {
+		return nil, err
+	}
+	return h, h.checkParams()
+}
+
+func parseDjangoPBKDF2(stored string) (foreignHash, error) {
+	// pbkdf2_sha256$600000$salt$hash (la salt de Django es texto, no base64)
+	parts := strings.Split(stored, "$")
+	if len(parts) != 4 || parts[2] == "" {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var h pbkdf2Hash
+	var err error
+	if h.digest, err = pbkdf2Digest(strings.TrimPrefix(parts[0], "pbkdf2_")); err != nil {
+		return nil, err
+	}
+	if h.iterations, err = strconv.Atoi(parts[1]); err != nil {
+		return nil, ErrInvalidPasswordHash
+	}
+	h.salt = []byte(parts[2])
+	if h.key, err = decodeHashBase64(parts[3]); err != nil {
+		return nil, err
+	}
+	return h, h.checkParams()
+}
+
+// checkParams acota el coste: cada bloque de la clave derivada repite todas
+// las iteraciones, así que también se limita su longitud.
+func (h pbkdf2Hash) checkParams() error {
+	if h.iterations < 1 || h.iterations > maxPBKDF2Iterations {
+		return fmt.Errorf("pbkdf2 iterations out of range")
+	}
+	if len(h.key) > maxHashKeyLen {
+		return ErrInvalidPasswordHash
+	}
+	return nil
+}
+
+func (h pbkdf2Hash) verify(password string) (bool, error) {
+	key, err := pbkdf2.Key(h.digest, password, h.salt, h.iterations, len(h.key))
+	if err != nil {
+		return false, err
+	}
+	return subtleConstantCompare(key, h.key), nil
+}
+
+// ---- Firebase scrypt ----
+
+// FirebaseScrypt son los parámetros del proyecto de Firebase del que se
+// exportaron los hashes (consola de Firebase → Authentication → Password hash parameters).
+type FirebaseScrypt struct {
+	SignerKey     []byte
+	SaltSeparator []byte
+	Rounds        int
+	MemCost       int
+}
+
+var firebaseScrypt *FirebaseScrypt
+
+// UseFirebaseScrypt instala los parámetros para verificar hashes de Firebase.
+func UseFirebaseScrypt(p *FirebaseScrypt) {
+	firebaseScrypt = p
+}
+
+// FirebaseScryptFromEnv lee FIREBASE_SCRYPT_SIGNER_KEY, FIREBASE_SCRYPT_SALT_SEPARATOR
+// (base64), FIREBASE_SCRYPT_ROUNDS y FIREBASE_SCRYPT_MEM_COST. Devuelve nil si
+// no hay signer key: sin ella no se pueden verificar hashes de Firebase.
+func FirebaseScryptFromEnv() (*FirebaseScrypt, error) {
+	signerKey := os.Getenv("FIREBASE_SCRYPT_SIGNER_KEY")
+	if signerKey == "" {
+		return nil, nil
+	}
+
+	p := &FirebaseScrypt{Rounds: 8, MemCost: 14}
+	var err error
+	if p.SignerKey, err = base64.StdEncoding.DecodeString(signerKey); err != nil {
+		return nil, fmt.Errorf("FIREBASE_SCRYPT_SIGNER_KEY: %w", err)
+	}
+	if p.SaltSeparator, err = base64.StdEncoding.DecodeString(os.Getenv("FIREBASE_SCRYPT_SALT_SEPARATOR")); err != nil {
+		return nil, fmt.Errorf("FIREBASE_SCRYPT_SALT_SEPARATOR: %w", err)
+	}
+	if v := os.Getenv("FIREBASE_SCRYPT_ROUNDS"); v != "" {
+		if p.Rounds, err = strconv.Atoi(v); err != nil || p.Rounds < 1 || p.Rounds > maxScryptRP {
+			return nil, fmt.Errorf("invalid FIREBASE_SCRYPT_ROUNDS %q", v)
+		}
+	}
+	if v := os.Getenv("FIREBASE_SCRYPT_MEM_COST"); v != "" {
+		if p.MemCost, err = strconv.Atoi(v); err != nil || p.MemCost < 1 || p.MemCost > maxScryptLogN {
+			return nil, fmt.Errorf("invalid FIREBASE_SCRYPT_MEM_COST %q", v)
+		}
+	}
+	if scryptMemory(p.MemCost, p.Rounds, 1) > maxHashMemory {
+		return nil, fmt.Errorf("FIREBASE_SCRYPT_ROUNDS and FIREBASE_SCRYPT_MEM_COST need more than %d MB", maxHashMemory>>20)
+	}
+	return p, nil
+}
+
+type firebaseScryptHash struct {
+	salt, key []byte
+}
+
+func parseFirebaseScrypt(stored string) (foreignHash, error) {
+	// $firebase-scrypt$salt$hash
+	parts := strings.Split(stored, "$")
+	if len(parts) != 4 {
+		return nil, ErrInvalidPasswordHash
+	}
+
+	var h firebaseScryptHash
+	var err error
+	if h.salt, err = decodeHashBase64(parts[2]); err != nil {
+		return nil, err
+	}
+	if h.key, err = decodeHashBase64(parts[3]); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// verify replica el scrypt modificado de Firebase: la clave derivada cifra la
+// signer key con AES-256-CTR (IV a cero) y el resultado es el hash.
+func (h firebaseScryptHash) verify(password string) (bool, error) {
+	p := firebaseScrypt
+	if p == nil {
+		return false, ErrFirebaseScryptNotConfigured
+	}
+
+	salt := make([]byte, 0, len(h.salt)+len(p.SaltSeparator))
+	salt = append(append(salt, h.salt...), p.SaltSeparator...)
+	derived, err := scrypt.Key([]byte(password), salt, 1<<p.MemCost, p.Rounds, 1, 32)
+	if err != nil {
+		return false, err
+	}
+
+	block, err := aes.NewCipher(derived)
+	if err != nil {
+		return false, err
+	}
+	calculated := make([]byte, len(p.SignerKey))
+	cipher.NewCTR(block, make([]byte, aes.BlockSize)).XORKeyStream(calculated, p.SignerKey)
+
+	return subtleConstantCompare(calculated, h.key), nil
+}
+
+// decodeHashBase64 acepta base64 estándar con o sin padding.
+func decodeHashBase64(s string) ([]byte, error) {
+	b, err := base64.RawStdEncoding.DecodeString(strings.TrimRight(s, "="))
+	if err != nil || len(b) == 0 {
+		return nil, ErrInvalidPasswordHash
+	}
+	return b, nil
+}
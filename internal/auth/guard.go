@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+)
+
+// ErrTooManyAttempts: demasiados fallos de contraseña para la cuenta o la IP.
+// Authenticate devuelve un *LoginThrottled que cumple errors.Is(err, ErrTooManyAttempts).
+var ErrTooManyAttempts = errors.New("too many failed login attempts")
+
+// LoginThrottled indica cuánto debe esperar el cliente antes de volver a
+// intentarlo: un retardo progresivo o, con Locked, un bloqueo temporal.
+type LoginThrottled struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *LoginThrottled) Error() string {
+	if e.Locked {
+		return "account temporarily locked"
+	}
+	return ErrTooManyAttempts.Error()
+}
+
+func (e *LoginThrottled) Is(target error) bool {
+	return target == ErrTooManyAttempts
+}
+
+// LoginGuard lo implementa lockout.Service; cuenta los fallos de contraseña
+// por cuenta (email) y por IP.
+type LoginGuard interface {
+	// Check devuelve un *LoginThrottled si el email o la IP deben esperar.
+	Check(ctx context.Context, email string, client ClientInfo) error
+	// Failed registra un fallo. user es nil si el email no tiene cuenta.
+	Failed(ctx context.Context, email string, user *dbgen.User, client ClientInfo)
+	// Succeeded reinicia el contador de la cuenta.
+	Succeeded(ctx context.Context, email string)
+}
+
+// UseLoginGuard instala la protección contra fuerza bruta. Sin ella, Authenticate
+// no limita los intentos.
+func (s *AuthService) UseLoginGuard(g LoginGuard) {
+	s.guard = g
+}
+
+// LoginSucceeded reinicia el contador de fallos de la cuenta. Se llama cuando
+// el login termina con tokens, no tras la contraseña: si no, cada contraseña
+// correcta borraría los códigos MFA fallidos y el segundo factor se podría
+// adivinar sin límite.
+func (s *AuthService) LoginSucceeded(ctx context.Context, email string) {
+	if s.guard != nil {
+		s.guard.Succeeded(ctx, email)
+	}
+}
+
+// SecondFactorFailed cuenta un código MFA incorrecto como un fallo de login de
+// la cuenta y la IP.
+func (s *AuthService) SecondFactorFailed(ctx context.Context, user *dbgen.User, client ClientInfo) {
+	if s.guard != nil {
+		s.guard.Failed(ctx, user.Email, user, client)
+	}
+}
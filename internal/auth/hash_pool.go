@@ -0,0 +1,230 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Cada hash de contraseña reserva argonMemory (64 MB): sin límite, una ráfaga
+// de logins concurrentes agota la memoria del pod. HashPool limita cuántos se
+// calculan a la vez y cuántos esperan; el resto falla al momento con
+// ErrHashPoolBusy (503) en lugar de encolarse sin fin.
+const (
+	defaultHashPoolQueue   = 64
+	defaultHashPoolMaxWait = 3 * time.Second
+)
+
+// ErrHashPoolBusy: la cola de hashes está llena o la espera superó el máximo.
+var ErrHashPoolBusy = errors.New("password hashing capacity exhausted")
+
+// Límites (en segundos) de los buckets de los histogramas de latencia.
+var HashPoolBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+type HashPoolConfig struct {
+	Workers  int           // hashes simultáneos (memoria ≈ Workers × 64 MB; hasta 256 MB cada uno con hashes importados)
+	MaxQueue int           // peticiones que pueden esperar un worker
+	MaxWait  time.Duration // espera máxima en la cola
+}
+
+// HashPoolConfigFromEnv lee HASH_POOL_WORKERS (por defecto GOMAXPROCS),
+// HASH_POOL_QUEUE (64) y HASH_POOL_MAX_WAIT (3s).
+func HashPoolConfigFromEnv() (HashPoolConfig, error) {
+	cfg := HashPoolConfig{
+		Workers:  runtime.GOMAXPROCS(0),
+		MaxQueue: defaultHashPoolQueue,
+		MaxWait:  defaultHashPoolMaxWait,
+	}
+
+	if v := os.Getenv("HASH_POOL_WORKERS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return cfg, fmt.Errorf("invalid HASH_POOL_WORKERS %q", v)
+		}
+		cfg.Workers = n
+	}
+	if v := os.Getenv("HASH_POOL_QUEUE"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return cfg, fmt.Errorf("invalid HASH_POOL_QUEUE %q", v)
+		}
+		cfg.MaxQueue = n
+	}
+	if v := os.Getenv("HASH_POOL_MAX_WAIT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return cfg, fmt.Errorf("invalid HASH_POOL_MAX_WAIT %q", v)
+		}
+		cfg.MaxWait = d
+	}
+	return cfg, nil
+}
+
+// HashPool es un semáforo con cola acotada para los hashes de contraseña.
+type HashPool struct {
+	cfg   HashPoolConfig
+	slots chan struct{}
+
+	queued    atomic.Int64
+	completed atomic.Int64
+	rejected  atomic.Int64
+	canceled  atomic.Int64
+	wait      histogram
+	run       histogram
+}
+
+// hashPool es el pool del proceso, instalado al arrancar con UseHashPool. Sin
+// él (CLI, bootstrap) los hashes se calculan directamente.
+var hashPool *HashPool
+
+// UseHashPool instala el pool por el que pasan HashPassword y VerifyPassword.
+func UseHashPool(p *HashPool) {
+	hashPool = p
+}
+
+func NewHashPool(cfg HashPoolConfig) *HashPool {
+	return &HashPool{
+		cfg:   cfg,
+		slots: make(chan struct{}, cfg.Workers),
+		wait:  newHistogram(),
+		run:   newHistogram(),
+	}
+}
+
+// Do ejecuta fn cuando hay un worker libre. Devuelve ErrHashPoolBusy si la cola
+// está llena o la espera supera MaxWait, y ctx.Err() si se cancela mientras espera.
+func (p *HashPool) Do(ctx context.Context, fn func()) error {
+	start := time.Now()
+
+	select {
+	case p.slots <- struct{}{}:
+	default:
+		if err := p.enqueue(ctx); err != nil {
+			return err
+		}
+	}
+	defer func() { <-p.slots }()
+
+	running := time.Now()
+	p.wait.observe(running.Sub(start))
+	fn()
+	p.run.observe(time.Since(running))
+	p.completed.Add(1)
+	return nil
+}
+
+// enqueue espera un worker ocupando un puesto de la cola.
+func (p *HashPool) enqueue(ctx context.Context) error {
+	if p.queued.Add(1) > int64(p.cfg.MaxQueue) {
+		p.queued.Add(-1)
+		p.rejected.Add(1)
+		return ErrHashPoolBusy
+	}
+	defer p.queued.Add(-1)
+
+	timer := time.NewTimer(p.cfg.MaxWait)
+	defer timer.Stop()
+
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		p.rejected.Add(1)
+		return ErrHashPoolBusy
+	case <-ctx.Done():
+		p.canceled.Add(1)
+		return ctx.Err()
+	}
+}
+
+// HashPoolStats es una foto de las métricas del pool.
+type HashPoolStats struct {
+	Workers   int
+	MaxQueue  int
+	InFlight  int   // hashes calculándose ahora
+	Queued    int64 // peticiones esperando worker
+	Completed int64
+	Rejected  int64 // cola llena o espera máxima superada (503)
+	Canceled  int64 // el cliente se fue mientras esperaba
+	Wait      HistogramSnapshot
+	Run       HistogramSnapshot
+}
+
+func (p *HashPool) Stats() HashPoolStats {
+	return HashPoolStats{
+		Workers:   p.cfg.Workers,
+		MaxQueue:  p.cfg.MaxQueue,
+		InFlight:  len(p.slots),
+		Queued:    p.queued.Load(),
+		Completed: p.completed.Load(),
+		Rejected:  p.rejected.Load(),
+		Canceled:  p.canceled.Load(),
+		Wait:      p.wait.snapshot(),
+		Run:       p.run.snapshot(),
+	}
+}
+
+// runHash pasa fn por el pool instalado, si lo hay.
+func runHash(ctx context.Context, fn func()) error {
+	if hashPool == nil {
+		fn()
+		return nil
+	}
+	return hashPool.Do(ctx, fn)
+}
+
+// isHashPoolError indica si err viene del pool (saturado o contexto cancelado)
+// y no del propio hash.
+func isHashPoolError(err error) bool {
+	return errors.Is(err, ErrHashPoolBusy) ||
+		errors.Is(err, context.Canceled) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// ---- Histograma ----
+
+// histogram acumula duraciones en los buckets de HashPoolBuckets, sin locks.
+type histogram struct {
+	buckets []atomic.Int64 // no acumulativos; el último es +Inf
+	count   atomic.Int64
+	sumNs   atomic.Int64
+}
+
+func newHistogram() histogram {
+	return histogram{buckets: make([]atomic.Int64, len(HashPoolBuckets)+1)}
+}
+
+func (h *histogram) observe(d time.Duration) {
+	i := 0
+	for i < len(HashPoolBuckets) && d.Seconds() > HashPoolBuckets[i] {
+		i++
+	}
+	h.buckets[i].Add(1)
+	h.count.Add(1)
+	h.sumNs.Add(int64(d))
+}
+
+// HistogramSnapshot: Buckets[i] cuenta las observaciones <= HashPoolBuckets[i]
+// (acumulativo, como en Prometheus).
+type HistogramSnapshot struct {
+	Buckets []int64
+	Count   int64
+	Sum     time.Duration
+}
+
+func (h *histogram) snapshot() HistogramSnapshot {
+	s := HistogramSnapshot{Buckets: make([]int64, len(HashPoolBuckets))}
+	var acc int64
+	for i := range HashPoolBuckets {
+		acc += h.buckets[i].Load()
+		s.Buckets[i] = acc
+	}
+	s.Count = h.count.Load()
+	s.Sum = time.Duration(h.sumNs.Load())
+	return s
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+)
+
+// JWK es la representación pública de una llave de firma (RFC 7517).
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+	// EC / OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet es el documento servido en /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS devuelve las llaves públicas que los servicios deben aceptar: las
+// activas, las pendientes de activar (pre-publicadas) y las retiradas que
+// siguen en periodo de gracia.
+func (k *KeyRing) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+	for _, key := range k.Keys() {
+		jwk, ok := publicJWK(key)
+		if ok {
+			set.Keys = append(set.Keys, jwk)
+		}
+	}
+	return set
+}
+
+// JWKSVersion identifica el conjunto de llaves publicado (se usa como ETag).
+// Cambia cada vez que se añade o expira una llave.
+func (k *KeyRing) JWKSVersion() string {
+	var kids []string
+	for _, key := range k.Keys() {
+		kids = append(kids, key.ID)
+	}
+	sort.Strings(kids)
+
+	sum := sha256.Sum256([]byte(strings.Join(kids, ",")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// JWKSMaxAge es el tiempo que los clientes pueden cachear el JWKS. Es menor
+// que el periodo de pre-publicación, así todos los servicios conocen una llave
+// nueva antes de que empiece a firmar.
+func (k *KeyRing) JWKSMaxAge() time.Duration {
+	maxAge := 15 * time.Minute
+	if half := k.cfg.PrePublish / 2; half < maxAge {
+		maxAge = half
+	}
+	return maxAge
+}
+
+func publicJWK(key *SigningKey) (JWK, bool) {
+	jwk := JWK{Kid: key.ID, Use: "sig", Alg: key.Algorithm}
+
+	switch pub := key.Public.(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = b64(pub.N.Bytes())
+		jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		jwk.X = b64(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = b64(pub.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = b64(pub)
+	default:
+		return jwk, false
+	}
+
+	return jwk, true
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
@@ -3,50 +3,183 @@ package auth
 import (
 	"errors"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// AccessTokenTTL es la vida de los access tokens emitidos por AuthService.
+const AccessTokenTTL = 15 * time.Minute
+
+// Valores del header typ. Distinguen los access tokens (RFC 9068) de los
+// ID tokens, firmados con las mismas llaves.
+const (
+	typAccessToken = "at+jwt"
+	typIDToken     = "JWT"
 )
 
 // Claims define the JWT payload used by the IAM system.
 type Claims struct {
 	UserID   string `json:"user_id"`
 	TenantID string `json:"tenant_id"`
+	// Tokens de máquina (client_credentials): no tienen usuario y sus
+	// permisos son los scopes concedidos, separados por espacios.
+	ClientID string `json:"client_id,omitempty"`
+	Scope    string `json:"scope,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// IsClient reports whether the token was issued to a machine client instead of a user.
+func (c *Claims) IsClient() bool {
+	return c.ClientID != "" && c.UserID == ""
+}
+
+// HasScope reports whether scope was granted to the token.
+func (c *Claims) HasScope(scope string) bool {
+	for _, s := range strings.Fields(c.Scope) {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// keyRing is the process-wide signing key ring, installed at startup with UseKeyRing.
+var keyRing *KeyRing
+
+// UseKeyRing installs the key ring used to sign and verify access tokens.
+func UseKeyRing(kr *KeyRing) {
+	keyRing = kr
+}
+
+// Issuer returns the issuer identifier (iss) of this IAM, read from JWT_ISSUER.
+// It is also the base URL published in the OpenID discovery document.
+func Issuer() string {
+	issuer := strings.TrimRight(os.Getenv("JWT_ISSUER"), "/")
+	if issuer == "" {
+		return "http://localhost:8080"
+	}
+	return issuer
+}
+
 // GenerateAccessToken creates a signed JWT for the given user and tenant.
+// The token is signed with the newest active key and carries its kid header.
 func GenerateAccessToken(userID, tenantID string, ttl time.Duration) (string, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return "", errors.New("JWT_SECRET is not set")
+	now := time.Now().UTC()
+
+	claims := &Claims{
+		UserID:   userID,
+		TenantID: tenantID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    Issuer(),
+			Subject:   userID,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
 	}
 
+	return signToken(claims, typAccessToken)
+}
+
+// GenerateClientAccessToken creates a signed JWT for a machine client
+// (client_credentials grant). sub and client_id are the client ID; the token
+// is bound to the client's tenant and carries the granted scopes.
+func GenerateClientAccessToken(clientID, tenantID, scope string, ttl time.Duration) (string, error) {
 	now := time.Now().UTC()
 
 	claims := &Claims{
-		UserID:   userID,
 		TenantID: tenantID,
+		ClientID: clientID,
+		Scope:    scope,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    Issuer(),
+			Subject:   clientID,
 			IssuedAt:  jwt.NewNumericDate(now),
 			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return signToken(claims, typAccessToken)
+}
+
+// IDTokenClaims is the payload of OpenID Connect ID tokens.
+type IDTokenClaims struct {
+	Nonce    string `json:"nonce,omitempty"`
+	AuthTime int64  `json:"auth_time,omitempty"`
+	Email    string `json:"email,omitempty"`
+	Name     string `json:"name,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// GenerateIDToken signs an OpenID Connect ID token for the given client (aud).
+func GenerateIDToken(userID, clientID string, claims IDTokenClaims, ttl time.Duration) (string, error) {
+	now := time.Now().UTC()
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		Issuer:    Issuer(),
+		Subject:   userID,
+		Audience:  jwt.ClaimStrings{clientID},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+	}
+
+	return signToken(&claims, typIDToken)
+}
+
+// signToken signs claims with the newest active key of the key ring and sets the kid and typ headers.
+func signToken(claims jwt.Claims, typ string) (string, error) {
+	if keyRing == nil {
+		return "", errors.New("signing key ring is not configured")
+	}
+
+	key, err := keyRing.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(key.signingMethod(), claims)
+	token.Header["kid"] = key.ID
+	token.Header["typ"] = typ
+	return token.SignedString(key.Private)
 }
 
 // ValidateAccessToken verifies the signature, expiration, and structure of a JWT.
+// The verification key is selected by the kid header; retired keys are accepted
+// until their grace period ends. Tokens in the denylist (see UseDenylist) are rejected
+// with ErrTokenRevoked.
 func ValidateAccessToken(tokenStr string) (*Claims, error) {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		return nil, errors.New("JWT_SECRET is not set")
+	if keyRing == nil {
+		return nil, errors.New("signing key ring is not configured")
 	}
 
 	token, err := jwt.ParseWithClaims(tokenStr, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		return []byte(secret), nil
-	})
+		// Un ID token no debe aceptarse como access token.
+		if typ, _ := token.Header["typ"].(string); typ != typAccessToken {
+			return nil, errors.New("not an access token")
+		}
+
+		kid, _ := token.Header["kid"].(string)
+		if kid == "" {
+			return nil, errors.New("missing kid header")
+		}
+
+		key, err := keyRing.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+
+		// El algoritmo lo decide la llave, nunca el header del token.
+		if token.Method.Alg() != key.Algorithm {
+			return nil, errors.New("unexpected signing method")
+		}
+
+		return key.Public, nil
+	}, jwt.WithValidMethods(SupportedSigningAlgs), jwt.WithExpirationRequired(), jwt.WithIssuer(Issuer()))
 
 	if err != nil {
 		return nil, err
@@ -57,5 +190,10 @@ func ValidateAccessToken(tokenStr string) (*Claims, error) {
 		return nil, errors.New("invalid token")
 	}
 
+	// Revocación por jti o por "tokens valid after" del usuario (en memoria)
+	if denylist != nil && denylist.IsRevoked(claims) {
+		return nil, ErrTokenRevoked
+	}
+
 	return claims, nil
 }
@@ -0,0 +1,426 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/secrets"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Algoritmos de firma soportados por el key ring.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Frecuencia por defecto con la que cada réplica recarga el key ring.
+const defaultKeyRingRefresh = time.Minute
+
+var (
+	ErrNoSigningKey      = errors.New("no active signing key")
+	ErrUnknownKeyID      = errors.New("unknown signing key id")
+	ErrUnsupportedAlg    = errors.New("unsupported signing algorithm")
+	SupportedSigningAlgs = []string{AlgRS256, AlgES256, AlgEdDSA}
+)
+
+// SigningKey es una llave del key ring ya descifrada y lista para usar.
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	Private     crypto.Signer
+	Public      crypto.PublicKey
+	ActivatesAt time.Time
+	RetiredAt   *time.Time // deja de firmar
+	ExpiresAt   *time.Time // deja de verificar (fin del periodo de gracia)
+}
+
+// CanSign indica si la llave debe usarse para firmar en el instante t.
+func (k *SigningKey) CanSign(t time.Time) bool {
+	if t.Before(k.ActivatesAt) {
+		return false
+	}
+	return k.RetiredAt == nil || t.Before(*k.RetiredAt)
+}
+
+// CanVerify indica si los tokens firmados con esta llave siguen siendo aceptados en t.
+func (k *SigningKey) CanVerify(t time.Time) bool {
+	return k.ExpiresAt == nil || t.Before(*k.ExpiresAt)
+}
+
+func (k *SigningKey) signingMethod() jwt.SigningMethod {
+	return jwt.GetSigningMethod(k.Algorithm)
+}
+
+// KeyRingConfig controla el algoritmo de las llaves nuevas y los tiempos de rotación.
+type KeyRingConfig struct {
+	Algorithm        string        // algoritmo de las llaves nuevas
+	RotationInterval time.Duration // edad máxima de la llave que firma
+	PrePublish       time.Duration // cuánto antes se publica la siguiente llave antes de firmar con ella
+	GracePeriod      time.Duration // cuánto siguen verificando las llaves retiradas
+}
+
+// KeyRingConfigFromEnv lee JWT_SIGNING_ALG, JWT_KEY_ROTATION_INTERVAL,
+// JWT_KEY_PREPUBLISH y JWT_KEY_GRACE_PERIOD con valores por defecto seguros.
+func KeyRingConfigFromEnv() (KeyRingConfig, error) {
+	cfg := KeyRingConfig{
+		Algorithm:        AlgES256,
+		RotationInterval: 30 * 24 * time.Hour,
+		PrePublish:       time.Hour,
+		GracePeriod:      24 * time.Hour,
+	}
+
+	if alg := os.Getenv("JWT_SIGNING_ALG"); alg != "" {
+		if !isSupportedAlg(alg) {
+			return cfg, fmt.Errorf("%w: %s", ErrUnsupportedAlg, alg)
+		}
+		cfg.Algorithm = alg
+	}
+
+	durations := map[string]*time.Duration{
+		"JWT_KEY_ROTATION_INTERVAL": &cfg.RotationInterval,
+		"JWT_KEY_PREPUBLISH":        &cfg.PrePublish,
+		"JWT_KEY_GRACE_PERIOD":      &cfg.GracePeriod,
+	}
+	for env, target := range durations {
+		raw := os.Getenv(env)
+		if raw == "" {
+			continue
+		}
+		d, err := time.ParseDuration(raw)
+		if err != nil {
+			return cfg, fmt.Errorf("invalid %s: %w", env, err)
+		}
+		*target = d
+	}
+
+	// Una llave retirada debe verificar al menos durante la vida de un access token.
+	if cfg.GracePeriod < AccessTokenTTL {
+		cfg.GracePeriod = AccessTokenTTL
+	}
+
+	return cfg, nil
+}
+
+// KeyRing mantiene en memoria las llaves de firma guardadas en la base de datos.
+// Varias llaves pueden estar activas a la vez: se firma con la más reciente
+// y se verifica con cualquiera que no haya expirado.
+type KeyRing struct {
+	repo *SigningKeysRepository
+	cfg  KeyRingConfig
+
+	mu   sync.RWMutex
+	keys []*SigningKey // ordenadas por ActivatesAt descendente
+}
+
+func NewKeyRing(repo *SigningKeysRepository, cfg KeyRingConfig) *KeyRing {
+	return &KeyRing{repo: repo, cfg: cfg}
+}
+
+// Load recarga las llaves verificables desde la base de datos.
+func (k *KeyRing) Load(ctx context.Context) error {
+	rows, err := k.repo.ListVerifiable(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*SigningKey, 0, len(rows))
+	for _, row := range rows {
+		key, err := decodeSigningKey(row)
+		if err != nil {
+			return fmt.Errorf("signing key %s: %w", row.ID, err)
+		}
+		keys = append(keys, key)
+	}
+
+	k.mu.Lock()
+	k.keys = keys
+	k.mu.Unlock()
+
+	return nil
+}
+
+// Start recarga el key ring periódicamente para que todas las réplicas
+// vean las rotaciones hechas por otras. Termina cuando ctx se cancela.
+func (k *KeyRing) Start(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultKeyRingRefresh
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := k.Load(ctx); err != nil {
+					log.Printf("⚠️  Error recargando llaves de firma: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+// EnsureSigningKey crea una llave activa si el key ring no tiene ninguna
+// (primer arranque). No hace nada si ya existe una llave que pueda firmar.
+func (k *KeyRing) EnsureSigningKey(ctx context.Context) error {
+	if _, err := k.SigningKey(); err == nil {
+		return nil
+	}
+
+	key, err := k.Generate(ctx, k.cfg.Algorithm, time.Now().UTC())
+	if err != nil {
+		return err
+	}
+
+	log.Printf("🔑 Llave de firma inicial creada: %s (%s)", key.ID, key.Algorithm)
+	return nil
+}
+
+// Generate crea una llave nueva que empezará a firmar en activatesAt y la
+// añade al key ring. Las llaves existentes no se modifican.
+func (k *KeyRing) Generate(ctx context.Context, algorithm string, activatesAt time.Time) (*SigningKey, error) {
+	private, err := generatePrivateKey(algorithm)
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(private)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := secrets.Seal(der)
+	if err != nil {
+		return nil, err
+	}
+
+	publicPEM, err := encodePublicKey(private.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	kid, err := newKeyID()
+	if err != nil {
+		return nil, err
+	}
+
+	row, err := k.repo.CreateSigningKey(ctx, kid, algorithm, sealed, publicPEM, activatesAt.UTC())
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	return &SigningKey{
+		ID:          row.ID,
+		Algorithm:   row.Algorithm,
+		Private:     private,
+		Public:      private.Public(),
+		ActivatesAt: row.ActivatesAt,
+	}, nil
+}
+
+// Rotate publica una llave nueva que empieza a firmar tras el periodo de
+// pre-publicación. En ese momento las llaves anteriores dejan de firmar y
+// siguen verificando durante el periodo de gracia. Si no hay ninguna llave
+// firmando, la nueva se activa de inmediato.
+func (k *KeyRing) Rotate(ctx context.Context) (*SigningKey, error) {
+	// Postgres guarda microsegundos; truncamos para que RetireBefore no
+	// incluya por redondeo a la llave recién creada.
+	activatesAt := time.Now().UTC().Truncate(time.Microsecond)
+	if _, err := k.SigningKey(); err == nil {
+		activatesAt = activatesAt.Add(k.cfg.PrePublish)
+	}
+
+	key, err := k.Generate(ctx, k.cfg.Algorithm, activatesAt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := k.repo.RetireBefore(ctx, activatesAt, activatesAt, activatesAt.Add(k.cfg.GracePeriod)); err != nil {
+		return nil, err
+	}
+
+	if err := k.Load(ctx); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// RotateIfDue rota cuando la llave más reciente (activa o pendiente) es más
+// antigua que el intervalo de rotación. Devuelve true si se creó una llave.
+func (k *KeyRing) RotateIfDue(ctx context.Context) (bool, error) {
+	if err := k.Load(ctx); err != nil {
+		return false, err
+	}
+
+	k.mu.RLock()
+	var newest *SigningKey
+	for _, key := range k.keys {
+		if key.RetiredAt == nil {
+			newest = key
+			break
+		}
+	}
+	k.mu.RUnlock()
+
+	if newest != nil && time.Since(newest.ActivatesAt) < k.cfg.RotationInterval {
+		return false, nil
+	}
+
+	if _, err := k.Rotate(ctx); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Retire deja de firmar con una llave de inmediato; sigue verificando durante el periodo de gracia.
+func (k *KeyRing) Retire(ctx context.Context, kid string) error {
+	now := time.Now().UTC()
+	if err := k.repo.Retire(ctx, kid, now, now.Add(k.cfg.GracePeriod)); err != nil {
+		return err
+	}
+	return k.Load(ctx)
+}
+
+// SigningKey devuelve la llave activa más reciente.
+func (k *KeyRing) SigningKey() (*SigningKey, error) {
+	now := time.Now().UTC()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.CanSign(now) {
+			return key, nil
+		}
+	}
+	return nil, ErrNoSigningKey
+}
+
+// VerificationKey busca una llave por kid entre las que todavía verifican.
+func (k *KeyRing) VerificationKey(kid string) (*SigningKey, error) {
+	now := time.Now().UTC()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	for _, key := range k.keys {
+		if key.ID == kid && key.CanVerify(now) {
+			return key, nil
+		}
+	}
+	return nil, ErrUnknownKeyID
+}
+
+// Keys devuelve una copia de las llaves verificables (incluye las pendientes de activar).
+func (k *KeyRing) Keys() []*SigningKey {
+	now := time.Now().UTC()
+
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+
+	out := make([]*SigningKey, 0, len(k.keys))
+	for _, key := range k.keys {
+		if key.CanVerify(now) {
+			out = append(out, key)
+		}
+	}
+	return out
+}
+
+// ---- Helpers ----
+
+func isSupportedAlg(alg string) bool {
+	for _, a := range SupportedSigningAlgs {
+		if a == alg {
+			return true
+		}
+	}
+	return false
+}
+
+func generatePrivateKey(algorithm string) (crypto.Signer, error) {
+	switch algorithm {
+	case AlgRS256:
+		return rsa.GenerateKey(rand.Reader, 3072)
+	case AlgES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case AlgEdDSA:
+		_, private, err := ed25519.GenerateKey(rand.Reader)
+		return private, err
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlg, algorithm)
+	}
+}
+
+func decodeSigningKey(row dbgen.SigningKey) (*SigningKey, error) {
+	der, err := secrets.Open(row.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	private, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, errors.New("private key is not a signer")
+	}
+
+	key := &SigningKey{
+		ID:          row.ID,
+		Algorithm:   row.Algorithm,
+		Private:     private,
+		Public:      private.Public(),
+		ActivatesAt: row.ActivatesAt,
+	}
+	if row.RetiredAt.Valid {
+		key.RetiredAt = &row.RetiredAt.Time
+	}
+	if row.ExpiresAt.Valid {
+		key.ExpiresAt = &row.ExpiresAt.Time
+	}
+
+	return key, nil
+}
+
+func encodePublicKey(public crypto.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(public)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+func newKeyID() (string, error) {
+	b := make([]byte, 12)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"errors"
+
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrMFARequired se devuelve cuando falta el segundo factor. Login devuelve
+	// un *MFAChallenge que cumple errors.Is(err, ErrMFARequired).
+	ErrMFARequired = errors.New("mfa required")
+	// ErrMFAEnrollmentRequired: el tenant exige MFA y el usuario aún no lo configuró.
+	ErrMFAEnrollmentRequired = errors.New("mfa enrollment required")
+	ErrInvalidMFACode        = errors.New("invalid mfa code")
+)
+
+// MFAChallenge es el resultado del primer factor cuando el usuario necesita un
+// segundo: el token se canjea en /auth/mfa/verify (o en /auth/mfa/enroll si
+// EnrollmentRequired) por los mismos tokens que Login.
+type MFAChallenge struct {
+	Token              string   `json:"mfa_token"`
+	Methods            []string `json:"methods"`
+	EnrollmentRequired bool     `json:"enrollment_required"`
+	ExpiresIn          int      `json:"expires_in"`
+}
+
+func (c *MFAChallenge) Error() string {
+	if c.EnrollmentRequired {
+		return ErrMFAEnrollmentRequired.Error()
+	}
+	return ErrMFARequired.Error()
+}
+
+func (c *MFAChallenge) Is(target error) bool {
+	return target == ErrMFARequired
+}
+
+// SecondFactor lo implementa mfa.Service. Se define aquí para que auth no
+// dependa del paquete mfa (que a su vez usa IssueTokens).
+type SecondFactor interface {
+	// Status indica si el usuario tiene un segundo factor configurado y si lo
+	// exige su tenant o el tenant del token (tenantID).
+	Status(ctx context.Context, user *dbgen.User, tenantID uuid.UUID) (enrolled, required bool, err error)
+	// Challenge crea un desafío pendiente del segundo factor.
+	Challenge(ctx context.Context, user *dbgen.User, tenantID uuid.UUID, enrolled bool) (*MFAChallenge, error)
+	// VerifyCode valida un código TOTP o de recuperación del usuario.
+	VerifyCode(ctx context.Context, userID uuid.UUID, code string) error
+}
+
+// UseSecondFactor instala el proveedor de MFA. Sin él, los logins no piden
+// segundo factor.
+func (s *AuthService) UseSecondFactor(sf SecondFactor) {
+	s.mfa = sf
+}
+
+// CompleteLogin termina un login cuyo primer factor ya se verificó (contraseña,
+// magic link, OTP): emite tokens o, si el usuario necesita segundo factor,
+// devuelve un *MFAChallenge como error. Antes comprueba que el usuario
+// pertenece a tenantID y que está activo, y aplica el requisito de email
+// verificado. Los requisitos son los del tenant del usuario y los de tenantID.
+func (s *AuthService) CompleteLogin(ctx context.Context, user *dbgen.User, tenantID uuid.UUID, client ClientInfo) (*LoginResult, error) {
+	// Antes del segundo factor: un tenant suspendido no llega a pedir el código
+	if _, err := s.loginTenant(ctx, user, tenantID.String()); err != nil {
+		return nil, err
+	}
+	if err := s.CheckEmailVerified(ctx, user, tenantID); err != nil {
+		return nil, err
+	}
+
+	if s.mfa != nil {
+		enrolled, required, err := s.mfa.Status(ctx, user, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if enrolled || required {
+			challenge, err := s.mfa.Challenge(ctx, user, tenantID, enrolled)
+			if err != nil {
+				return nil, err
+			}
+			return nil, challenge
+		}
+	}
+
+	return s.issueTokens(ctx, user.ID, tenantID, client)
+}
+
+// AuthenticateWithCode es el login de un solo paso del formulario de
+// /oauth/authorize: contraseña, tenant del usuario activo, email verificado y,
+// si el usuario lo tiene, código MFA. Un código incorrecto cuenta como fallo de login, así que el
+// bloqueo de la cuenta también limita los intentos del segundo factor.
+func (s *AuthService) AuthenticateWithCode(ctx context.Context, email, password, code string, client ClientInfo) (*dbgen.User, error) {
+	user, err := s.Authenticate(ctx, email, password, client)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.loginTenant(ctx, user, ""); err != nil {
+		return nil, err
+	}
+	if err := s.CheckEmailVerified(ctx, user, user.TenantID); err != nil {
+		return nil, err
+	}
+
+	if err := s.CheckSecondFactor(ctx, user, code); err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			s.SecondFactorFailed(ctx, user, client)
+		}
+		return nil, err
+	}
+
+	s.LoginSucceeded(ctx, email)
+	return user, nil
+}
+
+// CheckSecondFactor valida el segundo factor en flujos de un solo paso (el
+// formulario de /oauth/authorize). code vacío devuelve ErrMFARequired si el
+// usuario lo necesita. Usar AuthenticateWithCode, que cuenta los fallos.
+func (s *AuthService) CheckSecondFactor(ctx context.Context, user *dbgen.User, code string) error {
+	if s.mfa == nil {
+		return nil
+	}
+	enrolled, required, err := s.mfa.Status(ctx, user, user.TenantID)
+	if err != nil {
+		return err
+	}
+	if !enrolled {
+		if required {
+			return ErrMFAEnrollmentRequired
+		}
+		return nil
+	}
+	if code == "" {
+		return ErrMFARequired
+	}
+	return s.mfa.VerifyCode(ctx, user.ID, code)
+}
@@ -1,82 +1,180 @@
 package auth
 
 import (
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"fmt"
+	"strings"
 
 	"golang.org/x/crypto/argon2"
 )
 
+// Parámetros actuales de Argon2id. Se pueden subir sin romper nada: cada hash
+// guarda los suyos y los antiguos se rehashean en el siguiente login.
 const (
 	argonTime    = 1
 	argonMemory  = 64 * 1024 // 64 MB
 	argonThreads = 1
 	argonKeyLen  = 32
+	argonSaltLen = 16
 )
 
-// HashPassword generates an Argon2id hash for a plaintext password.
-func HashPassword(password string) (string, error) {
-	salt := make([]byte, 16)
+// Límites de los hashes guardados o importados: verificar uno no debe reservar
+// más memoria ni tiempo que unos pocos hashes normales.
+const (
+	maxHashMemory   = 256 << 20 // bytes (Argon2 m y scrypt 128·r·N)
+	maxHashKeyLen   = 64        // bytes del hash (Argon2, scrypt, PBKDF2)
+	maxArgonTime    = 10
+	maxArgonThreads = 16
+)
+
+var ErrInvalidPasswordHash = errors.New("invalid password hash format")
+
+// argonParams son los parámetros con los que se calculó un hash.
+type argonParams struct {
+	memory  uint32
+	time    uint32
+	threads uint8
+}
+
+var currentArgonParams = argonParams{memory: argonMemory, time: argonTime, threads: argonThreads}
+
+// HashPassword generates an Argon2id hash for a plaintext password in PHC
+// format: $argon2id$v=19$m=65536,t=1,p=1$<salt>$<hash>
+// It runs on the installed HashPool and returns ErrHashPoolBusy when saturated.
+func HashPassword(ctx context.Context, password string) (string, error) {
+	var hash string
+	var err error
+	if perr := runHash(ctx, func() { hash, err = hashPassword(password) }); perr != nil {
+		return "", perr
+	}
+	return hash, err
+}
+
+func hashPassword(password string) (string, error) {
+	salt := make([]byte, argonSaltLen)
 	_, err := rand.Read(salt)
 	if err != nil {
 		return "", err
 	}
 
-	hash := argon2.IDKey([]byte(password), salt, argonTime, argonMemory, argonThreads, argonKeyLen)
-
-	b64Salt := base64.RawStdEncoding.EncodeToString(salt)
-	b64Hash := base64.RawStdEncoding.EncodeToString(hash)
+	p := currentArgonParams
+	hash := argon2.IDKey([]byte(password), salt, p.time, p.memory, p.threads, argonKeyLen)
 
-	// Format: salt$hash
-	return b64Salt + "$" + b64Hash, nil
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.memory, p.time, p.threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
 }
 
-// VerifyPassword compares a plaintext password with an Argon2id stored hash.
-func VerifyPassword(password, stored string) (bool, error) {
-	parts := split(stored, '$')
-	if len(parts) != 2 {
-		return false, errors.New("invalid password hash format")
+// VerifyPassword compares a plaintext password with a stored Argon2id hash,
+// in PHC format or in the legacy "salt$hash" format, or with an imported one
+// (see foreign_hashes.go). It runs on the installed HashPool and returns
+// ErrHashPoolBusy when saturated.
+func VerifyPassword(ctx context.Context, password, stored string) (bool, error) {
+	var ok bool
+	var err error
+	if perr := runHash(ctx, func() { ok, err = verifyPassword(password, stored) }); perr != nil {
+		return false, perr
 	}
+	return ok, err
+}
 
-	saltBytes, err := base64.RawStdEncoding.DecodeString(parts[0])
-	if err != nil {
-		return false, err
+func verifyPassword(password, stored string) (bool, error) {
+	if h, ok, err := parseForeignHash(stored); ok {
+		if err != nil {
+			return false, err
+		}
+		return h.verify(password)
 	}
 
-	hashBytes, err := base64.RawStdEncoding.DecodeString(parts[1])
+	params, salt, hash, err := decodeArgonHash(stored)
 	if err != nil {
 		return false, err
 	}
 
-	calculated := argon2.IDKey([]byte(password), saltBytes, argonTime, argonMemory, argonThreads, uint32(len(hashBytes)))
+	calculated := argon2.IDKey([]byte(password), salt, params.time, params.memory, params.threads, uint32(len(hash)))
 
-	if subtleConstantCompare(calculated, hashBytes) {
+	if subtleConstantCompare(calculated, hash) {
 		return true, nil
 	}
 
 	return false, nil
 }
 
-// ---- Helpers ----
+// NeedsRehash indica si el hash se calculó con otro formato (legacy o
+// importado) o con parámetros distintos de los actuales. Tras un login
+// correcto se sustituye por uno nuevo.
+func NeedsRehash(stored string) bool {
+	if !strings.HasPrefix(stored, "$argon2id$") {
+		return true
+	}
+	params, _, hash, err := decodeArgonHash(stored)
+	if err != nil {
+		return true
+	}
+	return params != currentArgonParams || len(hash) != argonKeyLen
+}
 
-func split(s string, sep rune) []string {
-	var parts []string
-	current := ""
+// decodeArgonHash extrae parámetros, salt y hash de un hash PHC o legacy.
+func decodeArgonHash(stored string) (argonParams, []byte, []byte, error) {
+	var params argonParams
+	var b64Salt, b64Hash string
 
-	for _, c := range s {
-		if c == sep {
-			parts = append(parts, current)
-			current = ""
-		} else {
-			current += string(c)
+	if strings.HasPrefix(stored, "$") {
+		// $argon2id$v=19$m=...,t=...,p=...$salt$hash
+		parts := strings.Split(stored, "$")
+		if len(parts) != 6 || parts[1] != "argon2id" {
+			return params, nil, nil, ErrInvalidPasswordHash
+		}
+
+		var version int
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+			return params, nil, nil, ErrInvalidPasswordHash
+		}
+		if version != argon2.Version {
+			return params, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+		}
+
+		if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.memory, &params.time, &params.threads); err != nil {
+			return params, nil, nil, ErrInvalidPasswordHash
+		}
+		if params.time == 0 || params.threads == 0 {
+			return params, nil, nil, ErrInvalidPasswordHash
+		}
+		if params.memory > maxHashMemory/1024 || params.time > maxArgonTime || params.threads > maxArgonThreads {
+			return params, nil, nil, fmt.Errorf("argon2 parameters out of range")
 		}
+		b64Salt, b64Hash = parts[4], parts[5]
+	} else {
+		// Formato legacy: salt$hash con los parámetros originales
+		parts := strings.Split(stored, "$")
+		if len(parts) != 2 {
+			return params, nil, nil, ErrInvalidPasswordHash
+		}
+		params = argonParams{memory: 64 * 1024, time: 1, threads: 1}
+		b64Salt, b64Hash = parts[0], parts[1]
 	}
-	parts = append(parts, current)
 
-	return parts
+	salt, err := base64.RawStdEncoding.DecodeString(b64Salt)
+	if err != nil {
+		return params, nil, nil, err
+	}
+	hash, err := base64.RawStdEncoding.DecodeString(b64Hash)
+	if err != nil {
+		return params, nil, nil, err
+	}
+	if len(hash) == 0 || len(hash) > maxHashKeyLen {
+		return params, nil, nil, ErrInvalidPasswordHash
+	}
+	return params, salt, hash, nil
 }
 
+// ---- Helpers ----
+
 func subtleConstantCompare(a, b []byte) bool {
 	if len(a) != len(b) {
 		return false
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrPasswordPolicy: la contraseña nueva incumple la política del tenant.
+	// Se devuelve un *PasswordPolicyError que cumple errors.Is(err, ErrPasswordPolicy).
+	ErrPasswordPolicy = errors.New("password does not meet the password policy")
+	// ErrPasswordExpired: la contraseña superó la edad máxima del tenant y debe
+	// cambiarse en /auth/password/change antes de iniciar sesión.
+	ErrPasswordExpired = errors.New("password expired")
+)
+
+// Códigos de las reglas incumplidas.
+const (
+	ViolationTooShort         = "too_short"
+	ViolationTooLong          = "too_long"
+	ViolationMissingUppercase = "missing_uppercase"
+	ViolationMissingLowercase = "missing_lowercase"
+	ViolationMissingDigit     = "missing_digit"
+	ViolationMissingSymbol    = "missing_symbol"
+	ViolationBannedWord       = "banned_word"
+	ViolationUserInfo         = "contains_user_info"
+	ViolationReused           = "reused"
+	ViolationBreached         = "breached"
+)
+
+// PolicyViolation es una regla incumplida; Code es estable para que el
+// frontend muestre su propio mensaje.
+type PolicyViolation struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type PasswordPolicyError struct {
+	Violations []PolicyViolation
+}
+
+func (e *PasswordPolicyError) Error() string {
+	msgs := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		msgs[i] = v.Message
+	}
+	return ErrPasswordPolicy.Error() + ": " + strings.Join(msgs, "; ")
+}
+
+func (e *PasswordPolicyError) Is(target error) bool {
+	return target == ErrPasswordPolicy
+}
+
+// PasswordRules son las reglas de composición de una contraseña.
+type PasswordRules struct {
+	MinLength     int
+	MaxLength     int // 0 = sin máximo
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+	BannedWords   []string // no pueden aparecer en la contraseña (sin distinguir mayúsculas)
+}
+
+// DefaultPasswordRules se aplican sin política instalada y a los tenants que
+// no configuran la suya.
+var DefaultPasswordRules = PasswordRules{
+	MinLength: 8,
+	MaxLength: 128,
+}
+
+// Las partes del email o del nombre más cortas no cuentan como dato personal.
+const minUserInfoLength = 4
+
+// Check devuelve las reglas que incumple password. user aporta email y nombre,
+// que no pueden aparecer en la contraseña.
+func (r PasswordRules) Check(user *dbgen.User, password string) []PolicyViolation {
+	var violations []PolicyViolation
+	add := func(code, format string, args ...any) {
+		violations = append(violations, PolicyViolation{Code: code, Message: fmt.Sprintf(format, args...)})
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < r.MinLength {
+		add(ViolationTooShort, "must be at least %d characters", r.MinLength)
+	}
+	if r.MaxLength > 0 && length > r.MaxLength {
+		add(ViolationTooLong, "must be at most %d characters", r.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, c := range password {
+		switch {
+		case unicode.IsUpper(c):
+			upper = true
+		case unicode.IsLower(c):
+			lower = true
+		case unicode.IsDigit(c):
+			digit = true
+		case !unicode.IsLetter(c):
+			symbol = true
+		}
+	}
+	if r.RequireUpper && !upper {
+		add(ViolationMissingUppercase, "must contain an uppercase letter")
+	}
+	if r.RequireLower && !lower {
+		add(ViolationMissingLowercase, "must contain a lowercase letter")
+	}
+	if r.RequireDigit && !digit {
+		add(ViolationMissingDigit, "must contain a digit")
+	}
+	if r.RequireSymbol && !symbol {
+		add(ViolationMissingSymbol, "must contain a symbol")
+	}
+
+	lowered := strings.ToLower(password)
+	for _, word := range r.BannedWords {
+		word = strings.ToLower(strings.TrimSpace(word))
+		if word != "" && strings.Contains(lowered, word) {
+			add(ViolationBannedWord, "must not contain %q", word)
+			break
+		}
+	}
+
+	if user != nil {
+		for _, part := range userInfo(user) {
+			if strings.Contains(lowered, part) {
+				add(ViolationUserInfo, "must not contain your name or email")
+				break
+			}
+		}
+	}
+
+	return violations
+}
+
+// userInfo devuelve las partes del email y del nombre que no pueden aparecer en la contraseña.
+func userInfo(user *dbgen.User) []string {
+	local, _, _ := strings.Cut(user.Email, "@")
+	fields := strings.FieldsFunc(strings.ToLower(local+" "+user.DisplayName), func(c rune) bool {
+		return !unicode.IsLetter(c) && !unicode.IsDigit(c)
+	})
+
+	var parts []string
+	for _, f := range fields {
+		if utf8.RuneCountInString(f) >= minUserInfoLength {
+			parts = append(parts, f)
+		}
+	}
+	return parts
+}
+
+// PasswordPolicy lo implementa passwordpolicy.Service. Se define aquí por el
+// mismo motivo que SecondFactor: el paquete passwordpolicy depende de auth.
+type PasswordPolicy interface {
+	// Validate devuelve las reglas del tenant del usuario que incumple password,
+	// incluido el historial si el usuario ya existe (user.ID distinto de uuid.Nil).
+	Validate(ctx context.Context, user *dbgen.User, password string) ([]PolicyViolation, error)
+	// Remember guarda el hash en el historial de contraseñas del usuario.
+	Remember(ctx context.Context, userID uuid.UUID, passwordHash string) error
+	// MaxAge devuelve la edad máxima de las contraseñas del tenant (0 = no caducan).
+	MaxAge(ctx context.Context, tenantID uuid.UUID) time.Duration
+}
+
+// UsePasswordPolicy instala la política de contraseñas por tenant. Sin ella se
+// aplican DefaultPasswordRules, sin historial ni caducidad.
+func (s *AuthService) UsePasswordPolicy(p PasswordPolicy) {
+	s.policy = p
+}
+
+// ValidatePassword aplica la política del tenant a una contraseña nueva del
+// usuario. Devuelve un *PasswordPolicyError con las reglas incumplidas.
+func (s *AuthService) ValidatePassword(ctx context.Context, user *dbgen.User, password string) error {
+	var violations []PolicyViolation
+	if s.policy == nil {
+		violations = DefaultPasswordRules.Check(user, password)
+	} else {
+		var err error
+		violations, err = s.policy.Validate(ctx, user, password)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(violations) > 0 {
+		return &PasswordPolicyError{Violations: violations}
+	}
+	return nil
+}
+
+// rememberPassword guarda el hash en el historial. Un fallo solo se registra:
+// la contraseña ya está cambiada.
+func (s *AuthService) rememberPassword(ctx context.Context, userID uuid.UUID, passwordHash string) {
+	if s.policy == nil {
+		return
+	}
+	if err := s.policy.Remember(ctx, userID, passwordHash); err != nil {
+		log.Printf("⚠️  Error guardando historial de contraseñas: %v", err)
+	}
+}
+
+// passwordExpired indica si la contraseña, cambiada por última vez en
+// changedAt, superó la edad máxima del tenant del usuario.
+func (s *AuthService) passwordExpired(ctx context.Context, user *dbgen.User, changedAt time.Time) bool {
+	if s.policy == nil {
+		return false
+	}
+	maxAge := s.policy.MaxAge(ctx, user.TenantID)
+	return maxAge > 0 && time.Since(changedAt) > maxAge
+}
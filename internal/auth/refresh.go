@@ -4,20 +4,28 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
-// GenerateRefreshToken creates a cryptographically secure random string.
-// Format: base64url(64 bytes) ≈ 86 characters.
-func GenerateRefreshToken() (string, error) {
-	bytes := make([]byte, 64) // 512 bits
+// Largo mínimo de los refresh tokens anteriores al formato <session_id>.<secret>,
+// que se siguen aceptando hasta que expiren.
+const legacyRefreshTokenMinLength = 80
+
+// GenerateRefreshToken creates a refresh token for the given session.
+// Format: <session_id>.<secret>, where secret is base64url(32 random bytes).
+// Only a keyed hash of the token is stored (see sessions.HashRefreshToken).
+func GenerateRefreshToken(sessionID uuid.UUID) (string, error) {
+	bytes := make([]byte, 32) // 256 bits
 	_, err := rand.Read(bytes)
 	if err != nil {
 		return "", err
 	}
 
 	// URL-safe, no padding
-	return base64.RawURLEncoding.EncodeToString(bytes), nil
+	return sessionID.String() + "." + base64.RawURLEncoding.EncodeToString(bytes), nil
 }
 
 // ValidateRefreshToken ensures the token is structurally correct.
@@ -27,8 +35,19 @@ func ValidateRefreshToken(token string) error {
 		return errors.New("empty refresh token")
 	}
 
-	// Minimum: at least 80 chars for cryptographic safety
-	if len(token) < 80 {
+	id, secret, ok := strings.Cut(token, ".")
+	if !ok {
+		// Formato anterior: solo el secreto aleatorio
+		if len(token) < legacyRefreshTokenMinLength {
+			return errors.New("refresh token too small")
+		}
+		return nil
+	}
+
+	if _, err := uuid.Parse(id); err != nil {
+		return errors.New("malformed refresh token")
+	}
+	if len(secret) < 43 {
 		return errors.New("refresh token too small")
 	}
 
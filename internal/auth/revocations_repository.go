@@ -0,0 +1,59 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type RevocationsRepository struct {
+	q *gen.Queries
+}
+
+func NewRevocationsRepository(db gen.DBTX) *RevocationsRepository {
+	return &RevocationsRepository{q: gen.New(db)}
+}
+
+// RevokeToken añade un jti a la denylist hasta que el token expire.
+func (r *RevocationsRepository) RevokeToken(ctx context.Context, jti string, expiresAt time.Time) error {
+	return r.q.RevokeToken(ctx, gen.RevokeTokenParams{
+		Jti:       jti,
+		ExpiresAt: expiresAt,
+		RevokedAt: time.Now(),
+	})
+}
+
+// DeleteExpired elimina los jti revocados cuyo token ya expiró.
+func (r *RevocationsRepository) DeleteExpired(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredRevokedTokens(ctx)
+}
+
+// ListRevokedTokens devuelve los jti revocados que todavía no expiraron.
+func (r *RevocationsRepository) ListRevokedTokens(ctx context.Context) ([]gen.RevokedToken, error) {
+	return r.q.ListRevokedTokens(ctx)
+}
+
+// SetUserTokensValidAfter invalida los access tokens del usuario emitidos antes de t.
+func (r *RevocationsRepository) SetUserTokensValidAfter(ctx context.Context, userID uuid.UUID, t time.Time) error {
+	return r.q.SetUserTokensValidAfter(ctx, gen.SetUserTokensValidAfterParams{
+		ID:               userID,
+		TokensValidAfter: sql.NullTime{Time: t, Valid: true},
+	})
+}
+
+// ListUserTokenEpochs devuelve los usuarios cuyo "tokens valid after" es posterior a since.
+func (r *RevocationsRepository) ListUserTokenEpochs(ctx context.Context, since time.Time) ([]gen.ListUserTokenEpochsRow, error) {
+	return r.q.ListUserTokenEpochs(ctx, sql.NullTime{Time: since, Valid: true})
+}
+
+// SetTenantTokensValidAfter invalida los access tokens emitidos antes de t a los
+// usuarios del tenant y a los que tienen sesiones en él.
+func (r *RevocationsRepository) SetTenantTokensValidAfter(ctx context.Context, tenantID uuid.UUID, t time.Time) error {
+	return r.q.SetTenantTokensValidAfter(ctx, gen.SetTenantTokensValidAfterParams{
+		TenantID:         tenantID,
+		TokensValidAfter: sql.NullTime{Time: t, Valid: true},
+	})
+}
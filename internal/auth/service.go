@@ -2,9 +2,12 @@ package auth
 
 import (
 	"context"
+	"database/sql"
 	"errors"
+	"log"
 	"time"
 
+	"github.com/fzalvarez/odin-iam/internal/audit"
 	// Usamos alias para evitar problemas si el paquete se llama 'db' o 'gen'
 	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
 	"github.com/fzalvarez/odin-iam/internal/sessions"
@@ -25,8 +28,13 @@ type AuthEmailsRepository interface {
 }
 
 type AuthSessionsRepository interface {
-	CreateSession(ctx context.Context, sessionID, userID, tenantID uuid.UUID, refreshToken, userAgent, clientIP string, expiresAt time.Time) error
+	CreateSession(ctx context.Context, sessionID, familyID, userID, tenantID, clientID uuid.UUID, refreshToken, userAgent, clientIP string, expiresAt time.Time) error
 	GetByRefreshToken(ctx context.Context, refreshToken string) (*sessions.SessionModel, error)
+	ConsumeSession(ctx context.Context, refreshToken string) (*sessions.SessionModel, error)
+	DeleteSession(ctx context.Context, id uuid.UUID) error
+	DeleteFamily(ctx context.Context, familyID uuid.UUID) (int64, error)
+	DeleteByUser(ctx context.Context, userID uuid.UUID) (int64, error)
+	DeleteByTenant(ctx context.Context, tenantID uuid.UUID) (int64, error)
 }
 
 type TokenResponse struct {
@@ -34,11 +42,46 @@ type TokenResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
+// ClientInfo identifica el dispositivo que abre o rota una sesión; se guarda
+// en la sesión para que el usuario reconozca sus dispositivos.
+type ClientInfo struct {
+	UserAgent string
+	IP        string
+	// OAuthClientID es el cliente OAuth que pide los tokens (vacío = /auth/*).
+	// Solo ese cliente puede canjear el refresh token de la sesión.
+	OAuthClientID string
+}
+
+// oauthClientID devuelve el cliente OAuth como UUID (uuid.Nil si no hay).
+func (c ClientInfo) oauthClientID() (uuid.UUID, error) {
+	if c.OAuthClientID == "" {
+		return uuid.Nil, nil
+	}
+	return uuid.Parse(c.OAuthClientID)
+}
+
+// Límite del user agent guardado: el header lo controla el cliente.
+const maxUserAgentLength = 512
+
+func (c ClientInfo) userAgent() string {
+	if len(c.UserAgent) > maxUserAgentLength {
+		return c.UserAgent[:maxUserAgentLength]
+	}
+	return c.UserAgent
+}
+
 type AuthService struct {
 	users       AuthUsersRepository
 	emails      AuthEmailsRepository
 	credentials *CredentialsRepository
 	sessions    AuthSessionsRepository
+	denylist    *Denylist
+	audit       *audit.Service
+	mfa         SecondFactor
+	verifier    EmailVerifier
+	guard       LoginGuard
+	policy      PasswordPolicy
+	tenants     TenantDirectory
 }
 
 // Ajustamos el constructor para aceptar cualquier implementación que cumpla las interfaces
@@ -49,12 +92,16 @@ func NewService(
 	emails AuthEmailsRepository,
 	credentials *CredentialsRepository,
 	sessions AuthSessionsRepository,
+	denylist *Denylist,
+	auditService *audit.Service,
 ) *AuthService {
 	return &AuthService{
 		users:       users,
 		emails:      emails,
 		credentials: credentials,
 		sessions:    sessions,
+		denylist:    denylist,
+		audit:       auditService,
 	}
 }
 
@@ -64,58 +111,86 @@ func NewService(
 
 type RegisterResult struct {
 	UserID       string `json:"user_id"`
-	AccessToken  string `json:"access_token"`
-	RefreshToken string `json:"refresh_token"`
+	AccessToken  string `json:"access_token,omitempty"`
+	RefreshToken string `json:"refresh_token,omitempty"`
 	TenantID     string `json:"tenant_id"`
+	// EmailVerificationRequired: el tenant exige verificar el email antes del
+	// primer login, así que el registro no devuelve tokens.
+	EmailVerificationRequired bool `json:"email_verification_required,omitempty"`
 }
 
-func (s *AuthService) Register(ctx context.Context, name, email, password string) (*RegisterResult, error) {
+func (s *AuthService) Register(ctx context.Context, name, email, password string, client ClientInfo) (*RegisterResult, error) {
 	// 0) System-level tenant (UUID vacío = NULL)
 	var tenantUUID uuid.UUID
 
-	// 1) Crear usuario
-	user, err := s.users.CreateUser(ctx, tenantUUID, name, email)
-	if err != nil {
+	// 1) Política de contraseñas del tenant, antes de crear nada
+	candidate := &dbgen.User{TenantID: tenantUUID, DisplayName: name, Email: email}
+	if err := s.ValidatePassword(ctx, candidate, password); err != nil {
 		return nil, err
 	}
 
-	// 2) Crear credencial
-	hash, err := HashPassword(password)
+	// 2) Hash antes de crear el usuario: con el pool saturado no queda un
+	// usuario sin credencial
+	hash, err := HashPassword(ctx, password)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.credentials.CreateCredential(ctx, user.ID.String(), hash)
+	// 3) Crear usuario y credencial
+	user, err := s.users.CreateUser(ctx, tenantUUID, name, email)
 	if err != nil {
 		return nil, err
 	}
 
-	// 3) Crear sesión (refresh token)
-	refresh, err := GenerateRefreshToken()
+	err = s.credentials.CreateCredential(ctx, user.ID.String(), hash)
 	if err != nil {
 		return nil, err
 	}
+	s.rememberPassword(ctx, user.ID, hash)
+
+	// 4) Enviar link de verificación; si el tenant exige email verificado no
+	// se emiten tokens hasta que se confirme
+	if s.verifier != nil {
+		if err := s.verifier.SendVerification(ctx, user); err != nil {
+			log.Printf("⚠️  Error enviando verificación de email: %v", err)
+		}
+		if err := s.verifier.CheckLogin(ctx, user, user.TenantID); err != nil {
+			if !errors.Is(err, ErrEmailNotVerified) {
+				return nil, err
+			}
+			return &RegisterResult{
+				UserID:                    user.ID.String(),
+				TenantID:                  user.TenantID.String(),
+				EmailVerificationRequired: true,
+			}, nil
+		}
+	}
 
-	expires := time.Now().UTC().Add(RefreshSessionTTL())
-	sessionID := uuid.New()
-
-	err = s.sessions.CreateSession(ctx, sessionID, user.ID, tenantUUID, refresh, "", "", expires)
+	// 5) Crear sesión y tokens
+	res, err := s.IssueTokens(ctx, user.ID, user.TenantID, client)
 	if err != nil {
 		return nil, err
 	}
 
-	// 4) JWT
-	access, err := GenerateAccessToken(user.ID.String(), tenantUUID.String(), 15*time.Minute)
+	return (*RegisterResult)(res), nil
+}
+
+// ImportUser crea un usuario migrado de otro sistema con su hash de contraseña
+// original, para que conserve la contraseña. El hash no pasa por la política
+// del tenant (no se conoce la contraseña); se sustituye por Argon2id en el
+// primer login correcto. emailVerified marca el email como ya verificado.
+func (s *AuthService) ImportUser(ctx context.Context, tenantID uuid.UUID, name, email, passwordHash string, emailVerified bool) (*dbgen.User, error) {
+	// Usuario y credencial se crean juntos: un hash no soportado no deja el usuario a medias
+	user, err := s.credentials.ImportUser(ctx, tenantID, name, email, passwordHash)
 	if err != nil {
 		return nil, err
 	}
+	s.rememberPassword(ctx, user.ID, passwordHash)
 
-	return &RegisterResult{
-		UserID:       user.ID.String(),
-		AccessToken:  access,
-		RefreshToken: refresh,
-		TenantID:     tenantUUID.String(),
-	}, nil
+	if emailVerified {
+		s.ConfirmEmail(ctx, user)
+	}
+	return user, nil
 }
 
 // ----------------------------------------------
@@ -124,45 +199,147 @@ func (s *AuthService) Register(ctx context.Context, name, email, password string
 
 type LoginResult RegisterResult
 
-func (s *AuthService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+// ErrInvalidCredentials se devuelve para cualquier fallo de email/contraseña,
+// sin distinguir si el usuario existe.
+var ErrInvalidCredentials = errors.New("invalid credentials")
+
+// Login emite tokens del tenant indicado en tenant (key o ID), que debe ser el
+// del usuario o uno en el que tenga un rol; vacío = el tenant del usuario.
+func (s *AuthService) Login(ctx context.Context, email, password, tenant string, client ClientInfo) (*LoginResult, error) {
+	user, err := s.Authenticate(ctx, email, password, client)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, err := s.loginTenant(ctx, user, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	// Con MFA, el error es un *MFAChallenge en lugar de tokens
+	res, err := s.CompleteLogin(ctx, user, tenantID, client)
+	if err != nil {
+		return nil, err
+	}
+	s.LoginSucceeded(ctx, email)
+	return res, nil
+}
+
+// Authenticate verifica email y contraseña sin crear sesión.
+// La usan Login y los flujos que emiten tokens por otra vía (OAuth).
+// Con un LoginGuard, los fallos cuentan para el bloqueo de la cuenta y la IP.
+// Una contraseña correcta no reinicia el contador: lo hace LoginSucceeded
+// cuando el login termina, también el segundo factor.
+// Una contraseña correcta pero caducada devuelve ErrPasswordExpired.
+func (s *AuthService) Authenticate(ctx context.Context, email, password string, client ClientInfo) (*dbgen.User, error) {
+	user, cred, err := s.verifyCredentials(ctx, email, password, client)
+	if err != nil {
+		return nil, err
+	}
+	if s.passwordExpired(ctx, user, cred.UpdatedAt) {
+		return nil, ErrPasswordExpired
+	}
+	return user, nil
+}
+
+// verifyCredentials comprueba email y contraseña sin mirar la caducidad, que
+// ChangePassword debe poder saltarse.
+func (s *AuthService) verifyCredentials(ctx context.Context, email, password string, client ClientInfo) (*dbgen.User, *dbgen.Credential, error) {
+	// 0) Cuenta o IP bloqueada: no se llega a probar la contraseña
+	if s.guard != nil {
+		if err := s.guard.Check(ctx, email, client); err != nil {
+			return nil, nil, err
+		}
+	}
+
 	// 1) Buscar usuario por email
 	user, err := s.emails.GetUserByEmail(ctx, email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, s.authenticationFailed(ctx, email, nil, client)
 	}
 
-	userID := user.ID
-
 	// 2) Credencial
-	cred, err := s.credentials.GetByUserID(ctx, userID.String())
+	cred, err := s.credentials.GetByUserID(ctx, user.ID.String())
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, s.authenticationFailed(ctx, email, user, client)
 	}
 
-	ok, err := VerifyPassword(password, cred.PasswordHash)
+	ok, err := VerifyPassword(ctx, password, cred.PasswordHash)
+	// Pool saturado o petición cancelada: no es un intento fallido
+	if isHashPoolError(err) {
+		return nil, nil, err
+	}
 	if err != nil || !ok {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, s.authenticationFailed(ctx, email, user, client)
+	}
+	// Usuario desactivado: la contraseña es correcta, así que no cuenta como
+	// fallo, pero responde igual para no revelar el estado de la cuenta
+	if !user.IsActive {
+		return nil, nil, ErrInvalidCredentials
 	}
 
-	// Tenant vacío
-	var tenantUUID uuid.UUID
+	// Hash en formato o parámetros antiguos: se actualiza ahora que tenemos la contraseña
+	if NeedsRehash(cred.PasswordHash) {
+		s.rehashPassword(ctx, cred, password)
+	}
+	return user, cred, nil
+}
 
-	// 3) Refresh token
-	refresh, err := GenerateRefreshToken()
+// rehashPassword guarda la contraseña con los parámetros de Argon2id actuales.
+// Un fallo solo se registra: el login ya es válido y se reintentará en el siguiente.
+func (s *AuthService) rehashPassword(ctx context.Context, cred *dbgen.Credential, password string) {
+	hash, err := HashPassword(ctx, password)
 	if err != nil {
+		log.Printf("⚠️  Error rehasheando contraseña: %v", err)
+		return
+	}
+	if _, err := s.credentials.RehashPassword(ctx, cred.UserID, cred.PasswordHash, hash); err != nil {
+		log.Printf("⚠️  Error rehasheando contraseña: %v", err)
+	}
+}
+
+func (s *AuthService) authenticationFailed(ctx context.Context, email string, user *dbgen.User, client ClientInfo) error {
+	if s.guard != nil {
+		s.guard.Failed(ctx, email, user, client)
+	}
+	return ErrInvalidCredentials
+}
+
+// IssueTokens crea una sesión (refresh token) y un access token para el usuario.
+// Es el final de todos los logins (contraseña, magic link, OTP, passkey, MFA,
+// OAuth): aquí se comprueba que el usuario pertenece a tenantID y que el tenant
+// sigue activo, así uno suspendido no abre sesiones por ningún método.
+func (s *AuthService) IssueTokens(ctx context.Context, userID, tenantID uuid.UUID, client ClientInfo) (*LoginResult, error) {
+	if err := s.checkTokenTenant(ctx, userID, tenantID); err != nil {
 		return nil, err
 	}
+	return s.issueTokens(ctx, userID, tenantID, client)
+}
 
-	expires := time.Now().UTC().Add(RefreshSessionTTL())
+// issueTokens es IssueTokens sin comprobar el tenant, para quien ya lo hizo.
+func (s *AuthService) issueTokens(ctx context.Context, userID, tenantID uuid.UUID, client ClientInfo) (*LoginResult, error) {
+	// 1) Refresh token
 	sessionID := uuid.New()
+	refresh, err := GenerateRefreshToken(sessionID)
+	if err != nil {
+		return nil, err
+	}
 
-	err = s.sessions.CreateSession(ctx, sessionID, userID, tenantUUID, refresh, "", "", expires)
+	expires := time.Now().UTC().Add(RefreshSessionTTL())
+
+	clientID, err := client.oauthClientID()
 	if err != nil {
 		return nil, err
 	}
 
-	// 4) JWT
-	access, err := GenerateAccessToken(userID.String(), tenantUUID.String(), 15*time.Minute)
+	// Cada login abre una familia de rotación nueva
+	err = s.sessions.CreateSession(ctx, sessionID, sessionID, userID, tenantID, clientID, refresh, client.userAgent(), client.IP, expires)
+	if err != nil {
+		return nil, err
+	}
+
+	// 2) JWT
+	access, err := GenerateAccessToken(userID.String(), tenantID.String(), AccessTokenTTL)
 	if err != nil {
 		return nil, err
 	}
@@ -171,51 +348,78 @@ func (s *AuthService) Login(ctx context.Context, email, password string) (*Login
 		UserID:       userID.String(),
 		AccessToken:  access,
 		RefreshToken: refresh,
-		TenantID:     tenantUUID.String(),
+		TenantID:     tenantID.String(),
 	}, nil
 }
 
-func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenResponse, error) {
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReused indica que se presentó un refresh token ya rotado:
+	// la familia completa queda revocada.
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+)
+
+// Refresh rota el refresh token. La sesión se consume de forma atómica, así que
+// un refresh token solo sirve una vez; la nueva sesión hereda la familia.
+// Solo lo canjea el cliente al que se emitió (client.OAuthClientID, vacío para
+// /auth/refresh): un cliente distinto recibe ErrInvalidRefreshToken y la sesión
+// sigue intacta.
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string, client ClientInfo) (*TokenResponse, error) {
 	if err := ValidateRefreshToken(refreshToken); err != nil {
 		return nil, err
 	}
 
-	// 1) Buscar sesión
-	session, err := s.sessions.GetByRefreshToken(ctx, refreshToken)
+	if err := s.checkSessionClient(ctx, refreshToken, client); err != nil {
+		return nil, err
+	}
+
+	// 1) Consumir sesión
+	session, err := s.sessions.ConsumeSession(ctx, refreshToken)
 	if err != nil {
-		return nil, errors.New("invalid refresh token")
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, s.detectReuse(ctx, refreshToken)
+		}
+		return nil, err
 	}
 
-	if time.Now().UTC().After(session.ExpiresAt) {
+	if session.IsExpired() {
 		return nil, errors.New("refresh token expired")
 	}
 
 	// 2) Rotar refresh token
-	newRefresh, err := GenerateRefreshToken()
+	familyID, err := uuid.Parse(session.FamilyID)
 	if err != nil {
 		return nil, err
 	}
-
-	expires := time.Now().UTC().Add(RefreshSessionTTL())
-	sessionID := uuid.New()
-
-	// Parsear UUIDs de string a uuid.UUID
 	userID, err := uuid.Parse(session.UserID)
 	if err != nil {
 		return nil, err
 	}
+
+	// Un usuario desactivado no puede seguir rotando: se cierra la familia
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		if _, err := s.sessions.DeleteFamily(ctx, familyID); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
 	tenantID, err := uuid.Parse(session.TenantID)
 	if err != nil {
 		return nil, err
 	}
 
-	err = s.sessions.CreateSession(ctx, sessionID, userID, tenantID, newRefresh, "", "", expires)
+	newRefresh, err := s.rotateSession(ctx, session, tenantID, client)
 	if err != nil {
 		return nil, err
 	}
 
 	// 3) Nuevo access JWT
-	access, err := GenerateAccessToken(session.UserID, session.TenantID, 15*time.Minute)
+	access, err := GenerateAccessToken(session.UserID, session.TenantID, AccessTokenTTL)
 	if err != nil {
 		return nil, err
 	}
@@ -226,20 +430,347 @@ func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenR
 	}, nil
 }
 
+// rotateSession abre la sesión que sustituye a una ya consumida, en su misma
+// familia, y devuelve el nuevo refresh token. tenantID es el de la sesión
+// anterior salvo al cambiar de tenant.
+func (s *AuthService) rotateSession(ctx context.Context, prev *sessions.SessionModel, tenantID uuid.UUID, client ClientInfo) (string, error) {
+	familyID, err := uuid.Parse(prev.FamilyID)
+	if err != nil {
+		return "", err
+	}
+	userID, err := uuid.Parse(prev.UserID)
+	if err != nil {
+		return "", err
+	}
+
+	sessionID := uuid.New()
+	refresh, err := GenerateRefreshToken(sessionID)
+	if err != nil {
+		return "", err
+	}
+	expires := time.Now().UTC().Add(RefreshSessionTTL())
+
+	// El dispositivo puede cambiar de red entre refresh; si no llega el dato se
+	// conserva el de la sesión anterior
+	if client.UserAgent == "" {
+		client.UserAgent = prev.UserAgent
+	}
+	if client.IP == "" {
+		client.IP = prev.ClientIP
+	}
+
+	// La sesión sigue ligada al cliente OAuth al que se emitió
+	clientID := uuid.Nil
+	if prev.ClientID != "" {
+		if clientID, err = uuid.Parse(prev.ClientID); err != nil {
+			return "", err
+		}
+	}
+
+	err = s.sessions.CreateSession(ctx, sessionID, familyID, userID, tenantID, clientID, refresh, client.userAgent(), client.IP, expires)
+	if err != nil {
+		return "", err
+	}
+	return refresh, nil
+}
+
+// checkSessionClient comprueba, sin consumirla, que la sesión del refresh token
+// se emitió al cliente que la presenta (RFC 6749 §6). Un token desconocido o ya
+// consumido pasa: lo resuelve ConsumeSession (y la detección de reutilización).
+func (s *AuthService) checkSessionClient(ctx context.Context, refreshToken string, client ClientInfo) error {
+	session, err := s.sessions.GetByRefreshToken(ctx, refreshToken)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if session.ConsumedAt == nil && session.ClientID != client.OAuthClientID {
+		return ErrInvalidRefreshToken
+	}
+	return nil
+}
+
+// detectReuse se llama cuando un refresh token no pudo consumirse. Si el token
+// existe pero ya fue rotado, alguien está reutilizándolo (robo o replay): se
+// revoca toda la familia y los access tokens del usuario, y se emite una alerta.
+func (s *AuthService) detectReuse(ctx context.Context, refreshToken string) error {
+	session, err := s.sessions.GetByRefreshToken(ctx, refreshToken)
+	if err != nil || session.ConsumedAt == nil {
+		return ErrInvalidRefreshToken
+	}
+
+	familyID, err := uuid.Parse(session.FamilyID)
+	if err != nil {
+		return err
+	}
+	revoked, err := s.sessions.DeleteFamily(ctx, familyID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.RevokeUserTokens(ctx, session.UserID); err != nil {
+		return err
+	}
+
+	s.audit.Alert(ctx, audit.Event{
+		Type:     audit.EventRefreshTokenReuse,
+		TenantID: session.TenantID,
+		UserID:   session.UserID,
+		Metadata: map[string]any{
+			"family_id":        session.FamilyID,
+			"session_id":       session.ID,
+			"consumed_at":      session.ConsumedAt,
+			"revoked_sessions": revoked,
+		},
+	})
+
+	return ErrRefreshTokenReused
+}
+
+// UpdatePassword cambia la contraseña aplicando la política del tenant del
+// usuario (composición e historial). Devuelve un *PasswordPolicyError si no la cumple.
 func (s *AuthService) UpdatePassword(ctx context.Context, userID, newPassword string) error {
-	hash, err := HashPassword(newPassword)
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	user, err := s.users.GetByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+	if err := s.ValidatePassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+
+	hash, err := HashPassword(ctx, newPassword)
 	if err != nil {
 		return err
 	}
-	return s.credentials.UpdateCredentialPassword(ctx, userID, hash)
+	if err := s.credentials.UpdateCredentialPassword(ctx, userID, hash); err != nil {
+		return err
+	}
+	s.rememberPassword(ctx, user.ID, hash)
+	return nil
+}
+
+// ChangePassword cambia la contraseña con la actual, también si ya caducó, y
+// cierra las demás sesiones del usuario. Termina como un login: devuelve
+// tokens (del tenant indicado, como Login) o un *MFAChallenge.
+func (s *AuthService) ChangePassword(ctx context.Context, email, currentPassword, newPassword, tenant string, client ClientInfo) (*LoginResult, error) {
+	user, _, err := s.verifyCredentials(ctx, email, currentPassword, client)
+	if err != nil {
+		return nil, err
+	}
+	// Sin historial configurado, al menos no se acepta la misma contraseña
+	if newPassword == currentPassword {
+		return nil, &PasswordPolicyError{Violations: []PolicyViolation{
+			{Code: ViolationReused, Message: "must be different from the current password"},
+		}}
+	}
+	// El tenant se comprueba antes de cambiar nada
+	tenantID, err := s.loginTenant(ctx, user, tenant)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.UpdatePassword(ctx, user.ID.String(), newPassword); err != nil {
+		return nil, err
+	}
+	n, err := s.revokeUserSessions(ctx, user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:      audit.EventPasswordChanged,
+		TenantID:  user.TenantID.String(),
+		UserID:    user.ID.String(),
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]any{"revoked_sessions": n},
+	})
+
+	res, err := s.CompleteLogin(ctx, user, tenantID, client)
+	if err != nil {
+		return nil, err
+	}
+	s.LoginSucceeded(ctx, email)
+	return res, nil
+}
+
+// ResetPassword cambia la contraseña tras una recuperación por email y cierra
+// todas las sesiones del usuario: quien tuviera la contraseña anterior pierde el acceso.
+func (s *AuthService) ResetPassword(ctx context.Context, userID, newPassword string, client ClientInfo) (int64, error) {
+	if err := s.UpdatePassword(ctx, userID, newPassword); err != nil {
+		return 0, err
+	}
+	n, err := s.revokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:      audit.EventPasswordReset,
+		UserID:    userID,
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]any{"revoked_sessions": n},
+	})
+	return n, nil
 }
 
-func (s *AuthService) Logout(ctx context.Context, refreshToken string) error {
+// ----------------------------------------------
+// LOGOUT
+// ----------------------------------------------
+
+// Logout cierra la sesión del refresh token (toda su familia de rotación). Si
+// se envía también el access token en uso, se revoca para que deje de valer
+// antes de expirar. Un refresh token desconocido no es un error.
+func (s *AuthService) Logout(ctx context.Context, refreshToken, accessToken string) error {
+	if _, err := s.revokeRefreshToken(ctx, refreshToken); err != nil {
+		return err
+	}
+
+	if accessToken != "" {
+		if _, err := s.revokeAccessToken(ctx, accessToken); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+// LogoutAll cierra todas las sesiones del usuario e invalida sus access tokens.
+func (s *AuthService) LogoutAll(ctx context.Context, userID string) (int64, error) {
+	n, err := s.revokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventLogoutAll,
+		UserID:   userID,
+		Metadata: map[string]any{"revoked_sessions": n},
+	})
+	return n, nil
+}
+
+// RevokeUserSessions (admin) elimina todas las sesiones del usuario e invalida sus access tokens.
+func (s *AuthService) RevokeUserSessions(ctx context.Context, actorID, userID string) (int64, error) {
+	n, err := s.revokeUserSessions(ctx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventSessionsRevoked,
+		UserID:   userID,
+		Metadata: map[string]any{"actor_id": actorID, "revoked_sessions": n},
+	})
+	return n, nil
+}
+
+// RevokeTenantSessions (admin) elimina las sesiones del tenant (emitidas para él
+// o de sus usuarios) e invalida los access tokens de esos usuarios.
+func (s *AuthService) RevokeTenantSessions(ctx context.Context, actorID, tenantID string) (int64, error) {
+	tid, err := uuid.Parse(tenantID)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := s.denylist.RevokeTenantTokens(ctx, tid); err != nil {
+		return 0, err
+	}
+	n, err := s.sessions.DeleteByTenant(ctx, tid)
+	if err != nil {
+		return 0, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventSessionsRevoked,
+		TenantID: tenantID,
+		Metadata: map[string]any{"actor_id": actorID, "revoked_sessions": n},
+	})
+	return n, nil
+}
+
+func (s *AuthService) revokeUserSessions(ctx context.Context, userID string) (int64, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return 0, err
+	}
+
+	// Primero los access tokens: si falla el borrado no quedan tokens vivos sin sesión
+	if err := s.denylist.RevokeUserTokens(ctx, uid); err != nil {
+		return 0, err
+	}
+	return s.sessions.DeleteByUser(ctx, uid)
+}
+
+// ----------------------------------------------
+// REVOCACIÓN
+// ----------------------------------------------
+
+// RevokeToken revoca un access token (jti a la denylist) o un refresh token
+// (elimina su sesión). hint ("access_token" / "refresh_token") solo decide qué
+// se prueba primero. Un token desconocido o ya inválido no es un error (RFC 7009 §2.2).
+func (s *AuthService) RevokeToken(ctx context.Context, token, hint string) error {
+	if hint == "refresh_token" {
+		if ok, err := s.revokeRefreshToken(ctx, token); ok || err != nil {
+			return err
+		}
+		_, err := s.revokeAccessToken(ctx, token)
+		return err
+	}
+
+	if ok, err := s.revokeAccessToken(ctx, token); ok || err != nil {
+		return err
+	}
+	_, err := s.revokeRefreshToken(ctx, token)
+	return err
+}
+
+// RevokeUserTokens invalida todos los access tokens vigentes del usuario.
+func (s *AuthService) RevokeUserTokens(ctx context.Context, userID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	return s.denylist.RevokeUserTokens(ctx, uid)
+}
+
+func (s *AuthService) revokeAccessToken(ctx context.Context, token string) (bool, error) {
+	claims, err := ValidateAccessToken(token)
+	if err != nil {
+		return false, nil
+	}
+	if claims.ExpiresAt == nil {
+		return false, nil
+	}
+	return true, s.denylist.RevokeToken(ctx, claims.ID, claims.ExpiresAt.Time)
+}
+
+func (s *AuthService) revokeRefreshToken(ctx context.Context, token string) (bool, error) {
+	if err := ValidateRefreshToken(token); err != nil {
+		return false, nil
+	}
+
+	session, err := s.sessions.GetByRefreshToken(ctx, token)
+	if err != nil || !session.IsActive() {
+		return false, nil
+	}
+
+	// Revocar un refresh token invalida toda su cadena de rotación
+	familyID, err := uuid.Parse(session.FamilyID)
+	if err != nil {
+		return false, err
+	}
+	_, err = s.sessions.DeleteFamily(ctx, familyID)
+	return true, err
+}
+
 // RefreshToken es un alias de Refresh para compatibilidad hacia atrás
 // Deprecated: Usar Refresh en su lugar
 func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (*TokenResponse, error) {
-	return s.Refresh(ctx, refreshToken)
+	return s.Refresh(ctx, refreshToken, ClientInfo{})
 }
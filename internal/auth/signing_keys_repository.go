@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+)
+
+type SigningKeysRepository struct {
+	q *gen.Queries
+}
+
+func NewSigningKeysRepository(db gen.DBTX) *SigningKeysRepository {
+	return &SigningKeysRepository{q: gen.New(db)}
+}
+
+// CreateSigningKey guarda una llave nueva. privateKey ya debe venir cifrada.
+func (r *SigningKeysRepository) CreateSigningKey(ctx context.Context, kid, algorithm, privateKey, publicKey string, activatesAt time.Time) (*gen.SigningKey, error) {
+	key, err := r.q.CreateSigningKey(ctx, gen.CreateSigningKeyParams{
+		ID:          kid,
+		Algorithm:   algorithm,
+		PrivateKey:  privateKey,
+		PublicKey:   publicKey,
+		ActivatesAt: activatesAt,
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListVerifiable devuelve las llaves que todavía pueden verificar tokens.
+func (r *SigningKeysRepository) ListVerifiable(ctx context.Context) ([]gen.SigningKey, error) {
+	return r.q.ListVerifiableSigningKeys(ctx)
+}
+
+// ListAll devuelve todas las llaves, incluidas las expiradas.
+func (r *SigningKeysRepository) ListAll(ctx context.Context) ([]gen.SigningKey, error) {
+	return r.q.ListSigningKeys(ctx)
+}
+
+// Retire deja de usar una llave para firmar en retiredAt; sigue verificando hasta expiresAt.
+func (r *SigningKeysRepository) Retire(ctx context.Context, kid string, retiredAt, expiresAt time.Time) error {
+	return r.q.RetireSigningKey(ctx, gen.RetireSigningKeyParams{
+		ID:        kid,
+		RetiredAt: sql.NullTime{Time: retiredAt, Valid: true},
+		ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+	})
+}
+
+// RetireBefore retira todas las llaves activadas antes de activatesAt.
+func (r *SigningKeysRepository) RetireBefore(ctx context.Context, activatesAt, retiredAt, expiresAt time.Time) error {
+	return r.q.RetireSigningKeysBefore(ctx, gen.RetireSigningKeysBeforeParams{
+		ActivatesAt: activatesAt,
+		RetiredAt:   sql.NullTime{Time: retiredAt, Valid: true},
+		ExpiresAt:   sql.NullTime{Time: expiresAt, Valid: true},
+	})
+}
@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/fzalvarez/odin-iam/internal/audit"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrTenantAccessDenied: el tenant pedido no existe o el usuario no pertenece
+	// a él. No se distingue para no revelar qué tenants existen.
+	ErrTenantAccessDenied = errors.New("user does not belong to tenant")
+	// ErrTenantInactive: el usuario pertenece al tenant pero está suspendido o cerrado.
+	ErrTenantInactive = errors.New("tenant is not active")
+)
+
+// TenantDirectory lo implementa tenants.Service. Se define aquí como el resto
+// de hooks para que auth no dependa de los paquetes de dominio.
+type TenantDirectory interface {
+	// ResolveTenant busca un tenant por su ID o su key. Devuelve sql.ErrNoRows si no existe.
+	ResolveTenant(ctx context.Context, ref string) (id uuid.UUID, active bool, err error)
+	// IsMember indica si el usuario es del tenant o tiene un rol en él.
+	IsMember(ctx context.Context, userID, tenantID uuid.UUID) (bool, error)
+}
+
+// UseTenantDirectory instala la búsqueda de tenants. Sin ella, los logins solo
+// pueden pedir el tenant del propio usuario.
+func (s *AuthService) UseTenantDirectory(d TenantDirectory) {
+	s.tenants = d
+}
+
+// loginTenant devuelve el tenant del token: el indicado en ref (key o ID) si el
+// usuario pertenece a él, o el del usuario si ref está vacío. En ambos casos
+// el tenant debe estar activo.
+func (s *AuthService) loginTenant(ctx context.Context, user *dbgen.User, ref string) (uuid.UUID, error) {
+	if ref == "" {
+		ref = user.TenantID.String()
+	}
+	if s.tenants == nil {
+		if ref == user.TenantID.String() {
+			return user.TenantID, nil
+		}
+		return uuid.Nil, ErrTenantAccessDenied
+	}
+
+	tenantID, active, err := s.tenants.ResolveTenant(ctx, ref)
+	if errors.Is(err, sql.ErrNoRows) {
+		return uuid.Nil, ErrTenantAccessDenied
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if tenantID != user.TenantID {
+		member, err := s.tenants.IsMember(ctx, user.ID, tenantID)
+		if err != nil {
+			return uuid.Nil, err
+		}
+		if !member {
+			return uuid.Nil, ErrTenantAccessDenied
+		}
+	}
+	// Solo después de comprobar la pertenencia, para no revelar el estado de otros tenants
+	if !active {
+		return uuid.Nil, ErrTenantInactive
+	}
+	return tenantID, nil
+}
+
+// checkTokenTenant es loginTenant con el tenant ya resuelto, para los flujos
+// que emiten tokens sin pasar por Login.
+func (s *AuthService) checkTokenTenant(ctx context.Context, userID, tenantID uuid.UUID) error {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	_, err = s.loginTenant(ctx, user, tenantID.String())
+	return err
+}
+
+// SwitchTenant pasa la sesión del usuario autenticado a otro tenant al que
+// pertenece. refreshToken debe ser el de la sesión actual: se rota dentro de
+// su familia, así que el token anterior deja de servir y el dispositivo sigue
+// siendo una sola sesión.
+//
+// Se aplican los requisitos del tenant destino como en un login: email
+// verificado y MFA. Un usuario con segundo factor ya lo pasó al abrir la
+// sesión; si no lo tiene y el destino lo exige, devuelve un *MFAChallenge para
+// enrolarse (que abre una sesión nueva) y la actual sigue intacta.
+func (s *AuthService) SwitchTenant(ctx context.Context, userID, currentTenantID, refreshToken, tenantRef string, client ClientInfo) (*LoginResult, error) {
+	if err := ValidateRefreshToken(refreshToken); err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// La sesión debe ser la del access token. Se comprueba todo antes de
+	// consumirla: un cambio rechazado no cierra la sesión actual
+	session, err := s.sessions.GetByRefreshToken(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+	// Las sesiones de clientes OAuth se rotan en /oauth/token, no aquí
+	if session.UserID != userID || session.TenantID != currentTenantID || session.ClientID != "" || session.IsExpired() {
+		return nil, ErrInvalidRefreshToken
+	}
+	if session.ConsumedAt != nil {
+		return nil, s.detectReuse(ctx, refreshToken)
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	user, err := s.users.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, ErrTenantAccessDenied
+	}
+
+	tenantID, err := s.loginTenant(ctx, user, tenantRef)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.CheckEmailVerified(ctx, user, tenantID); err != nil {
+		return nil, err
+	}
+	if s.mfa != nil {
+		enrolled, required, err := s.mfa.Status(ctx, user, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if required && !enrolled {
+			challenge, err := s.mfa.Challenge(ctx, user, tenantID, false)
+			if err != nil {
+				return nil, err
+			}
+			return nil, challenge
+		}
+	}
+
+	// Consumo atómico: si otra petición ya rotó el token, es reutilización
+	session, err = s.sessions.ConsumeSession(ctx, refreshToken)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, s.detectReuse(ctx, refreshToken)
+		}
+		return nil, err
+	}
+
+	refresh, err := s.rotateSession(ctx, session, tenantID, client)
+	if err != nil {
+		return nil, err
+	}
+	access, err := GenerateAccessToken(user.ID.String(), tenantID.String(), AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:      audit.EventTenantSwitched,
+		TenantID:  tenantID.String(),
+		UserID:    user.ID.String(),
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]any{"from_tenant_id": currentTenantID, "family_id": session.FamilyID},
+	})
+
+	return &LoginResult{
+		UserID:       user.ID.String(),
+		AccessToken:  access,
+		RefreshToken: refresh,
+		TenantID:     tenantID.String(),
+	}, nil
+}
@@ -0,0 +1,54 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log"
+
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+// ErrEmailNotVerified: el tenant del usuario o el del login exige email
+// verificado para iniciar sesión.
+var ErrEmailNotVerified = errors.New("email not verified")
+
+// EmailVerifier lo implementa verification.Service. Se define aquí por el mismo
+// motivo que SecondFactor: el paquete verification depende de auth.
+type EmailVerifier interface {
+	// CheckLogin devuelve ErrEmailNotVerified si el tenant del usuario o el
+	// tenant del token (tenantID) exige email verificado y el del usuario aún no lo está.
+	CheckLogin(ctx context.Context, user *dbgen.User, tenantID uuid.UUID) error
+	// SendVerification envía un link de verificación al email del usuario.
+	SendVerification(ctx context.Context, user *dbgen.User) error
+	// MarkVerified registra el email del usuario como verificado.
+	MarkVerified(ctx context.Context, user *dbgen.User) error
+}
+
+// UseEmailVerifier instala la verificación de email. Sin ella, ningún login
+// exige email verificado.
+func (s *AuthService) UseEmailVerifier(v EmailVerifier) {
+	s.verifier = v
+}
+
+// CheckEmailVerified aplica el requisito de email verificado para emitir tokens
+// de tenantID. La usan CompleteLogin, SwitchTenant y los flujos que emiten
+// tokens por otra vía (OAuth, passkeys).
+func (s *AuthService) CheckEmailVerified(ctx context.Context, user *dbgen.User, tenantID uuid.UUID) error {
+	if s.verifier == nil {
+		return nil
+	}
+	return s.verifier.CheckLogin(ctx, user, tenantID)
+}
+
+// ConfirmEmail marca el email como verificado en los flujos que ya prueban que
+// el usuario lo controla (magic link, OTP por email, recuperación de contraseña).
+// Un fallo solo se registra: no debe impedir el flujo que lo llama.
+func (s *AuthService) ConfirmEmail(ctx context.Context, user *dbgen.User) {
+	if s.verifier == nil {
+		return
+	}
+	if err := s.verifier.MarkVerified(ctx, user); err != nil {
+		log.Printf("⚠️  Error marcando email verificado: %v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"os"
 
 	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/passwordpolicy"
 	"github.com/fzalvarez/odin-iam/internal/users"
 	"github.com/google/uuid"
 )
@@ -64,7 +65,7 @@ func InitializeSystem(ctx context.Context, db *sql.DB) error {
 	}
 
 	// Hashear contraseña
-	hashedPassword, err := auth.HashPassword(adminPassword)
+	hashedPassword, err := auth.HashPassword(ctx, adminPassword)
 	if err != nil {
 		return err
 	}
@@ -74,6 +75,10 @@ func InitializeSystem(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return err
 	}
+	// Historial: cambiarla después no puede volver a la contraseña inicial
+	if err := passwordpolicy.NewRepository(db).Add(ctx, user.ID, hashedPassword); err != nil {
+		return err
+	}
 
 	// Asignar rol Super Admin
 	roleID, _ := uuid.Parse(SuperAdminRoleID)
@@ -0,0 +1,136 @@
+package breached
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// BuildOptions controla qué se guarda del corpus.
+type BuildOptions struct {
+	PrefixLen int   // bytes de SHA-1 por contraseña (4-20; por defecto DefaultPrefix)
+	MinCount  int64 // descarta las contraseñas vistas menos veces (0 = todas)
+}
+
+// BuildStats resume una conversión.
+type BuildStats struct {
+	Lines   int64 // líneas leídas
+	Written uint64
+	Skipped int64 // por MinCount o por prefijo repetido
+}
+
+// Build convierte el corpus de HIBP ordenado por hash ("SHA1:COUNT" por línea,
+// como el que genera PwnedPasswordsDownloader con -s false) en un archivo
+// para Checker. Se escribe en path+".tmp" y se renombra al terminar, así que
+// un servicio que lo esté usando nunca ve un archivo a medias.
+func Build(in io.Reader, path string, opts BuildOptions) (BuildStats, error) {
+	var stats BuildStats
+	if opts.PrefixLen == 0 {
+		opts.PrefixLen = DefaultPrefix
+	}
+	if opts.PrefixLen < minPrefixLen || opts.PrefixLen > maxPrefixLen {
+		return stats, fmt.Errorf("prefix length must be between %d and %d", minPrefixLen, maxPrefixLen)
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return stats, err
+	}
+	defer os.Remove(tmp) // no-op tras el rename
+	defer f.Close()
+
+	if _, err := f.Seek(headerSize, io.SeekStart); err != nil {
+		return stats, err
+	}
+	w := bufio.NewWriterSize(f, 1<<20)
+
+	index := make([]uint64, indexSize)
+	nextBucket := 0
+	var prev []byte
+
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		stats.Lines++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		hexHash, countStr, hasCount := strings.Cut(line, ":")
+		if len(hexHash) != 2*maxPrefixLen {
+			return stats, fmt.Errorf("line %d: expected a 40-character SHA-1", stats.Lines)
+		}
+		sum, err := hex.DecodeString(hexHash)
+		if err != nil {
+			return stats, fmt.Errorf("line %d: %w", stats.Lines, err)
+		}
+		if hasCount && opts.MinCount > 0 {
+			count, err := strconv.ParseInt(countStr, 10, 64)
+			if err != nil {
+				return stats, fmt.Errorf("line %d: invalid count: %w", stats.Lines, err)
+			}
+			if count < opts.MinCount {
+				stats.Skipped++
+				continue
+			}
+		}
+
+		prefix := sum[:opts.PrefixLen]
+		if prev != nil {
+			switch cmp := bytes.Compare(prefix, prev); {
+			case cmp < 0:
+				return stats, fmt.Errorf("line %d: input is not sorted by hash", stats.Lines)
+			case cmp == 0:
+				stats.Skipped++
+				continue
+			}
+		}
+
+		// Los buckets sin hashes apuntan al siguiente registro
+		bucket := int(binary.BigEndian.Uint16(prefix))
+		for ; nextBucket <= bucket; nextBucket++ {
+			index[nextBucket] = stats.Written
+		}
+
+		if _, err := w.Write(prefix); err != nil {
+			return stats, err
+		}
+		stats.Written++
+		prev = prefix
+	}
+	if err := scanner.Err(); err != nil {
+		return stats, err
+	}
+	for ; nextBucket < indexSize; nextBucket++ {
+		index[nextBucket] = stats.Written
+	}
+	if err := w.Flush(); err != nil {
+		return stats, err
+	}
+
+	header := make([]byte, headerSize)
+	copy(header, magic)
+	binary.BigEndian.PutUint32(header[8:12], uint32(opts.PrefixLen))
+	binary.BigEndian.PutUint64(header[16:24], stats.Written)
+	for i, v := range index {
+		binary.BigEndian.PutUint64(header[24+i*8:], v)
+	}
+	if _, err := f.WriteAt(header, 0); err != nil {
+		return stats, err
+	}
+
+	if err := f.Sync(); err != nil {
+		return stats, err
+	}
+	if err := f.Close(); err != nil {
+		return stats, err
+	}
+	return stats, os.Rename(tmp, path)
+}
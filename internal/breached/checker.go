@@ -0,0 +1,152 @@
+// Package breached comprueba contraseñas contra un corpus de filtraciones
+// (Pwned Passwords de HIBP) sin llamadas de red. El corpus se convierte con
+// `odin-breach build` en un archivo compacto con los primeros bytes del SHA-1
+// de cada contraseña, ordenados, que se consulta con búsqueda binaria en disco.
+package breached
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// Formato del archivo:
+//
+//	magic      [8]byte            "ODINPWD1"
+//	prefixLen  uint32             bytes de SHA-1 guardados por contraseña
+//	reserved   uint32
+//	count      uint64             número de registros
+//	index      [65537]uint64      index[b] = primer registro cuyo SHA-1 empieza por b (2 bytes)
+//	records    [count][prefixLen]byte, ordenados
+//
+// Todos los enteros en big endian.
+const (
+	magic         = "ODINPWD1"
+	indexSize     = 1<<16 + 1
+	headerSize    = 8 + 4 + 4 + 8 + indexSize*8
+	minPrefixLen  = 4
+	maxPrefixLen  = sha1.Size
+	DefaultPrefix = 8 // con 1.000 millones de hashes, ~5e-11 falsos positivos
+)
+
+var ErrInvalidFile = errors.New("invalid breached passwords file")
+
+// Checker consulta un archivo generado por Build. Es seguro para uso concurrente.
+type Checker struct {
+	f         *os.File
+	prefixLen int
+	count     uint64
+	index     []uint64
+}
+
+// Open abre y valida un archivo generado por Build. El índice se carga en
+// memoria (512 KB); los registros se leen de disco en cada consulta.
+func Open(path string) (*Checker, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := load(f)
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+func load(f *os.File) (*Checker, error) {
+	header := make([]byte, headerSize)
+	if _, err := io.ReadFull(f, header); err != nil {
+		return nil, ErrInvalidFile
+	}
+	if string(header[:8]) != magic {
+		return nil, ErrInvalidFile
+	}
+
+	c := &Checker{
+		f:         f,
+		prefixLen: int(binary.BigEndian.Uint32(header[8:12])),
+		count:     binary.BigEndian.Uint64(header[16:24]),
+		index:     make([]uint64, indexSize),
+	}
+	if c.prefixLen < minPrefixLen || c.prefixLen > maxPrefixLen {
+		return nil, ErrInvalidFile
+	}
+	if c.count > uint64(math.MaxInt64-headerSize)/uint64(c.prefixLen) {
+		return nil, ErrInvalidFile
+	}
+
+	// containsHash confía en el índice para acotar la búsqueda: debe empezar en
+	// 0, no bajar nunca y terminar en count
+	for i := range c.index {
+		c.index[i] = binary.BigEndian.Uint64(header[24+i*8:])
+		if i > 0 && c.index[i] < c.index[i-1] {
+			return nil, ErrInvalidFile
+		}
+	}
+	if c.index[0] != 0 || c.index[indexSize-1] != c.count {
+		return nil, ErrInvalidFile
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() != headerSize+int64(c.count)*int64(c.prefixLen) {
+		return nil, ErrInvalidFile
+	}
+	return c, nil
+}
+
+// OpenFromEnv abre BREACHED_PASSWORDS_FILE. Devuelve nil si no está configurado.
+func OpenFromEnv() (*Checker, error) {
+	path := os.Getenv("BREACHED_PASSWORDS_FILE")
+	if path == "" {
+		return nil, nil
+	}
+	return Open(path)
+}
+
+// Count devuelve el número de hashes del archivo.
+func (c *Checker) Count() uint64 {
+	return c.count
+}
+
+// Contains indica si la contraseña aparece en el corpus. Con prefijos de
+// SHA-1 puede haber falsos positivos, nunca falsos negativos.
+func (c *Checker) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	return c.containsHash(sum[:c.prefixLen])
+}
+
+func (c *Checker) containsHash(prefix []byte) (bool, error) {
+	bucket := binary.BigEndian.Uint16(prefix)
+	lo, hi := c.index[bucket], c.index[int(bucket)+1]
+
+	record := make([]byte, c.prefixLen)
+	for lo < hi {
+		mid := lo + (hi-lo)/2
+		if _, err := c.f.ReadAt(record, headerSize+int64(mid)*int64(c.prefixLen)); err != nil {
+			return false, err
+		}
+		switch cmp := bytes.Compare(record, prefix); {
+		case cmp == 0:
+			return true, nil
+		case cmp < 0:
+			lo = mid + 1
+		default:
+			hi = mid
+		}
+	}
+	return false, nil
+}
+
+func (c *Checker) Close() error {
+	return c.f.Close()
+}
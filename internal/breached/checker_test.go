@@ -0,0 +1,211 @@
+package breached
+
+import (
+	"crypto/sha1"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// corpus arma un corpus de HIBP ("SHA1:COUNT" ordenado por hash).
+func corpus(counts map[string]int) string {
+	lines := make([]string, 0, len(counts))
+	for password, count := range counts {
+		sum := sha1.Sum([]byte(password))
+		lines = append(lines, fmt.Sprintf("%s:%d", strings.ToUpper(hex.EncodeToString(sum[:])), count))
+	}
+	slices.Sort(lines)
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+func build(t *testing.T, in string, opts BuildOptions) (string, BuildStats) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "pwned.bin")
+	stats, err := Build(strings.NewReader(in), path, opts)
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	return path, stats
+}
+
+func TestBuildOpenContains(t *testing.T) {
+	counts := map[string]int{
+		"password": 9659365,
+		"123456":   37359195,
+		"qwerty":   10556095,
+		"letmein":  1423121,
+		"hunter2":  43357,
+		"rare-one": 1,
+		"rare-two": 2,
+	}
+	// Hashes repartidos por muchos buckets del índice
+	for i := range 2000 {
+		counts[fmt.Sprintf("filler-%d", i)] = 10
+	}
+	notBreached := []string{"", "correct horse battery staple", "Password", "filler-2000"}
+
+	tests := []struct {
+		name      string
+		opts      BuildOptions
+		excluded  []string // descartadas por MinCount
+		wantCount uint64
+	}{
+		{"default prefix", BuildOptions{}, nil, uint64(len(counts))},
+		{"min prefix", BuildOptions{PrefixLen: minPrefixLen}, nil, uint64(len(counts))},
+		{"full sha1", BuildOptions{PrefixLen: maxPrefixLen}, nil, uint64(len(counts))},
+		{"min count", BuildOptions{MinCount: 3}, []string{"rare-one", "rare-two"}, uint64(len(counts) - 2)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, stats := build(t, corpus(counts), tt.opts)
+			if stats.Written != tt.wantCount || stats.Skipped != int64(len(tt.excluded)) {
+				t.Errorf("stats = %+v, want %d written and %d skipped", stats, tt.wantCount, len(tt.excluded))
+			}
+
+			c, err := Open(path)
+			if err != nil {
+				t.Fatalf("Open: %v", err)
+			}
+			defer c.Close()
+			if c.Count() != tt.wantCount {
+				t.Errorf("Count = %d, want %d", c.Count(), tt.wantCount)
+			}
+
+			for password := range counts {
+				want := !slices.Contains(tt.excluded, password)
+				if got, err := c.Contains(password); err != nil || got != want {
+					t.Errorf("Contains(%q) = %v, %v; want %v", password, got, err, want)
+				}
+			}
+			for _, password := range notBreached {
+				if got, err := c.Contains(password); err != nil || got {
+					t.Errorf("Contains(%q) = %v, %v; want false", password, got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestBuildEmptyCorpus(t *testing.T) {
+	path, _ := build(t, "", BuildOptions{})
+	c, err := Open(path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer c.Close()
+	if got, err := c.Contains("password"); err != nil || got {
+		t.Errorf("Contains = %v, %v; want false", got, err)
+	}
+}
+
+func TestBuildInvalidInput(t *testing.T) {
+	sorted := corpus(map[string]int{"a": 1, "b": 1})
+	lines := strings.Fields(sorted)
+
+	tests := []struct {
+		name string
+		in   string
+		opts BuildOptions
+	}{
+		{"unsorted", lines[1] + "\n" + lines[0] + "\n", BuildOptions{}},
+		{"short hash", "5BAA61E4C9B93F3F0682250B6CF8331B7EE68F:1\n", BuildOptions{}},
+		{"not hex", strings.Repeat("Z", 40) + ":1\n", BuildOptions{}},
+		{"invalid count", lines[0][:40] + ":many\n", BuildOptions{MinCount: 2}},
+		{"prefix too short", sorted, BuildOptions{PrefixLen: minPrefixLen - 1}},
+		{"prefix too long", sorted, BuildOptions{PrefixLen: maxPrefixLen + 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pwned.bin")
+			if _, err := Build(strings.NewReader(tt.in), path, tt.opts); err == nil {
+				t.Fatal("Build succeeded, want error")
+			}
+			if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("failed Build left %s behind", path)
+			}
+			if _, err := os.Stat(path + ".tmp"); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("failed Build left %s.tmp behind", path)
+			}
+		})
+	}
+}
+
+func TestOpenInvalidFile(t *testing.T) {
+	counts := map[string]int{}
+	for i := range 100 {
+		counts[fmt.Sprintf("password-%d", i)] = 1
+	}
+	path, stats := build(t, corpus(counts), BuildOptions{})
+	valid, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	indexAt := func(i int) int { return 24 + i*8 }
+
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+	}{
+		{"bad magic", func(b []byte) []byte { b[0] = 'X'; return b }},
+		{"truncated header", func(b []byte) []byte { return b[:headerSize-1] }},
+		{"truncated records", func(b []byte) []byte { return b[:len(b)-1] }},
+		{"extra records", func(b []byte) []byte { return append(b, make([]byte, DefaultPrefix)...) }},
+		{"prefix too short", func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[8:], minPrefixLen-1)
+			return b
+		}},
+		{"prefix too long", func(b []byte) []byte {
+			binary.BigEndian.PutUint32(b[8:], maxPrefixLen+1)
+			return b
+		}},
+		{"count does not match index", func(b []byte) []byte {
+			binary.BigEndian.PutUint64(b[16:], stats.Written-1)
+			return b
+		}},
+		{"count overflows file size", func(b []byte) []byte {
+			binary.BigEndian.PutUint64(b[16:], 1<<62)
+			binary.BigEndian.PutUint64(b[indexAt(indexSize-1):], 1<<62)
+			return b
+		}},
+		{"index does not start at zero", func(b []byte) []byte {
+			binary.BigEndian.PutUint64(b[indexAt(0):], 1)
+			return b
+		}},
+		{"index decreases", func(b []byte) []byte {
+			// Sigue dentro de count: solo lo detecta la comprobación de orden
+			binary.BigEndian.PutUint64(b[indexAt(indexSize-2):], 0)
+			return b
+		}},
+		{"index beyond count", func(b []byte) []byte {
+			binary.BigEndian.PutUint64(b[indexAt(indexSize/2):], stats.Written+1)
+			return b
+		}},
+		{"index beyond file", func(b []byte) []byte {
+			binary.BigEndian.PutUint64(b[indexAt(indexSize-2):], 1<<40)
+			return b
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			corrupt := filepath.Join(t.TempDir(), "pwned.bin")
+			if err := os.WriteFile(corrupt, tt.modify(slices.Clone(valid)), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			c, err := Open(corrupt)
+			if err == nil {
+				c.Close()
+				t.Fatal("Open succeeded, want ErrInvalidFile")
+			}
+			if !errors.Is(err, ErrInvalidFile) {
+				t.Errorf("Open = %v, want ErrInvalidFile", err)
+			}
+		})
+	}
+}
@@ -14,9 +14,9 @@ import (
 )
 
 const CreateAPIKey = `-- name: CreateAPIKey :one
-INSERT INTO api_keys (id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at
+INSERT INTO api_keys (id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, scopes)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at, scopes
 `
 
 type CreateAPIKeyParams struct {
@@ -28,6 +28,7 @@ type CreateAPIKeyParams struct {
 	IsActive  bool
 	CreatedAt time.Time
 	ExpiresAt sql.NullTime
+	Scopes    string
 }
 
 func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
@@ -40,6 +41,7 @@ func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (Api
 		arg.IsActive,
 		arg.CreatedAt,
 		arg.ExpiresAt,
+		arg.Scopes,
 	)
 	var i ApiKey
 	err := row.Scan(
@@ -52,6 +54,7 @@ func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (Api
 		&i.CreatedAt,
 		&i.ExpiresAt,
 		&i.LastUsedAt,
+		&i.Scopes,
 	)
 	return i, err
 }
@@ -67,7 +70,7 @@ func (q *Queries) DeleteAPIKey(ctx context.Context, id uuid.UUID) error {
 }
 
 const GetAPIKeyByHash = `-- name: GetAPIKeyByHash :one
-SELECT id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at FROM api_keys
+SELECT id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at, scopes FROM api_keys
 WHERE key_hash = $1 LIMIT 1
 `
 
@@ -84,12 +87,13 @@ func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey,
 		&i.CreatedAt,
 		&i.ExpiresAt,
 		&i.LastUsedAt,
+		&i.Scopes,
 	)
 	return i, err
 }
 
 const GetAPIKeyByID = `-- name: GetAPIKeyByID :one
-SELECT id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at FROM api_keys
+SELECT id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at, scopes FROM api_keys
 WHERE id = $1 LIMIT 1
 `
 
@@ -106,12 +110,13 @@ func (q *Queries) GetAPIKeyByID(ctx context.Context, id uuid.UUID) (ApiKey, erro
 		&i.CreatedAt,
 		&i.ExpiresAt,
 		&i.LastUsedAt,
+		&i.Scopes,
 	)
 	return i, err
 }
 
 const ListAPIKeysByTenant = `-- name: ListAPIKeysByTenant :many
-SELECT id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at FROM api_keys
+SELECT id, name, tenant_id, key_hash, prefix, is_active, created_at, expires_at, last_used_at, scopes FROM api_keys
 WHERE tenant_id = $1
 ORDER BY created_at DESC
 `
@@ -135,6 +140,7 @@ func (q *Queries) ListAPIKeysByTenant(ctx context.Context, tenantID uuid.UUID) (
 			&i.CreatedAt,
 			&i.ExpiresAt,
 			&i.LastUsedAt,
+			&i.Scopes,
 		); err != nil {
 			return nil, err
 		}
@@ -40,6 +40,26 @@ func (q *Queries) GetCredentialByUserID(ctx context.Context, userID uuid.UUID) (
 	return i, err
 }
 
+const RehashCredentialPassword = `-- name: RehashCredentialPassword :execrows
+UPDATE credentials
+SET password_hash = $1
+WHERE user_id = $2 AND password_hash = $3
+`
+
+type RehashCredentialPasswordParams struct {
+	NewHash string
+	UserID  uuid.UUID
+	OldHash string
+}
+
+func (q *Queries) RehashCredentialPassword(ctx context.Context, arg RehashCredentialPasswordParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, RehashCredentialPassword, arg.NewHash, arg.UserID, arg.OldHash)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const UpdateCredentialPassword = `-- name: UpdateCredentialPassword :exec
 UPDATE credentials
 SET password_hash = $2, updated_at = NOW()
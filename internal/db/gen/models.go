@@ -22,6 +22,18 @@ type ApiKey struct {
 	CreatedAt  time.Time
 	ExpiresAt  sql.NullTime
 	LastUsedAt sql.NullTime
+	Scopes     string
+}
+
+type AuditEvent struct {
+	ID        uuid.UUID
+	EventType string
+	TenantID  uuid.NullUUID
+	UserID    uuid.NullUUID
+	ClientIp  sql.NullString
+	UserAgent sql.NullString
+	Metadata  json.RawMessage
+	CreatedAt time.Time
 }
 
 type Credential struct {
@@ -30,6 +42,130 @@ type Credential struct {
 	UpdatedAt    time.Time
 }
 
+type EmailVerification struct {
+	Email      string
+	UserID     uuid.UUID
+	VerifiedAt time.Time
+	CreatedAt  time.Time
+}
+
+type EmailVerificationToken struct {
+	ID         uuid.UUID
+	TokenHash  string
+	Email      string
+	UserID     uuid.NullUUID
+	ExpiresAt  time.Time
+	ConsumedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+type LoginThrottle struct {
+	Kind            string
+	Subject         string
+	Failures        int32
+	WindowStartedAt time.Time
+	LastFailureAt   time.Time
+	LockedUntil     sql.NullTime
+}
+
+type MagicLink struct {
+	ID         uuid.UUID
+	TokenHash  string
+	Email      string
+	UserID     uuid.NullUUID
+	ClientIp   sql.NullString
+	ExpiresAt  time.Time
+	ConsumedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+type MfaChallenge struct {
+	ID                 uuid.UUID
+	TokenHash          string
+	UserID             uuid.UUID
+	TenantID           uuid.NullUUID
+	Attempts           int32
+	ExpiresAt          time.Time
+	ConsumedAt         sql.NullTime
+	CreatedAt          time.Time
+	EnrollmentRequired bool
+}
+
+type MfaRecoveryCode struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	CodeHash  string
+	UsedAt    sql.NullTime
+	CreatedAt time.Time
+}
+
+type MfaTotp struct {
+	UserID       uuid.UUID
+	Secret       string
+	ConfirmedAt  sql.NullTime
+	LastUsedStep sql.NullInt64
+	CreatedAt    time.Time
+}
+
+type OauthAuthorizationCode struct {
+	CodeHash            string
+	ClientID            uuid.UUID
+	UserID              uuid.UUID
+	TenantID            uuid.UUID
+	RedirectUri         string
+	Scope               string
+	Nonce               sql.NullString
+	CodeChallenge       string
+	CodeChallengeMethod string
+	AuthTime            time.Time
+	ExpiresAt           time.Time
+	ConsumedAt          sql.NullTime
+	CreatedAt           time.Time
+}
+
+type OauthClient struct {
+	ID           uuid.UUID
+	SecretHash   sql.NullString
+	Name         string
+	TenantID     uuid.UUID
+	RedirectUris json.RawMessage
+	GrantTypes   string
+	Scopes       string
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type OtpCode struct {
+	ID         uuid.UUID
+	Email      string
+	UserID     uuid.NullUUID
+	CodeHash   string
+	Channel    string
+	ClientIp   sql.NullString
+	Attempts   int32
+	ExpiresAt  time.Time
+	ConsumedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
+type PasswordHistory struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	PasswordHash string
+	CreatedAt    time.Time
+}
+
+type PasswordReset struct {
+	ID         uuid.UUID
+	TokenHash  string
+	Email      string
+	UserID     uuid.NullUUID
+	ClientIp   sql.NullString
+	ExpiresAt  time.Time
+	ConsumedAt sql.NullTime
+	CreatedAt  time.Time
+}
+
 type Permission struct {
 	ID          uuid.UUID
 	Code        string
@@ -37,6 +173,17 @@ type Permission struct {
 	CreatedAt   time.Time
 }
 
+type RateLimitBucket struct {
+	Key string
+	Tat int64
+}
+
+type RevokedToken struct {
+	Jti       string
+	ExpiresAt time.Time
+	RevokedAt time.Time
+}
+
 type Role struct {
 	ID          uuid.UUID
 	Name        string
@@ -52,6 +199,12 @@ type RolePermission struct {
 	AssignedAt   time.Time
 }
 
+type ScheduledJob struct {
+	Name      string
+	LastRunAt time.Time
+	LastError sql.NullString
+}
+
 type Session struct {
 	ID           uuid.UUID
 	UserID       uuid.UUID
@@ -61,6 +214,20 @@ type Session struct {
 	ClientIp     sql.NullString
 	ExpiresAt    time.Time
 	CreatedAt    time.Time
+	FamilyID     uuid.UUID
+	ConsumedAt   sql.NullTime
+	ClientID     uuid.NullUUID
+}
+
+type SigningKey struct {
+	ID          string
+	Algorithm   string
+	PrivateKey  string
+	PublicKey   string
+	ActivatesAt time.Time
+	RetiredAt   sql.NullTime
+	ExpiresAt   sql.NullTime
+	CreatedAt   time.Time
 }
 
 type Tenant struct {
@@ -80,13 +247,14 @@ type Tenant struct {
 }
 
 type User struct {
-	ID          uuid.UUID
-	TenantID    uuid.UUID
-	DisplayName string
-	Email       string
-	IsActive    bool
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID               uuid.UUID
+	TenantID         uuid.UUID
+	DisplayName      string
+	Email            string
+	IsActive         bool
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	TokensValidAfter sql.NullTime
 }
 
 type UserRole struct {
@@ -94,3 +262,26 @@ type UserRole struct {
 	RoleID     uuid.UUID
 	AssignedAt time.Time
 }
+
+type WebauthnChallenge struct {
+	ID        uuid.UUID
+	Challenge string
+	Purpose   string
+	UserID    uuid.NullUUID
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+type WebauthnCredential struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	CredentialID string
+	PublicKey    []byte
+	Algorithm    int32
+	SignCount    int64
+	Transports   string
+	Aaguid       string
+	Name         string
+	CreatedAt    time.Time
+	LastUsedAt   sql.NullTime
+}
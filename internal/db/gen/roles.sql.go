@@ -174,6 +174,44 @@ func (q *Queries) GetPermissionsByUser(ctx context.Context, userID uuid.UUID) ([
 	return items, nil
 }
 
+const GetPermissionsByUserInTenant = `-- name: GetPermissionsByUserInTenant :many
+SELECT DISTINCT p.code
+FROM permissions p
+JOIN role_permissions rp ON p.id = rp.permission_id
+JOIN user_roles ur ON rp.role_id = ur.role_id
+JOIN roles r ON r.id = ur.role_id
+WHERE ur.user_id = $1
+  AND COALESCE(r.tenant_id, '00000000-0000-0000-0000-000000000000') = $2
+`
+
+type GetPermissionsByUserInTenantParams struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+}
+
+func (q *Queries) GetPermissionsByUserInTenant(ctx context.Context, arg GetPermissionsByUserInTenantParams) ([]string, error) {
+	rows, err := q.db.QueryContext(ctx, GetPermissionsByUserInTenant, arg.UserID, arg.TenantID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []string{}
+	for rows.Next() {
+		var code string
+		if err := rows.Scan(&code); err != nil {
+			return nil, err
+		}
+		items = append(items, code)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetRoleByID = `-- name: GetRoleByID :one
 SELECT id, name, description, tenant_id, created_at, updated_at FROM roles
 WHERE id = $1 LIMIT 1
@@ -13,10 +13,36 @@ import (
 	"github.com/google/uuid"
 )
 
+const ConsumeSession = `-- name: ConsumeSession :one
+UPDATE sessions
+SET consumed_at = NOW()
+WHERE refresh_token = $1 AND consumed_at IS NULL
+RETURNING id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at, family_id, consumed_at, client_id
+`
+
+func (q *Queries) ConsumeSession(ctx context.Context, refreshToken string) (Session, error) {
+	row := q.db.QueryRowContext(ctx, ConsumeSession, refreshToken)
+	var i Session
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.TenantID,
+		&i.RefreshToken,
+		&i.UserAgent,
+		&i.ClientIp,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ConsumedAt,
+		&i.ClientID,
+	)
+	return i, err
+}
+
 const CreateSession = `-- name: CreateSession :one
-INSERT INTO sessions (id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
-RETURNING id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at
+INSERT INTO sessions (id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at, family_id, client_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+RETURNING id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at, family_id, consumed_at, client_id
 `
 
 type CreateSessionParams struct {
@@ -28,6 +54,8 @@ type CreateSessionParams struct {
 	ClientIp     sql.NullString
 	ExpiresAt    time.Time
 	CreatedAt    time.Time
+	FamilyID     uuid.UUID
+	ClientID     uuid.NullUUID
 }
 
 func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error) {
@@ -40,6 +68,8 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		arg.ClientIp,
 		arg.ExpiresAt,
 		arg.CreatedAt,
+		arg.FamilyID,
+		arg.ClientID,
 	)
 	var i Session
 	err := row.Scan(
@@ -51,10 +81,26 @@ func (q *Queries) CreateSession(ctx context.Context, arg CreateSessionParams) (S
 		&i.ClientIp,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ConsumedAt,
+		&i.ClientID,
 	)
 	return i, err
 }
 
+const DeleteExpiredSessions = `-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= NOW()
+`
+
+func (q *Queries) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteExpiredSessions)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const DeleteSession = `-- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = $1
@@ -65,8 +111,66 @@ func (q *Queries) DeleteSession(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const DeleteSessionFamily = `-- name: DeleteSessionFamily :execrows
+DELETE FROM sessions
+WHERE family_id = $1
+`
+
+func (q *Queries) DeleteSessionFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteSessionFamily, familyID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteSessionsByTenant = `-- name: DeleteSessionsByTenant :execrows
+DELETE FROM sessions
+WHERE tenant_id = $1
+   OR user_id IN (SELECT id FROM users WHERE users.tenant_id = $1)
+`
+
+func (q *Queries) DeleteSessionsByTenant(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteSessionsByTenant, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteSessionsByUser = `-- name: DeleteSessionsByUser :execrows
+DELETE FROM sessions
+WHERE user_id = $1
+`
+
+func (q *Queries) DeleteSessionsByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteSessionsByUser, userID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+const DeleteUserSessionFamily = `-- name: DeleteUserSessionFamily :execrows
+DELETE FROM sessions
+WHERE family_id = $1 AND user_id = $2
+`
+
+type DeleteUserSessionFamilyParams struct {
+	FamilyID uuid.UUID
+	UserID   uuid.UUID
+}
+
+func (q *Queries) DeleteUserSessionFamily(ctx context.Context, arg DeleteUserSessionFamilyParams) (int64, error) {
+	result, err := q.db.ExecContext(ctx, DeleteUserSessionFamily, arg.FamilyID, arg.UserID)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 const GetSessionByID = `-- name: GetSessionByID :one
-SELECT id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at FROM sessions
+SELECT id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at, family_id, consumed_at, client_id FROM sessions
 WHERE id = $1 LIMIT 1
 `
 
@@ -82,12 +186,15 @@ func (q *Queries) GetSessionByID(ctx context.Context, id uuid.UUID) (Session, er
 		&i.ClientIp,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ConsumedAt,
+		&i.ClientID,
 	)
 	return i, err
 }
 
 const GetSessionByRefreshToken = `-- name: GetSessionByRefreshToken :one
-SELECT id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at FROM sessions
+SELECT id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at, family_id, consumed_at, client_id FROM sessions
 WHERE refresh_token = $1 LIMIT 1
 `
 
@@ -103,6 +210,110 @@ func (q *Queries) GetSessionByRefreshToken(ctx context.Context, refreshToken str
 		&i.ClientIp,
 		&i.ExpiresAt,
 		&i.CreatedAt,
+		&i.FamilyID,
+		&i.ConsumedAt,
+		&i.ClientID,
 	)
 	return i, err
 }
+
+const ListActiveSessionsByUser = `-- name: ListActiveSessionsByUser :many
+SELECT s.id, s.family_id, s.user_agent, s.client_ip, s.created_at, s.expires_at,
+       (SELECT MIN(f.created_at) FROM sessions f WHERE f.family_id = s.family_id)::timestamptz AS signed_in_at
+FROM sessions s
+WHERE s.user_id = $1
+  AND s.consumed_at IS NULL
+  AND s.expires_at > NOW()
+ORDER BY s.created_at DESC
+`
+
+type ListActiveSessionsByUserRow struct {
+	ID         uuid.UUID
+	FamilyID   uuid.UUID
+	UserAgent  sql.NullString
+	ClientIp   sql.NullString
+	CreatedAt  time.Time
+	ExpiresAt  time.Time
+	SignedInAt time.Time
+}
+
+func (q *Queries) ListActiveSessionsByUser(ctx context.Context, userID uuid.UUID) ([]ListActiveSessionsByUserRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListActiveSessionsByUser, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListActiveSessionsByUserRow{}
+	for rows.Next() {
+		var i ListActiveSessionsByUserRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.FamilyID,
+			&i.UserAgent,
+			&i.ClientIp,
+			&i.CreatedAt,
+			&i.ExpiresAt,
+			&i.SignedInAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const ListLegacySessionTokens = `-- name: ListLegacySessionTokens :many
+SELECT id, refresh_token FROM sessions
+WHERE refresh_token NOT LIKE 'h1:%'
+LIMIT $1
+`
+
+type ListLegacySessionTokensRow struct {
+	ID           uuid.UUID
+	RefreshToken string
+}
+
+func (q *Queries) ListLegacySessionTokens(ctx context.Context, limit int32) ([]ListLegacySessionTokensRow, error) {
+	rows, err := q.db.QueryContext(ctx, ListLegacySessionTokens, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListLegacySessionTokensRow{}
+	for rows.Next() {
+		var i ListLegacySessionTokensRow
+		if err := rows.Scan(&i.ID, &i.RefreshToken); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const SetSessionRefreshToken = `-- name: SetSessionRefreshToken :exec
+UPDATE sessions
+SET refresh_token = $2
+WHERE id = $1
+`
+
+type SetSessionRefreshTokenParams struct {
+	ID           uuid.UUID
+	RefreshToken string
+}
+
+func (q *Queries) SetSessionRefreshToken(ctx context.Context, arg SetSessionRefreshTokenParams) error {
+	_, err := q.db.ExecContext(ctx, SetSessionRefreshToken, arg.ID, arg.RefreshToken)
+	return err
+}
@@ -69,6 +69,38 @@ func (q *Queries) CreateTenant(ctx context.Context, arg CreateTenantParams) (Ten
 	return i, err
 }
 
+const ExpireTenantTrials = `-- name: ExpireTenantTrials :many
+UPDATE tenants
+SET status = 'suspended', is_active = false, disabled_at = NOW(), updated_at = NOW()
+WHERE status = 'active'
+  AND trial_ends_at IS NOT NULL
+  AND trial_ends_at <= NOW()
+RETURNING id
+`
+
+func (q *Queries) ExpireTenantTrials(ctx context.Context) ([]uuid.UUID, error) {
+	rows, err := q.db.QueryContext(ctx, ExpireTenantTrials)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []uuid.UUID{}
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		items = append(items, id)
+	}
+	if err := rows.Close(); err != nil {
+		return nil, err
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const GetTenantByID = `-- name: GetTenantByID :one
 SELECT id, key, name, description, origin, subtype, status, is_active, config, trial_ends_at, disabled_at, created_at, updated_at 
 FROM tenants
@@ -313,3 +345,26 @@ func (q *Queries) UpdateTenantStatus(ctx context.Context, arg UpdateTenantStatus
 	_, err := q.db.ExecContext(ctx, UpdateTenantStatus, arg.ID, arg.IsActive)
 	return err
 }
+
+const UserBelongsToTenant = `-- name: UserBelongsToTenant :one
+SELECT EXISTS (
+    SELECT 1 FROM users u
+    WHERE u.id = $1 AND u.tenant_id = $2
+) OR EXISTS (
+    SELECT 1 FROM user_roles ur
+    JOIN roles r ON r.id = ur.role_id
+    WHERE ur.user_id = $1 AND r.tenant_id = $2
+) AS belongs
+`
+
+type UserBelongsToTenantParams struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+}
+
+func (q *Queries) UserBelongsToTenant(ctx context.Context, arg UserBelongsToTenantParams) (bool, error) {
+	row := q.db.QueryRowContext(ctx, UserBelongsToTenant, arg.UserID, arg.TenantID)
+	var belongs bool
+	err := row.Scan(&belongs)
+	return belongs, err
+}
@@ -15,7 +15,7 @@ import (
 const CreateUser = `-- name: CreateUser :one
 INSERT INTO users (id, tenant_id, display_name, email, is_active, created_at, updated_at)
 VALUES ($1, $2, $3, $4, $5, $6, $7)
-RETURNING id, tenant_id, display_name, email, is_active, created_at, updated_at
+RETURNING id, tenant_id, display_name, email, is_active, created_at, updated_at, tokens_valid_after
 `
 
 type CreateUserParams struct {
@@ -47,12 +47,13 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
 
 const GetUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, tenant_id, display_name, email, is_active, created_at, updated_at FROM users
+SELECT id, tenant_id, display_name, email, is_active, created_at, updated_at, tokens_valid_after FROM users
 WHERE email = $1 LIMIT 1
 `
 
@@ -67,12 +68,13 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
 
 const GetUserByID = `-- name: GetUserByID :one
-SELECT id, tenant_id, display_name, email, is_active, created_at, updated_at FROM users
+SELECT id, tenant_id, display_name, email, is_active, created_at, updated_at, tokens_valid_after FROM users
 WHERE id = $1 LIMIT 1
 `
 
@@ -87,12 +89,13 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.TokensValidAfter,
 	)
 	return i, err
 }
 
 const ListUsersByTenant = `-- name: ListUsersByTenant :many
-SELECT id, tenant_id, display_name, email, is_active, created_at, updated_at FROM users
+SELECT id, tenant_id, display_name, email, is_active, created_at, updated_at, tokens_valid_after FROM users
 WHERE tenant_id = $1
 ORDER BY created_at DESC
 `
@@ -114,6 +117,7 @@ func (q *Queries) ListUsersByTenant(ctx context.Context, tenantID uuid.UUID) ([]
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.TokensValidAfter,
 		); err != nil {
 			return nil, err
 		}
@@ -130,7 +134,10 @@ func (q *Queries) ListUsersByTenant(ctx context.Context, tenantID uuid.UUID) ([]
 
 const UpdateUserStatus = `-- name: UpdateUserStatus :exec
 UPDATE users
-SET is_active = $2, updated_at = NOW()
+SET is_active = $2,
+    updated_at = NOW(),
+    -- Al desactivar un usuario se invalidan sus access tokens vigentes
+    tokens_valid_after = CASE WHEN $2 THEN tokens_valid_after ELSE NOW() END
 WHERE id = $1
 `
 
@@ -0,0 +1,17 @@
+-- Migración: Llaves de firma para JWT (RS256 / ES256 / EdDSA)
+-- Descripción: Key ring persistente. Una llave firma desde activates_at hasta retired_at
+-- y sigue verificando tokens hasta expires_at (periodo de gracia).
+
+CREATE TABLE signing_keys (
+    id TEXT PRIMARY KEY,
+    algorithm TEXT NOT NULL,
+    private_key TEXT NOT NULL,
+    public_key TEXT NOT NULL,
+    activates_at TIMESTAMPTZ NOT NULL,
+    retired_at TIMESTAMPTZ,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT check_signing_key_algorithm CHECK (algorithm IN ('RS256', 'ES256', 'EdDSA'))
+);
+
+CREATE INDEX idx_signing_keys_activates_at ON signing_keys(activates_at);
@@ -0,0 +1,46 @@
+-- Migración: Proveedor OAuth2 / OpenID Connect
+-- Descripción: Clientes registrados y códigos de autorización (authorization code + PKCE)
+
+CREATE TABLE oauth_clients (
+    id UUID PRIMARY KEY,                 -- client_id público
+    secret_hash TEXT,                    -- NULL para clientes públicos (SPA, móvil)
+    name TEXT NOT NULL,
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    redirect_uris JSONB NOT NULL DEFAULT '[]',
+    grant_types TEXT NOT NULL DEFAULT 'authorization_code refresh_token',
+    scopes TEXT NOT NULL DEFAULT 'openid profile email',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE oauth_authorization_codes (
+    code_hash TEXT PRIMARY KEY,
+    client_id UUID NOT NULL REFERENCES oauth_clients(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id),
+    tenant_id UUID NOT NULL REFERENCES tenants(id),
+    redirect_uri TEXT NOT NULL,
+    scope TEXT NOT NULL,
+    nonce TEXT,
+    code_challenge TEXT NOT NULL,
+    code_challenge_method TEXT NOT NULL,
+    auth_time TIMESTAMPTZ NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_oauth_clients_tenant_id ON oauth_clients(tenant_id);
+CREATE INDEX idx_oauth_authorization_codes_expires_at ON oauth_authorization_codes(expires_at);
+
+-- Permisos para administrar clientes OAuth
+INSERT INTO permissions (id, code, description, created_at) VALUES
+('10000000-0000-0000-0000-000000000016', 'oauth_clients:create', 'Register OAuth clients', NOW()),
+('10000000-0000-0000-0000-000000000017', 'oauth_clients:list', 'List OAuth clients', NOW()),
+('10000000-0000-0000-0000-000000000018', 'oauth_clients:delete', 'Delete OAuth clients', NOW())
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, assigned_at)
+SELECT '20000000-0000-0000-0000-000000000001', id, NOW()
+FROM permissions
+WHERE code IN ('oauth_clients:create', 'oauth_clients:list', 'oauth_clients:delete')
+ON CONFLICT (role_id, permission_id) DO NOTHING;
@@ -0,0 +1,4 @@
+-- Migración: Grant client_credentials
+-- Descripción: Scopes (códigos de permiso) que una API key puede solicitar al canjearse por un access token
+
+ALTER TABLE api_keys ADD COLUMN scopes TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,14 @@
+-- Migración: Revocación de access tokens
+-- Descripción: Denylist por jti y marca por usuario "tokens válidos desde"
+
+-- Los access tokens emitidos antes de esta fecha dejan de ser válidos
+ALTER TABLE users ADD COLUMN tokens_valid_after TIMESTAMPTZ;
+
+CREATE TABLE revoked_tokens (
+    jti TEXT PRIMARY KEY,
+    expires_at TIMESTAMPTZ NOT NULL,     -- exp del token; después ya no hace falta guardarlo
+    revoked_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_revoked_tokens_expires_at ON revoked_tokens(expires_at);
+CREATE INDEX idx_users_tokens_valid_after ON users(tokens_valid_after) WHERE tokens_valid_after IS NOT NULL;
@@ -0,0 +1,27 @@
+-- Migración: Familias de rotación de refresh tokens y auditoría
+-- Descripción: Cada login abre una familia; cada refresh consume su sesión y crea
+-- otra en la misma familia. Reusar un refresh token consumido revoca la familia.
+
+ALTER TABLE sessions ADD COLUMN family_id UUID;
+ALTER TABLE sessions ADD COLUMN consumed_at TIMESTAMPTZ;
+
+-- Las sesiones existentes forman cada una su propia familia
+UPDATE sessions SET family_id = id WHERE family_id IS NULL;
+ALTER TABLE sessions ALTER COLUMN family_id SET NOT NULL;
+
+CREATE INDEX idx_sessions_family_id ON sessions(family_id);
+
+CREATE TABLE audit_events (
+    id UUID PRIMARY KEY,
+    event_type TEXT NOT NULL,            -- ej. session.refresh_reuse
+    tenant_id UUID,
+    user_id UUID,
+    client_ip TEXT,
+    user_agent TEXT,
+    metadata JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_audit_events_created_at ON audit_events(created_at);
+CREATE INDEX idx_audit_events_user_id ON audit_events(user_id);
+CREATE INDEX idx_audit_events_tenant_id ON audit_events(tenant_id);
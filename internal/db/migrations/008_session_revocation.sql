@@ -0,0 +1,13 @@
+-- Migración: Revocación de sesiones por administradores
+
+INSERT INTO permissions (id, code, description, created_at) VALUES
+('10000000-0000-0000-0000-000000000019', 'sessions:revoke', 'Revoke sessions of users and tenants', NOW())
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, assigned_at)
+SELECT '20000000-0000-0000-0000-000000000001', id, NOW()
+FROM permissions
+WHERE code = 'sessions:revoke'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
+
+CREATE INDEX idx_sessions_tenant_id ON sessions(tenant_id);
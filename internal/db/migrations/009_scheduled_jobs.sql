@@ -0,0 +1,11 @@
+-- Migración: Tareas periódicas (scheduler en proceso)
+
+-- Última ejecución de cada tarea. Junto con un advisory lock por tarea evita
+-- que varias réplicas la ejecuten en el mismo intervalo.
+CREATE TABLE scheduled_jobs (
+    name TEXT PRIMARY KEY,
+    last_run_at TIMESTAMPTZ NOT NULL,
+    last_error TEXT
+);
+
+CREATE INDEX idx_tenants_trial_ends_at ON tenants(trial_ends_at) WHERE trial_ends_at IS NOT NULL;
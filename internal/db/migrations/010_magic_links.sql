@@ -0,0 +1,18 @@
+-- Migración: Acceso sin contraseña por link de email (magic link)
+
+-- Solo se guarda el hash del token. Las solicitudes para emails sin cuenta
+-- también se registran (user_id NULL, nunca se envían) para que el rate limit
+-- por email no revele qué emails existen.
+CREATE TABLE magic_links (
+    id UUID PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    client_ip TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_magic_links_email_created_at ON magic_links(email, created_at);
+CREATE INDEX idx_magic_links_expires_at ON magic_links(expires_at);
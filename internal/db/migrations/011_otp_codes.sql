@@ -0,0 +1,20 @@
+-- Migración: Acceso con código de un solo uso (OTP) por email, SMS o WhatsApp
+
+-- Igual que magic_links: solo se guarda el HMAC del código y las solicitudes
+-- para emails sin cuenta se registran (user_id NULL) sin enviarse.
+CREATE TABLE otp_codes (
+    id UUID PRIMARY KEY,
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    channel TEXT NOT NULL,
+    client_ip TEXT,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_otp_codes_email_created_at ON otp_codes(email, created_at);
+CREATE INDEX idx_otp_codes_client_ip_created_at ON otp_codes(client_ip, created_at);
+CREATE INDEX idx_otp_codes_expires_at ON otp_codes(expires_at);
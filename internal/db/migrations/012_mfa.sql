@@ -0,0 +1,48 @@
+-- Migración: Autenticación multifactor (TOTP + códigos de recuperación)
+
+-- Secreto TOTP cifrado con SECRETS_KEY. confirmed_at NULL = enrolamiento
+-- pendiente de confirmar con un primer código. last_used_step evita que un
+-- código ya aceptado se reutilice dentro de su ventana.
+CREATE TABLE mfa_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret TEXT NOT NULL,
+    confirmed_at TIMESTAMPTZ,
+    last_used_step BIGINT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Códigos de recuperación de un solo uso; solo se guarda su HMAC.
+CREATE TABLE mfa_recovery_codes (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_mfa_recovery_codes_user_id ON mfa_recovery_codes(user_id);
+
+-- Desafíos MFA: el paso de contraseña devuelve un token opaco que se canjea
+-- con el segundo factor. Intentos limitados por desafío.
+CREATE TABLE mfa_challenges (
+    id UUID PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID,
+    attempts INT NOT NULL DEFAULT 0,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_mfa_challenges_expires_at ON mfa_challenges(expires_at);
+
+INSERT INTO permissions (id, code, description, created_at) VALUES
+('10000000-0000-0000-0000-000000000020', 'users:reset_mfa', 'Reset multi-factor authentication of users', NOW())
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, assigned_at)
+SELECT '20000000-0000-0000-0000-000000000001', id, NOW()
+FROM permissions
+WHERE code = 'users:reset_mfa'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
@@ -0,0 +1,32 @@
+-- Migración: WebAuthn / passkeys
+
+-- Llaves públicas registradas por usuario. credential_id es el id del
+-- autenticador en base64url; public_key es la llave COSE tal cual la envía.
+CREATE TABLE webauthn_credentials (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    credential_id TEXT NOT NULL UNIQUE,
+    public_key BYTEA NOT NULL,
+    algorithm INT NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    transports TEXT NOT NULL DEFAULT '',
+    aaguid TEXT NOT NULL DEFAULT '',
+    name TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_used_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_webauthn_credentials_user_id ON webauthn_credentials(user_id);
+
+-- Challenges de las ceremonias en curso (registro, login, segundo factor).
+-- Se consumen al terminar la ceremonia; user_id NULL = login sin usuario previo.
+CREATE TABLE webauthn_challenges (
+    id UUID PRIMARY KEY,
+    challenge TEXT NOT NULL UNIQUE,
+    purpose TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_webauthn_challenges_expires_at ON webauthn_challenges(expires_at);
@@ -0,0 +1,20 @@
+-- Migración: Recuperación de contraseña por email
+
+-- Como magic_links: solo se guarda el hash del token y las solicitudes para
+-- emails sin cuenta se registran (user_id NULL) sin enviarse, para que ni la
+-- respuesta ni el rate limit revelen qué emails existen.
+CREATE TABLE password_resets (
+    id UUID PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    client_ip TEXT,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_resets_email_created_at ON password_resets(email, created_at);
+CREATE INDEX idx_password_resets_client_ip_created_at ON password_resets(client_ip, created_at);
+CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+CREATE INDEX idx_password_resets_expires_at ON password_resets(expires_at);
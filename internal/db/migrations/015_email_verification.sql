@@ -0,0 +1,34 @@
+-- Migración: Verificación de email
+
+-- Estado de verificación por dirección: si el email de un usuario cambia, la
+-- dirección nueva empieza sin verificar.
+CREATE TABLE email_verifications (
+    email TEXT PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    verified_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_verifications_user_id ON email_verifications(user_id);
+
+-- Las cuentas existentes se consideran verificadas: se crearon antes de que
+-- existiera el flujo y activar el requisito en un tenant no debe dejarlas fuera.
+INSERT INTO email_verifications (email, user_id, verified_at)
+SELECT LOWER(email), id, NOW() FROM users
+ON CONFLICT (email) DO NOTHING;
+
+-- Tokens enviados por email. Como en password_resets, las solicitudes para
+-- emails sin cuenta (o ya verificados) se registran con user_id NULL para que
+-- el límite de reenvíos no revele nada.
+CREATE TABLE email_verification_tokens (
+    id UUID PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    email TEXT NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMPTZ NOT NULL,
+    consumed_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_verification_tokens_email_created_at ON email_verification_tokens(email, created_at);
+CREATE INDEX idx_email_verification_tokens_expires_at ON email_verification_tokens(expires_at);
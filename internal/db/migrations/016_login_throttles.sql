@@ -0,0 +1,28 @@
+-- Migración: Protección contra fuerza bruta en el login
+
+-- Contadores de fallos de contraseña. kind = 'account' (subject = email en
+-- minúsculas, también para emails sin cuenta: así un bloqueo no revela si la
+-- cuenta existe) o 'ip' (subject = IP del cliente).
+CREATE TABLE login_throttles (
+    kind TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    failures INT NOT NULL DEFAULT 0,
+    window_started_at TIMESTAMPTZ NOT NULL,
+    last_failure_at TIMESTAMPTZ NOT NULL,
+    locked_until TIMESTAMPTZ,
+    PRIMARY KEY (kind, subject),
+    CONSTRAINT check_login_throttle_kind CHECK (kind IN ('account', 'ip'))
+);
+
+CREATE INDEX idx_login_throttles_locked_until ON login_throttles(locked_until);
+CREATE INDEX idx_login_throttles_last_failure_at ON login_throttles(last_failure_at);
+
+INSERT INTO permissions (id, code, description, created_at) VALUES
+('10000000-0000-0000-0000-000000000021', 'users:unlock', 'Unlock accounts and IPs locked after failed logins', NOW())
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, assigned_at)
+SELECT '20000000-0000-0000-0000-000000000001', id, NOW()
+FROM permissions
+WHERE code = 'users:unlock'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
@@ -0,0 +1,12 @@
+-- Migración: Rate limiting compartido entre réplicas
+
+-- Un bucket por política y clave (IP, email, API key o tenant). tat es el
+-- "theoretical arrival time" de GCRA en microsegundos desde epoch: el momento
+-- en que el bucket vuelve a estar lleno. Un bucket con tat pasado equivale a
+-- uno lleno y puede borrarse.
+CREATE TABLE rate_limit_buckets (
+    key TEXT PRIMARY KEY,
+    tat BIGINT NOT NULL
+);
+
+CREATE INDEX idx_rate_limit_buckets_tat ON rate_limit_buckets(tat);
@@ -0,0 +1,17 @@
+-- Migración: Historial de contraseñas
+
+-- Hashes de las últimas contraseñas de cada usuario, incluida la actual, para
+-- que la política del tenant impida reutilizarlas. Se guardan como mucho las
+-- 24 más recientes.
+CREATE TABLE password_history (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    password_hash TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_password_history_user_id ON password_history(user_id, created_at DESC);
+
+-- La contraseña actual de las cuentas existentes entra en el historial
+INSERT INTO password_history (id, user_id, password_hash, created_at)
+SELECT gen_random_uuid(), user_id, password_hash, updated_at FROM credentials;
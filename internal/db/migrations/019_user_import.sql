@@ -0,0 +1,11 @@
+-- Migración: Importación de usuarios con hashes de contraseña de otros sistemas
+
+INSERT INTO permissions (id, code, description, created_at) VALUES
+('10000000-0000-0000-0000-000000000022', 'users:import', 'Import users with password hashes from other systems', NOW())
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, assigned_at)
+SELECT '20000000-0000-0000-0000-000000000001', id, NOW()
+FROM permissions
+WHERE code = 'users:import'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
@@ -0,0 +1,5 @@
+-- Migración: Desafíos MFA de enrolamiento
+
+-- Marca los desafíos emitidos a usuarios sin MFA en un tenant que lo exige: solo
+-- esos tokens sirven para enrolarse desde el login (/auth/mfa/enroll).
+ALTER TABLE mfa_challenges ADD COLUMN enrollment_required BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,5 @@
+-- Migración: Cliente OAuth de cada sesión
+
+-- Un refresh token solo puede canjearlo el cliente al que se emitió (RFC 6749 §6).
+-- NULL = sesión de /auth/* (login propio del IAM), sin cliente OAuth.
+ALTER TABLE sessions ADD COLUMN client_id UUID REFERENCES oauth_clients(id) ON DELETE CASCADE;
@@ -0,0 +1,11 @@
+-- Migración: Permiso para inspeccionar tokens de cualquier tenant en /oauth/introspect
+
+INSERT INTO permissions (id, code, description, created_at) VALUES
+('10000000-0000-0000-0000-000000000023', 'tokens:introspect_all', 'Introspect tokens of any tenant', NOW())
+ON CONFLICT (code) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id, assigned_at)
+SELECT '20000000-0000-0000-0000-000000000001', id, NOW()
+FROM permissions
+WHERE code = 'tokens:introspect_all'
+ON CONFLICT (role_id, permission_id) DO NOTHING;
@@ -0,0 +1,3 @@
+-- name: CreateAuditEvent :exec
+INSERT INTO audit_events (id, event_type, tenant_id, user_id, client_ip, user_agent, metadata, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
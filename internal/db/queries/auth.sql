@@ -10,3 +10,8 @@ WHERE user_id = $1 LIMIT 1;
 UPDATE credentials
 SET password_hash = $2, updated_at = NOW()
 WHERE user_id = $1;
+
+-- name: RehashCredentialPassword :execrows
+UPDATE credentials
+SET password_hash = sqlc.arg(new_hash)
+WHERE user_id = sqlc.arg(user_id) AND password_hash = sqlc.arg(old_hash);
@@ -0,0 +1,37 @@
+-- name: GetEmailVerification :one
+SELECT * FROM email_verifications
+WHERE email = $1 LIMIT 1;
+
+-- name: MarkEmailVerified :exec
+INSERT INTO email_verifications (email, user_id, verified_at, created_at)
+VALUES ($1, $2, $3, $3)
+ON CONFLICT (email) DO UPDATE
+SET user_id = EXCLUDED.user_id,
+    verified_at = EXCLUDED.verified_at
+WHERE email_verifications.user_id <> EXCLUDED.user_id;
+
+-- name: CreateEmailVerificationToken :exec
+INSERT INTO email_verification_tokens (id, token_hash, email, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: CountRecentEmailVerificationTokens :one
+SELECT COUNT(*) FROM email_verification_tokens
+WHERE email = $1 AND created_at > $2;
+
+-- name: ConsumeEmailVerificationToken :one
+UPDATE email_verification_tokens
+SET consumed_at = NOW()
+WHERE token_hash = $1
+  AND consumed_at IS NULL
+  AND expires_at > NOW()
+  AND user_id IS NOT NULL
+RETURNING *;
+
+-- name: InvalidateEmailVerificationTokens :execrows
+UPDATE email_verification_tokens
+SET consumed_at = NOW()
+WHERE email = $1 AND consumed_at IS NULL;
+
+-- name: DeleteExpiredEmailVerificationTokens :execrows
+DELETE FROM email_verification_tokens
+WHERE expires_at < $1;
@@ -0,0 +1,33 @@
+-- name: GetLoginThrottle :one
+SELECT * FROM login_throttles
+WHERE kind = $1 AND subject = $2 LIMIT 1;
+
+-- name: RecordLoginFailure :one
+INSERT INTO login_throttles (kind, subject, failures, window_started_at, last_failure_at)
+VALUES (sqlc.arg(kind), sqlc.arg(subject), 1, sqlc.arg(now), sqlc.arg(now))
+ON CONFLICT (kind, subject) DO UPDATE
+SET failures = CASE WHEN login_throttles.window_started_at < sqlc.arg(window_start) THEN 1 ELSE login_throttles.failures + 1 END,
+    window_started_at = CASE WHEN login_throttles.window_started_at < sqlc.arg(window_start) THEN sqlc.arg(now) ELSE login_throttles.window_started_at END,
+    last_failure_at = sqlc.arg(now)
+RETURNING *;
+
+-- name: LockLoginThrottle :exec
+UPDATE login_throttles
+SET locked_until = $3,
+    failures = 0,
+    window_started_at = $4
+WHERE kind = $1 AND subject = $2;
+
+-- name: DeleteLoginThrottle :execrows
+DELETE FROM login_throttles
+WHERE kind = $1 AND subject = $2;
+
+-- name: ListLockedLoginThrottles :many
+SELECT * FROM login_throttles
+WHERE locked_until > $1
+ORDER BY locked_until DESC;
+
+-- name: DeleteStaleLoginThrottles :execrows
+DELETE FROM login_throttles
+WHERE last_failure_at < $1
+  AND (locked_until IS NULL OR locked_until < NOW());
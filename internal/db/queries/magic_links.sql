@@ -0,0 +1,20 @@
+-- name: CreateMagicLink :exec
+INSERT INTO magic_links (id, token_hash, email, user_id, client_ip, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: CountRecentMagicLinks :one
+SELECT COUNT(*) FROM magic_links
+WHERE email = $1 AND created_at > $2;
+
+-- name: ConsumeMagicLink :one
+UPDATE magic_links
+SET consumed_at = NOW()
+WHERE token_hash = $1
+  AND consumed_at IS NULL
+  AND expires_at > NOW()
+  AND user_id IS NOT NULL
+RETURNING *;
+
+-- name: DeleteExpiredMagicLinks :execrows
+DELETE FROM magic_links
+WHERE expires_at < $1;
@@ -0,0 +1,74 @@
+-- name: UpsertPendingTOTP :execrows
+INSERT INTO mfa_totp (user_id, secret, created_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret,
+    last_used_step = NULL,
+    created_at = EXCLUDED.created_at
+WHERE mfa_totp.confirmed_at IS NULL;
+
+-- name: GetTOTP :one
+SELECT * FROM mfa_totp
+WHERE user_id = $1;
+
+-- name: ConfirmTOTP :execrows
+UPDATE mfa_totp
+SET confirmed_at = NOW(), last_used_step = $2
+WHERE user_id = $1 AND confirmed_at IS NULL;
+
+-- name: UseTOTPStep :execrows
+UPDATE mfa_totp
+SET last_used_step = $2
+WHERE user_id = $1
+  AND confirmed_at IS NOT NULL
+  AND (last_used_step IS NULL OR last_used_step < $2);
+
+-- name: DeleteTOTP :execrows
+DELETE FROM mfa_totp
+WHERE user_id = $1;
+
+-- name: CreateRecoveryCode :exec
+INSERT INTO mfa_recovery_codes (id, user_id, code_hash, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: UseRecoveryCode :execrows
+UPDATE mfa_recovery_codes
+SET used_at = NOW()
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountUnusedRecoveryCodes :one
+SELECT COUNT(*) FROM mfa_recovery_codes
+WHERE user_id = $1 AND used_at IS NULL;
+
+-- name: DeleteRecoveryCodes :execrows
+DELETE FROM mfa_recovery_codes
+WHERE user_id = $1;
+
+-- name: CreateMFAChallenge :exec
+INSERT INTO mfa_challenges (id, token_hash, user_id, tenant_id, expires_at, created_at, enrollment_required)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: GetActiveMFAChallenge :one
+SELECT * FROM mfa_challenges
+WHERE token_hash = $1
+  AND consumed_at IS NULL
+  AND expires_at > NOW();
+
+-- name: IncrementMFAChallengeAttempts :one
+UPDATE mfa_challenges
+SET attempts = attempts + 1
+WHERE id = $1 AND attempts < $2 AND consumed_at IS NULL
+RETURNING attempts;
+
+-- name: ConsumeMFAChallenge :execrows
+UPDATE mfa_challenges
+SET consumed_at = NOW()
+WHERE id = $1 AND consumed_at IS NULL;
+
+-- name: DeleteMFAChallengesByUser :execrows
+DELETE FROM mfa_challenges
+WHERE user_id = $1;
+
+-- name: DeleteExpiredMFAChallenges :execrows
+DELETE FROM mfa_challenges
+WHERE expires_at < $1;
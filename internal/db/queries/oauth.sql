@@ -0,0 +1,30 @@
+-- name: CreateOAuthClient :one
+INSERT INTO oauth_clients (id, secret_hash, name, tenant_id, redirect_uris, grant_types, scopes, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetOAuthClientByID :one
+SELECT * FROM oauth_clients
+WHERE id = $1 LIMIT 1;
+
+-- name: ListOAuthClients :many
+SELECT * FROM oauth_clients
+ORDER BY created_at DESC;
+
+-- name: DeleteOAuthClient :exec
+DELETE FROM oauth_clients
+WHERE id = $1;
+
+-- name: CreateAuthorizationCode :exec
+INSERT INTO oauth_authorization_codes (code_hash, client_id, user_id, tenant_id, redirect_uri, scope, nonce, code_challenge, code_challenge_method, auth_time, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
+
+-- name: ConsumeAuthorizationCode :one
+UPDATE oauth_authorization_codes
+SET consumed_at = NOW()
+WHERE code_hash = $1 AND consumed_at IS NULL AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredAuthorizationCodes :execrows
+DELETE FROM oauth_authorization_codes
+WHERE expires_at <= NOW();
@@ -0,0 +1,34 @@
+-- name: CreateOTPCode :exec
+INSERT INTO otp_codes (id, email, user_id, code_hash, channel, client_ip, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: CountRecentOTPCodesByEmail :one
+SELECT COUNT(*) FROM otp_codes
+WHERE email = $1 AND created_at > $2;
+
+-- name: CountRecentOTPCodesByIP :one
+SELECT COUNT(*) FROM otp_codes
+WHERE client_ip = $1 AND created_at > $2;
+
+-- name: GetActiveOTPCode :one
+SELECT * FROM otp_codes
+WHERE email = $1
+  AND consumed_at IS NULL
+  AND expires_at > NOW()
+ORDER BY created_at DESC
+LIMIT 1;
+
+-- name: IncrementOTPAttempts :one
+UPDATE otp_codes
+SET attempts = attempts + 1
+WHERE id = $1 AND attempts < $2 AND consumed_at IS NULL
+RETURNING attempts;
+
+-- name: ConsumeOTPCode :execrows
+UPDATE otp_codes
+SET consumed_at = NOW()
+WHERE id = $1 AND consumed_at IS NULL;
+
+-- name: DeleteExpiredOTPCodes :execrows
+DELETE FROM otp_codes
+WHERE expires_at < $1;
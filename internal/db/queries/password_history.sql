@@ -0,0 +1,19 @@
+-- name: AddPasswordHistory :exec
+INSERT INTO password_history (id, user_id, password_hash, created_at)
+VALUES ($1, $2, $3, $4);
+
+-- name: ListPasswordHistory :many
+SELECT password_hash FROM password_history
+WHERE user_id = $1
+ORDER BY created_at DESC
+LIMIT $2;
+
+-- name: PrunePasswordHistory :execrows
+DELETE FROM password_history
+WHERE user_id = $1
+  AND id NOT IN (
+    SELECT id FROM password_history
+    WHERE user_id = $1
+    ORDER BY created_at DESC
+    LIMIT $2
+  );
@@ -0,0 +1,37 @@
+-- name: CreatePasswordReset :exec
+INSERT INTO password_resets (id, token_hash, email, user_id, client_ip, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7);
+
+-- name: CountRecentPasswordResetsByEmail :one
+SELECT COUNT(*) FROM password_resets
+WHERE email = $1 AND created_at > $2;
+
+-- name: CountRecentPasswordResetsByIP :one
+SELECT COUNT(*) FROM password_resets
+WHERE client_ip = $1 AND created_at > $2;
+
+-- name: GetActivePasswordReset :one
+SELECT * FROM password_resets
+WHERE token_hash = $1
+  AND consumed_at IS NULL
+  AND expires_at > NOW()
+  AND user_id IS NOT NULL
+LIMIT 1;
+
+-- name: ConsumePasswordReset :one
+UPDATE password_resets
+SET consumed_at = NOW()
+WHERE token_hash = $1
+  AND consumed_at IS NULL
+  AND expires_at > NOW()
+  AND user_id IS NOT NULL
+RETURNING *;
+
+-- name: InvalidateUserPasswordResets :execrows
+UPDATE password_resets
+SET consumed_at = NOW()
+WHERE user_id = $1 AND consumed_at IS NULL;
+
+-- name: DeleteExpiredPasswordResets :execrows
+DELETE FROM password_resets
+WHERE expires_at < $1;
@@ -0,0 +1,15 @@
+-- name: TakeRateLimitToken :one
+INSERT INTO rate_limit_buckets AS b (key, tat)
+VALUES (sqlc.arg(key), sqlc.arg(now)::bigint + sqlc.arg(emission)::bigint)
+ON CONFLICT (key) DO UPDATE
+SET tat = GREATEST(b.tat, sqlc.arg(now)::bigint) + sqlc.arg(emission)::bigint
+WHERE GREATEST(b.tat, sqlc.arg(now)::bigint) + sqlc.arg(emission)::bigint - sqlc.arg(now)::bigint <= sqlc.arg(tolerance)::bigint
+RETURNING tat;
+
+-- name: GetRateLimitBucket :one
+SELECT tat FROM rate_limit_buckets
+WHERE key = $1;
+
+-- name: DeleteExpiredRateLimitBuckets :execrows
+DELETE FROM rate_limit_buckets
+WHERE tat < $1;
@@ -0,0 +1,27 @@
+-- name: RevokeToken :exec
+INSERT INTO revoked_tokens (jti, expires_at, revoked_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (jti) DO NOTHING;
+
+-- name: ListRevokedTokens :many
+SELECT * FROM revoked_tokens
+WHERE expires_at > NOW();
+
+-- name: SetUserTokensValidAfter :exec
+UPDATE users
+SET tokens_valid_after = $2
+WHERE id = $1;
+
+-- name: ListUserTokenEpochs :many
+SELECT id, tokens_valid_after FROM users
+WHERE tokens_valid_after > $1;
+
+-- name: SetTenantTokensValidAfter :exec
+UPDATE users
+SET tokens_valid_after = $2
+WHERE users.tenant_id = $1
+   OR users.id IN (SELECT user_id FROM sessions WHERE sessions.tenant_id = $1);
+
+-- name: DeleteExpiredRevokedTokens :execrows
+DELETE FROM revoked_tokens
+WHERE expires_at <= NOW();
@@ -36,3 +36,12 @@ FROM permissions p
 JOIN role_permissions rp ON p.id = rp.permission_id
 JOIN user_roles ur ON rp.role_id = ur.role_id
 WHERE ur.user_id = $1;
+
+-- name: GetPermissionsByUserInTenant :many
+SELECT DISTINCT p.code
+FROM permissions p
+JOIN role_permissions rp ON p.id = rp.permission_id
+JOIN user_roles ur ON rp.role_id = ur.role_id
+JOIN roles r ON r.id = ur.role_id
+WHERE ur.user_id = $1
+  AND COALESCE(r.tenant_id, '00000000-0000-0000-0000-000000000000') = $2;
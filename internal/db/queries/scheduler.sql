@@ -0,0 +1,15 @@
+-- name: TryAdvisoryLock :one
+SELECT pg_try_advisory_lock($1);
+
+-- name: AdvisoryUnlock :one
+SELECT pg_advisory_unlock($1);
+
+-- name: GetScheduledJob :one
+SELECT * FROM scheduled_jobs
+WHERE name = $1 LIMIT 1;
+
+-- name: MarkScheduledJobRun :exec
+INSERT INTO scheduled_jobs (name, last_run_at, last_error)
+VALUES ($1, $2, $3)
+ON CONFLICT (name) DO UPDATE
+SET last_run_at = EXCLUDED.last_run_at, last_error = EXCLUDED.last_error;
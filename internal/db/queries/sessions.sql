@@ -1,6 +1,6 @@
 -- name: CreateSession :one
-INSERT INTO sessions (id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at)
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+INSERT INTO sessions (id, user_id, tenant_id, refresh_token, user_agent, client_ip, expires_at, created_at, family_id, client_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
 RETURNING *;
 
 -- name: GetSessionByID :one
@@ -14,3 +14,49 @@ WHERE refresh_token = $1 LIMIT 1;
 -- name: DeleteSession :exec
 DELETE FROM sessions
 WHERE id = $1;
+
+-- name: ConsumeSession :one
+UPDATE sessions
+SET consumed_at = NOW()
+WHERE refresh_token = $1 AND consumed_at IS NULL
+RETURNING *;
+
+-- name: DeleteSessionFamily :execrows
+DELETE FROM sessions
+WHERE family_id = $1;
+
+-- name: DeleteSessionsByUser :execrows
+DELETE FROM sessions
+WHERE user_id = $1;
+
+-- name: DeleteSessionsByTenant :execrows
+DELETE FROM sessions
+WHERE tenant_id = $1
+   OR user_id IN (SELECT id FROM users WHERE users.tenant_id = $1);
+
+-- name: ListActiveSessionsByUser :many
+SELECT s.id, s.family_id, s.user_agent, s.client_ip, s.created_at, s.expires_at,
+       (SELECT MIN(f.created_at) FROM sessions f WHERE f.family_id = s.family_id)::timestamptz AS signed_in_at
+FROM sessions s
+WHERE s.user_id = $1
+  AND s.consumed_at IS NULL
+  AND s.expires_at > NOW()
+ORDER BY s.created_at DESC;
+
+-- name: DeleteUserSessionFamily :execrows
+DELETE FROM sessions
+WHERE family_id = $1 AND user_id = $2;
+
+-- name: DeleteExpiredSessions :execrows
+DELETE FROM sessions
+WHERE expires_at <= NOW();
+
+-- name: ListLegacySessionTokens :many
+SELECT id, refresh_token FROM sessions
+WHERE refresh_token NOT LIKE 'h1:%'
+LIMIT $1;
+
+-- name: SetSessionRefreshToken :exec
+UPDATE sessions
+SET refresh_token = $2
+WHERE id = $1;
@@ -0,0 +1,23 @@
+-- name: CreateSigningKey :one
+INSERT INTO signing_keys (id, algorithm, private_key, public_key, activates_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: ListVerifiableSigningKeys :many
+SELECT * FROM signing_keys
+WHERE expires_at IS NULL OR expires_at > NOW()
+ORDER BY activates_at DESC;
+
+-- name: ListSigningKeys :many
+SELECT * FROM signing_keys
+ORDER BY activates_at DESC;
+
+-- name: RetireSigningKey :exec
+UPDATE signing_keys
+SET retired_at = $2, expires_at = $3
+WHERE id = $1 AND retired_at IS NULL;
+
+-- name: RetireSigningKeysBefore :exec
+UPDATE signing_keys
+SET retired_at = $2, expires_at = $3
+WHERE retired_at IS NULL AND activates_at < $1;
@@ -44,3 +44,21 @@ WHERE id = $1;
 SELECT id, key, name, description, origin, subtype, status, is_active, config, trial_ends_at, disabled_at, created_at, updated_at 
 FROM tenants
 WHERE key = $1 LIMIT 1;
+
+-- name: ExpireTenantTrials :many
+UPDATE tenants
+SET status = 'suspended', is_active = false, disabled_at = NOW(), updated_at = NOW()
+WHERE status = 'active'
+  AND trial_ends_at IS NOT NULL
+  AND trial_ends_at <= NOW()
+RETURNING id;
+
+-- name: UserBelongsToTenant :one
+SELECT EXISTS (
+    SELECT 1 FROM users u
+    WHERE u.id = sqlc.arg(user_id) AND u.tenant_id = sqlc.arg(tenant_id)
+) OR EXISTS (
+    SELECT 1 FROM user_roles ur
+    JOIN roles r ON r.id = ur.role_id
+    WHERE ur.user_id = sqlc.arg(user_id) AND r.tenant_id = sqlc.arg(tenant_id)
+) AS belongs;
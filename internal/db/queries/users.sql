@@ -18,5 +18,8 @@ ORDER BY created_at DESC;
 
 -- name: UpdateUserStatus :exec
 UPDATE users
-SET is_active = $2, updated_at = NOW()
+SET is_active = $2,
+    updated_at = NOW(),
+    -- Al desactivar un usuario se invalidan sus access tokens vigentes
+    tokens_valid_after = CASE WHEN $2 THEN tokens_valid_after ELSE NOW() END
 WHERE id = $1;
@@ -0,0 +1,42 @@
+-- name: CreateWebAuthnCredential :exec
+INSERT INTO webauthn_credentials (id, user_id, credential_id, public_key, algorithm, sign_count, transports, aaguid, name, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10);
+
+-- name: GetWebAuthnCredentialByCredentialID :one
+SELECT * FROM webauthn_credentials
+WHERE credential_id = $1;
+
+-- name: ListWebAuthnCredentialsByUser :many
+SELECT * FROM webauthn_credentials
+WHERE user_id = $1
+ORDER BY created_at;
+
+-- name: CountWebAuthnCredentialsByUser :one
+SELECT COUNT(*) FROM webauthn_credentials
+WHERE user_id = $1;
+
+-- name: UpdateWebAuthnCredentialUsage :execrows
+UPDATE webauthn_credentials
+SET sign_count = $2, last_used_at = NOW()
+WHERE id = $1 AND (sign_count = 0 OR sign_count < $2);
+
+-- name: DeleteWebAuthnCredential :execrows
+DELETE FROM webauthn_credentials
+WHERE id = $1 AND user_id = $2;
+
+-- name: DeleteWebAuthnCredentialsByUser :execrows
+DELETE FROM webauthn_credentials
+WHERE user_id = $1;
+
+-- name: CreateWebAuthnChallenge :exec
+INSERT INTO webauthn_challenges (id, challenge, purpose, user_id, expires_at, created_at)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: ConsumeWebAuthnChallenge :one
+DELETE FROM webauthn_challenges
+WHERE challenge = $1 AND purpose = $2 AND expires_at > NOW()
+RETURNING *;
+
+-- name: DeleteExpiredWebAuthnChallenges :execrows
+DELETE FROM webauthn_challenges
+WHERE expires_at < $1;
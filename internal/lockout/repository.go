@@ -0,0 +1,79 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+)
+
+// Tipos de contador.
+const (
+	KindAccount = "account"
+	KindIP      = "ip"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+// Get devuelve el contador. Devuelve sql.ErrNoRows si no hay fallos registrados.
+func (r *Repository) Get(ctx context.Context, kind, subject string) (*gen.LoginThrottle, error) {
+	t, err := r.q.GetLoginThrottle(ctx, gen.GetLoginThrottleParams{
+		Kind:    kind,
+		Subject: subject,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RecordFailure suma un fallo de forma atómica. Los fallos anteriores a
+// windowStart no cuentan: el contador vuelve a 1.
+func (r *Repository) RecordFailure(ctx context.Context, kind, subject string, now, windowStart time.Time) (*gen.LoginThrottle, error) {
+	t, err := r.q.RecordLoginFailure(ctx, gen.RecordLoginFailureParams{
+		Kind:        kind,
+		Subject:     subject,
+		Now:         now,
+		WindowStart: windowStart,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Lock bloquea hasta until y reinicia el contador.
+func (r *Repository) Lock(ctx context.Context, kind, subject string, until, now time.Time) error {
+	return r.q.LockLoginThrottle(ctx, gen.LockLoginThrottleParams{
+		Kind:            kind,
+		Subject:         subject,
+		LockedUntil:     sql.NullTime{Time: until, Valid: true},
+		WindowStartedAt: now,
+	})
+}
+
+// Delete elimina el contador (y el bloqueo). Devuelve false si no existía.
+func (r *Repository) Delete(ctx context.Context, kind, subject string) (bool, error) {
+	n, err := r.q.DeleteLoginThrottle(ctx, gen.DeleteLoginThrottleParams{
+		Kind:    kind,
+		Subject: subject,
+	})
+	return n > 0, err
+}
+
+// ListLocked devuelve los bloqueos vigentes en now.
+func (r *Repository) ListLocked(ctx context.Context, now time.Time) ([]gen.LoginThrottle, error) {
+	return r.q.ListLockedLoginThrottles(ctx, sql.NullTime{Time: now, Valid: true})
+}
+
+// DeleteStale elimina los contadores sin fallos desde before y sin bloqueo vigente.
+func (r *Repository) DeleteStale(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteStaleLoginThrottles(ctx, before)
+}
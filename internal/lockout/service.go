@@ -0,0 +1,312 @@
+package lockout
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/audit"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/tenants"
+	"github.com/google/uuid"
+)
+
+// Umbrales por cuenta; cada tenant puede cambiarlos en su config. Un email sin
+// cuenta usa los valores por defecto.
+const (
+	defaultMaxFailures     = 5
+	defaultLockoutDuration = 15 * time.Minute
+	defaultWindow          = 15 * time.Minute
+	defaultDelayAfter      = 3
+	defaultMaxDelay        = 30 * time.Second
+	baseDelay              = time.Second
+	maxWindow              = 24 * time.Hour
+
+	tenantMaxFailuresKey     = "lockout_max_failures"
+	tenantLockoutDurationKey = "lockout_duration"
+	tenantWindowKey          = "lockout_window"
+	tenantDelayAfterKey      = "login_delay_after"
+	tenantMaxDelayKey        = "login_max_delay"
+)
+
+// Umbrales por IP: una IP prueba cuentas de cualquier tenant, así que son globales.
+const (
+	ipMaxFailures     = 50
+	ipLockoutDuration = 15 * time.Minute
+	ipWindow          = 15 * time.Minute
+)
+
+var ErrInvalidIP = errors.New("invalid ip address")
+
+type UsersRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*dbgen.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*dbgen.User, error)
+}
+
+// Policy son los umbrales que se aplican a una cuenta.
+type Policy struct {
+	MaxFailures     int           // fallos que bloquean la cuenta (0 = sin bloqueo)
+	LockoutDuration time.Duration // duración del bloqueo
+	Window          time.Duration // los fallos más antiguos no cuentan
+	DelayAfter      int           // fallos a partir de los que hay retardo (0 = sin retardo)
+	MaxDelay        time.Duration // tope del retardo, que se duplica con cada fallo
+}
+
+// delay devuelve la espera obligatoria tras failures fallos seguidos.
+func (p Policy) delay(failures int32) time.Duration {
+	if p.DelayAfter <= 0 || int(failures) < p.DelayAfter {
+		return 0
+	}
+	d := baseDelay
+	for i := p.DelayAfter; i < int(failures) && d < p.MaxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.MaxDelay)
+}
+
+// Lock es un bloqueo vigente.
+type Lock struct {
+	Kind        string    `json:"kind"`    // account o ip
+	Subject     string    `json:"subject"` // email o IP
+	LockedUntil time.Time `json:"locked_until"`
+}
+
+type Service struct {
+	repo    *Repository
+	users   UsersRepository
+	tenants *tenants.Service
+	audit   *audit.Service
+}
+
+func NewService(repo *Repository, users UsersRepository, tenantService *tenants.Service, auditService *audit.Service) *Service {
+	return &Service{
+		repo:    repo,
+		users:   users,
+		tenants: tenantService,
+		audit:   auditService,
+	}
+}
+
+// Check devuelve un *auth.LoginThrottled si la IP o la cuenta están bloqueadas
+// o aún no pasó el retardo tras el último fallo; implementa auth.LoginGuard.
+func (s *Service) Check(ctx context.Context, email string, client auth.ClientInfo) error {
+	now := time.Now()
+
+	if client.IP != "" {
+		t, err := s.get(ctx, KindIP, client.IP)
+		if err != nil {
+			return err
+		}
+		if t != nil && t.LockedUntil.Valid && t.LockedUntil.Time.After(now) {
+			return &auth.LoginThrottled{Locked: true, RetryAfter: t.LockedUntil.Time.Sub(now)}
+		}
+	}
+
+	t, err := s.get(ctx, KindAccount, accountKey(email))
+	if err != nil || t == nil {
+		return err
+	}
+	if t.LockedUntil.Valid && t.LockedUntil.Time.After(now) {
+		return &auth.LoginThrottled{Locked: true, RetryAfter: t.LockedUntil.Time.Sub(now)}
+	}
+
+	policy := s.policyFor(ctx, email)
+	if t.WindowStartedAt.Before(now.Add(-policy.Window)) {
+		return nil
+	}
+	if wait := t.LastFailureAt.Add(policy.delay(t.Failures)).Sub(now); wait > 0 {
+		return &auth.LoginThrottled{RetryAfter: wait}
+	}
+	return nil
+}
+
+// Failed registra el fallo para la cuenta y la IP y bloquea la que supere su
+// umbral; implementa auth.LoginGuard. Los errores solo se registran: el
+// cliente recibe igualmente ErrInvalidCredentials.
+func (s *Service) Failed(ctx context.Context, email string, user *dbgen.User, client auth.ClientInfo) {
+	now := time.Now()
+	key := accountKey(email)
+
+	policy := s.defaultPolicy()
+	event := audit.Event{
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+	}
+	if user != nil {
+		policy = s.policy(ctx, user.TenantID)
+		event.TenantID = user.TenantID.String()
+		event.UserID = user.ID.String()
+	}
+
+	t, err := s.repo.RecordFailure(ctx, KindAccount, key, now, now.Add(-policy.Window))
+	if err != nil {
+		log.Printf("⚠️  Error registrando login fallido: %v", err)
+		return
+	}
+
+	failed := event
+	failed.Type = audit.EventLoginFailed
+	failed.Metadata = map[string]any{"email": key, "failures": t.Failures}
+	s.audit.Record(ctx, failed)
+
+	if policy.MaxFailures > 0 && int(t.Failures) >= policy.MaxFailures {
+		until := now.Add(policy.LockoutDuration)
+		if err := s.repo.Lock(ctx, KindAccount, key, until, now); err != nil {
+			log.Printf("⚠️  Error bloqueando cuenta: %v", err)
+		} else {
+			locked := event
+			locked.Type = audit.EventAccountLocked
+			locked.Metadata = map[string]any{"email": key, "failures": t.Failures, "locked_until": until}
+			s.audit.Alert(ctx, locked)
+		}
+	}
+
+	if client.IP == "" {
+		return
+	}
+	t, err = s.repo.RecordFailure(ctx, KindIP, client.IP, now, now.Add(-ipWindow))
+	if err != nil {
+		log.Printf("⚠️  Error registrando login fallido: %v", err)
+		return
+	}
+	if t.Failures >= ipMaxFailures {
+		until := now.Add(ipLockoutDuration)
+		if err := s.repo.Lock(ctx, KindIP, client.IP, until, now); err != nil {
+			log.Printf("⚠️  Error bloqueando IP: %v", err)
+			return
+		}
+		s.audit.Alert(ctx, audit.Event{
+			Type:      audit.EventIPLocked,
+			ClientIP:  client.IP,
+			UserAgent: client.UserAgent,
+			Metadata:  map[string]any{"failures": t.Failures, "locked_until": until},
+		})
+	}
+}
+
+// Succeeded reinicia el contador de la cuenta; implementa auth.LoginGuard. El
+// de la IP no: un atacante con una cuenta válida podría reiniciarlo.
+func (s *Service) Succeeded(ctx context.Context, email string) {
+	if _, err := s.repo.Delete(ctx, KindAccount, accountKey(email)); err != nil {
+		log.Printf("⚠️  Error reiniciando intentos de login: %v", err)
+	}
+}
+
+// UnlockUser (admin) quita el bloqueo y los fallos acumulados de la cuenta.
+func (s *Service) UnlockUser(ctx context.Context, actorID, userID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	user, err := s.users.GetByID(ctx, uid)
+	if err != nil {
+		return err
+	}
+
+	key := accountKey(user.Email)
+	if _, err := s.repo.Delete(ctx, KindAccount, key); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventAccountUnlocked,
+		TenantID: user.TenantID.String(),
+		UserID:   userID,
+		Metadata: map[string]any{"actor_id": actorID, "email": key},
+	})
+	return nil
+}
+
+// UnlockIP (admin) quita el bloqueo y los fallos acumulados de la IP.
+func (s *Service) UnlockIP(ctx context.Context, actorID, ip string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return ErrInvalidIP
+	}
+
+	if _, err := s.repo.Delete(ctx, KindIP, parsed.String()); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventIPUnlocked,
+		ClientIP: parsed.String(),
+		Metadata: map[string]any{"actor_id": actorID, "ip": parsed.String()},
+	})
+	return nil
+}
+
+// ListLocked (admin) devuelve las cuentas e IPs bloqueadas ahora.
+func (s *Service) ListLocked(ctx context.Context) ([]Lock, error) {
+	rows, err := s.repo.ListLocked(ctx, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	locks := make([]Lock, 0, len(rows))
+	for _, t := range rows {
+		locks = append(locks, Lock{
+			Kind:        t.Kind,
+			Subject:     t.Subject,
+			LockedUntil: t.LockedUntil.Time,
+		})
+	}
+	return locks, nil
+}
+
+// PurgeStale elimina los contadores que ya no cuentan para ningún umbral; la
+// ejecuta el scheduler.
+func (s *Service) PurgeStale(ctx context.Context) (int64, error) {
+	return s.repo.DeleteStale(ctx, time.Now().Add(-maxWindow))
+}
+
+func (s *Service) get(ctx context.Context, kind, subject string) (*dbgen.LoginThrottle, error) {
+	t, err := s.repo.Get(ctx, kind, subject)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return t, err
+}
+
+// policyFor devuelve los umbrales del tenant del email, o los de por defecto
+// si no tiene cuenta.
+func (s *Service) policyFor(ctx context.Context, email string) Policy {
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err != nil {
+		return s.defaultPolicy()
+	}
+	return s.policy(ctx, user.TenantID)
+}
+
+func (s *Service) policy(ctx context.Context, tenantID uuid.UUID) Policy {
+	cfg, err := s.tenants.GetConfig(ctx, tenantID)
+	if err != nil {
+		return s.defaultPolicy()
+	}
+	return Policy{
+		MaxFailures:     cfg.Int(tenantMaxFailuresKey, defaultMaxFailures),
+		LockoutDuration: cfg.Duration(tenantLockoutDurationKey, defaultLockoutDuration),
+		Window:          min(cfg.Duration(tenantWindowKey, defaultWindow), maxWindow),
+		DelayAfter:      cfg.Int(tenantDelayAfterKey, defaultDelayAfter),
+		MaxDelay:        cfg.Duration(tenantMaxDelayKey, defaultMaxDelay),
+	}
+}
+
+func (s *Service) defaultPolicy() Policy {
+	return Policy{
+		MaxFailures:     defaultMaxFailures,
+		LockoutDuration: defaultLockoutDuration,
+		Window:          defaultWindow,
+		DelayAfter:      defaultDelayAfter,
+		MaxDelay:        defaultMaxDelay,
+	}
+}
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
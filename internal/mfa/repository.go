@@ -0,0 +1,143 @@
+package mfa
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+// SavePendingTOTP guarda (o reemplaza) un secreto sin confirmar. Devuelve
+// false si el usuario ya tiene TOTP confirmado.
+func (r *Repository) SavePendingTOTP(ctx context.Context, userID uuid.UUID, sealedSecret string) (bool, error) {
+	n, err := r.q.UpsertPendingTOTP(ctx, gen.UpsertPendingTOTPParams{
+		UserID:    userID,
+		Secret:    sealedSecret,
+		CreatedAt: time.Now(),
+	})
+	return n == 1, err
+}
+
+func (r *Repository) GetTOTP(ctx context.Context, userID uuid.UUID) (*gen.MfaTotp, error) {
+	totp, err := r.q.GetTOTP(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &totp, nil
+}
+
+// ConfirmTOTP activa el secreto pendiente. Devuelve false si ya estaba confirmado.
+func (r *Repository) ConfirmTOTP(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	n, err := r.q.ConfirmTOTP(ctx, gen.ConfirmTOTPParams{
+		UserID:       userID,
+		LastUsedStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+	return n == 1, err
+}
+
+// UseTOTPStep registra el paso usado. Devuelve false si ese paso (o uno
+// posterior) ya se había usado: el código se está reutilizando.
+func (r *Repository) UseTOTPStep(ctx context.Context, userID uuid.UUID, step int64) (bool, error) {
+	n, err := r.q.UseTOTPStep(ctx, gen.UseTOTPStepParams{
+		UserID:       userID,
+		LastUsedStep: sql.NullInt64{Int64: step, Valid: true},
+	})
+	return n == 1, err
+}
+
+func (r *Repository) DeleteTOTP(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.DeleteTOTP(ctx, userID)
+}
+
+// ReplaceRecoveryCodes elimina los códigos anteriores del usuario y guarda los nuevos hashes.
+func (r *Repository) ReplaceRecoveryCodes(ctx context.Context, userID uuid.UUID, hashes []string) error {
+	if _, err := r.q.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	for _, h := range hashes {
+		err := r.q.CreateRecoveryCode(ctx, gen.CreateRecoveryCodeParams{
+			ID:        uuid.New(),
+			UserID:    userID,
+			CodeHash:  h,
+			CreatedAt: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// UseRecoveryCode marca el código como usado. Devuelve false si no existe o ya se usó.
+func (r *Repository) UseRecoveryCode(ctx context.Context, userID uuid.UUID, codeHash string) (bool, error) {
+	n, err := r.q.UseRecoveryCode(ctx, gen.UseRecoveryCodeParams{
+		UserID:   userID,
+		CodeHash: codeHash,
+	})
+	return n == 1, err
+}
+
+func (r *Repository) CountUnusedRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.CountUnusedRecoveryCodes(ctx, userID)
+}
+
+func (r *Repository) DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.DeleteRecoveryCodes(ctx, userID)
+}
+
+// CreateChallenge guarda un desafío MFA. tenantID uuid.Nil = tenant System.
+// enrollmentRequired marca los desafíos que solo sirven para enrolarse.
+func (r *Repository) CreateChallenge(ctx context.Context, tokenHash string, userID, tenantID uuid.UUID, enrollmentRequired bool, expiresAt time.Time) error {
+	return r.q.CreateMFAChallenge(ctx, gen.CreateMFAChallengeParams{
+		ID:                 uuid.New(),
+		TokenHash:          tokenHash,
+		UserID:             userID,
+		TenantID:           uuid.NullUUID{UUID: tenantID, Valid: tenantID != uuid.Nil},
+		ExpiresAt:          expiresAt,
+		CreatedAt:          time.Now(),
+		EnrollmentRequired: enrollmentRequired,
+	})
+}
+
+// GetActiveChallenge devuelve el desafío vigente (sin consumir ni expirar).
+func (r *Repository) GetActiveChallenge(ctx context.Context, tokenHash string) (*gen.MfaChallenge, error) {
+	c, err := r.q.GetActiveMFAChallenge(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// IncrementChallengeAttempts registra un intento. Devuelve sql.ErrNoRows si
+// el desafío ya agotó maxAttempts o se consumió.
+func (r *Repository) IncrementChallengeAttempts(ctx context.Context, id uuid.UUID, maxAttempts int32) (int32, error) {
+	return r.q.IncrementMFAChallengeAttempts(ctx, gen.IncrementMFAChallengeAttemptsParams{
+		ID:       id,
+		Attempts: maxAttempts,
+	})
+}
+
+// ConsumeChallenge marca el desafío como usado. Devuelve false si otro request lo consumió antes.
+func (r *Repository) ConsumeChallenge(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := r.q.ConsumeMFAChallenge(ctx, id)
+	return n == 1, err
+}
+
+func (r *Repository) DeleteChallengesByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.DeleteMFAChallengesByUser(ctx, userID)
+}
+
+// DeleteExpiredChallenges elimina los desafíos que expiraron antes de before.
+func (r *Repository) DeleteExpiredChallenges(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteExpiredMFAChallenges(ctx, before)
+}
@@ -0,0 +1,698 @@
+package mfa
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/audit"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/secrets"
+	"github.com/fzalvarez/odin-iam/internal/tenants"
+	"github.com/google/uuid"
+)
+
+// Métodos de segundo factor que acepta un desafío.
+const (
+	MethodTOTP         = "totp"
+	MethodRecoveryCode = "recovery_code"
+	MethodPasskey      = "passkey"
+)
+
+const (
+	challengeTTL         = 5 * time.Minute
+	challengeMaxAttempts = 5
+	recoveryCodeCount    = 10
+	// El tenant exige MFA a todos sus usuarios con {"mfa_required": true}
+	tenantMFARequiredKey = "mfa_required"
+)
+
+var (
+	ErrInvalidChallenge  = errors.New("mfa token is invalid or expired")
+	ErrAlreadyEnrolled   = errors.New("totp is already enabled")
+	ErrEnrollmentMissing = errors.New("totp enrollment has not been started")
+	ErrNotEnrolled       = errors.New("totp is not enabled")
+	// ErrEnrollmentNotAllowed: el token no se emitió para enrolarse (el usuario
+	// ya tenía MFA o su tenant no lo exige).
+	ErrEnrollmentNotAllowed = errors.New("mfa token does not allow enrollment")
+)
+
+type UsersRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*dbgen.User, error)
+}
+
+// Passkeys lo implementa webauthn.Service: una passkey registrada cuenta como
+// segundo factor.
+type Passkeys interface {
+	HasPasskeys(ctx context.Context, userID uuid.UUID) (bool, error)
+	DeleteUserPasskeys(ctx context.Context, userID uuid.UUID) (int64, error)
+}
+
+type Service struct {
+	repo     *Repository
+	auth     *auth.AuthService
+	users    UsersRepository
+	tenants  *tenants.Service
+	audit    *audit.Service
+	passkeys Passkeys
+}
+
+func NewService(
+	repo *Repository,
+	authService *auth.AuthService,
+	users UsersRepository,
+	tenantService *tenants.Service,
+	auditService *audit.Service,
+) *Service {
+	return &Service{
+		repo:    repo,
+		auth:    authService,
+		users:   users,
+		tenants: tenantService,
+		audit:   auditService,
+	}
+}
+
+// UsePasskeys instala el registro de passkeys como segundo factor.
+func (s *Service) UsePasskeys(p Passkeys) {
+	s.passkeys = p
+}
+
+// Enrollment es el secreto TOTP recién generado. Se muestra una sola vez: la
+// app lo lee de la URI (normalmente como código QR).
+type Enrollment struct {
+	Secret     string `json:"secret"`
+	OtpauthURI string `json:"otpauth_uri"`
+}
+
+// Status resume el MFA de un usuario.
+type Status struct {
+	TOTPEnabled            bool  `json:"totp_enabled"`
+	PasskeysEnabled        bool  `json:"passkeys_enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+	Required               bool  `json:"required"`
+}
+
+// ----------------------------------------------
+// auth.SecondFactor
+// ----------------------------------------------
+
+func (s *Service) Status(ctx context.Context, user *dbgen.User, tenantID uuid.UUID) (enrolled, required bool, err error) {
+	methods, err := s.methods(ctx, user.ID)
+	if err != nil {
+		return false, false, err
+	}
+	required = s.tenantRequiresMFA(ctx, user.TenantID) ||
+		(tenantID != user.TenantID && s.tenantRequiresMFA(ctx, tenantID))
+	return len(methods) > 0, required, nil
+}
+
+func (s *Service) Challenge(ctx context.Context, user *dbgen.User, tenantID uuid.UUID, enrolled bool) (*auth.MFAChallenge, error) {
+	token, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.CreateChallenge(ctx, hashToken(token), user.ID, tenantID, !enrolled, time.Now().UTC().Add(challengeTTL)); err != nil {
+		return nil, err
+	}
+
+	challenge := &auth.MFAChallenge{
+		Token:     token,
+		Methods:   []string{},
+		ExpiresIn: int(challengeTTL.Seconds()),
+	}
+	if enrolled {
+		if challenge.Methods, err = s.methods(ctx, user.ID); err != nil {
+			return nil, err
+		}
+	} else {
+		// El tenant exige MFA y el usuario no lo tiene: el token solo sirve
+		// para enrolarse (/auth/mfa/enroll)
+		challenge.EnrollmentRequired = true
+	}
+	return challenge, nil
+}
+
+// VerifyCode acepta un código TOTP (6 dígitos) o un código de recuperación.
+func (s *Service) VerifyCode(ctx context.Context, userID uuid.UUID, code string) error {
+	code = strings.TrimSpace(code)
+	if isTOTPCode(code) {
+		return s.verifyTOTP(ctx, userID, code)
+	}
+	return s.useRecoveryCode(ctx, userID, code)
+}
+
+// ----------------------------------------------
+// LOGIN EN DOS PASOS
+// ----------------------------------------------
+
+// VerifyChallenge canjea el token del paso de contraseña y un segundo factor
+// por el mismo resultado que Login.
+func (s *Service) VerifyChallenge(ctx context.Context, token, code string, client auth.ClientInfo) (*auth.LoginResult, error) {
+	return s.VerifyChallengeWith(ctx, token, func(ctx context.Context, userID uuid.UUID) error {
+		return s.VerifyCode(ctx, userID, code)
+	}, client)
+}
+
+// VerifyChallengeWith completa el desafío con un segundo factor verificado
+// por verify (p. ej. una passkey). Cada llamada cuenta como un intento.
+func (s *Service) VerifyChallengeWith(ctx context.Context, token string, verify func(ctx context.Context, userID uuid.UUID) error, client auth.ClientInfo) (*auth.LoginResult, error) {
+	challenge, err := s.attemptChallenge(ctx, token)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := verify(ctx, challenge.UserID); err != nil {
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			s.secondFactorFailed(ctx, challenge.UserID, client)
+		}
+		return nil, err
+	}
+
+	return s.completeChallenge(ctx, challenge, client)
+}
+
+// ChallengeUserID devuelve el usuario de un desafío vigente sin contar un intento.
+func (s *Service) ChallengeUserID(ctx context.Context, token string) (uuid.UUID, error) {
+	challenge, err := s.repo.GetActiveChallenge(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return uuid.Nil, ErrInvalidChallenge
+		}
+		return uuid.Nil, err
+	}
+	return challenge.UserID, nil
+}
+
+// EnrollWithChallenge inicia el enrolamiento TOTP de un usuario al que su
+// tenant exige MFA, usando el token del paso de contraseña. Solo lo aceptan
+// los desafíos emitidos con EnrollmentRequired.
+func (s *Service) EnrollWithChallenge(ctx context.Context, token string) (*Enrollment, error) {
+	challenge, err := s.repo.GetActiveChallenge(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	if !challenge.EnrollmentRequired {
+		return nil, ErrEnrollmentNotAllowed
+	}
+	return s.StartEnrollment(ctx, challenge.UserID)
+}
+
+// ConfirmWithChallenge confirma el enrolamiento iniciado con
+// EnrollWithChallenge y completa el login. Devuelve los códigos de recuperación.
+func (s *Service) ConfirmWithChallenge(ctx context.Context, token, code string, client auth.ClientInfo) (*auth.LoginResult, []string, error) {
+	challenge, err := s.attemptChallenge(ctx, token)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !challenge.EnrollmentRequired {
+		return nil, nil, ErrEnrollmentNotAllowed
+	}
+
+	// Si entretanto el usuario configuró otro factor (p. ej. una passkey), el
+	// login debe pasar por él y no por un enrolamiento nuevo
+	methods, err := s.methods(ctx, challenge.UserID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(methods) > 0 {
+		return nil, nil, ErrAlreadyEnrolled
+	}
+
+	codes, err := s.ConfirmEnrollment(ctx, challenge.UserID, code)
+	if err != nil {
+		if errors.Is(err, auth.ErrInvalidMFACode) {
+			s.secondFactorFailed(ctx, challenge.UserID, client)
+		}
+		return nil, nil, err
+	}
+
+	res, err := s.completeChallenge(ctx, challenge, client)
+	if err != nil {
+		return nil, nil, err
+	}
+	return res, codes, nil
+}
+
+// attemptChallenge busca el desafío y cuenta el intento antes de comprobar el
+// código: dos requests en paralelo no pueden superar el máximo.
+func (s *Service) attemptChallenge(ctx context.Context, token string) (*dbgen.MfaChallenge, error) {
+	if token == "" {
+		return nil, ErrInvalidChallenge
+	}
+
+	challenge, err := s.repo.GetActiveChallenge(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+
+	if _, err := s.repo.IncrementChallengeAttempts(ctx, challenge.ID, challengeMaxAttempts); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidChallenge
+		}
+		return nil, err
+	}
+	return challenge, nil
+}
+
+func (s *Service) completeChallenge(ctx context.Context, challenge *dbgen.MfaChallenge, client auth.ClientInfo) (*auth.LoginResult, error) {
+	consumed, err := s.repo.ConsumeChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, err
+	}
+	if !consumed {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.users.GetByID(ctx, challenge.UserID)
+	if err != nil || !user.IsActive {
+		return nil, ErrInvalidChallenge
+	}
+
+	res, err := s.auth.IssueTokens(ctx, user.ID, challenge.TenantID.UUID, client)
+	if err != nil {
+		return nil, err
+	}
+	s.auth.LoginSucceeded(ctx, user.Email)
+	return res, nil
+}
+
+// secondFactorFailed cuenta un código incorrecto para el bloqueo de la cuenta:
+// el límite de intentos del desafío se reinicia con cada contraseña correcta.
+func (s *Service) secondFactorFailed(ctx context.Context, userID uuid.UUID, client auth.ClientInfo) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return
+	}
+	s.auth.SecondFactorFailed(ctx, user, client)
+}
+
+// ----------------------------------------------
+// ENROLAMIENTO
+// ----------------------------------------------
+
+// StartEnrollment genera un secreto TOTP pendiente de confirmar. Repetirlo
+// antes de confirmar reemplaza el secreto anterior.
+func (s *Service) StartEnrollment(ctx context.Context, userID uuid.UUID) (*Enrollment, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := newTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := secrets.Seal([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.SavePendingTOTP(ctx, user.ID, sealed)
+	if err != nil {
+		return nil, err
+	}
+	if !saved {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	return &Enrollment{
+		Secret:     secret,
+		OtpauthURI: otpauthURI(Issuer(), user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment activa TOTP con un primer código válido y genera los
+// códigos de recuperación, que solo se devuelven aquí.
+func (s *Service) ConfirmEnrollment(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrEnrollmentMissing
+		}
+		return nil, err
+	}
+	if totp.ConfirmedAt.Valid {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	secret, err := secrets.Open(totp.Secret)
+	if err != nil {
+		return nil, err
+	}
+	step, ok := validateTOTP(string(secret), strings.TrimSpace(code), time.Now())
+	if !ok {
+		return nil, auth.ErrInvalidMFACode
+	}
+
+	confirmed, err := s.repo.ConfirmTOTP(ctx, userID, step)
+	if err != nil {
+		return nil, err
+	}
+	if !confirmed {
+		return nil, ErrAlreadyEnrolled
+	}
+
+	codes, err := s.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:   audit.EventMFAEnabled,
+		UserID: userID.String(),
+		Metadata: map[string]any{
+			"method": MethodTOTP,
+		},
+	})
+	return codes, nil
+}
+
+// RegenerateRecoveryCodes invalida los códigos de recuperación anteriores y
+// devuelve otros nuevos. Exige un código válido del usuario.
+func (s *Service) RegenerateRecoveryCodes(ctx context.Context, userID uuid.UUID, code string) ([]string, error) {
+	methods, err := s.methods(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(methods) == 0 {
+		return nil, ErrNotEnrolled
+	}
+	if err := s.VerifyCode(ctx, userID, code); err != nil {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// EnsureRecoveryCodes genera códigos de recuperación si el usuario no tiene
+// ninguno sin usar (primera passkey sin TOTP). Devuelve nil si ya tenía.
+func (s *Service) EnsureRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	n, err := s.repo.CountUnusedRecoveryCodes(ctx, userID)
+	if err != nil || n > 0 {
+		return nil, err
+	}
+	return s.replaceRecoveryCodes(ctx, userID)
+}
+
+// Disable desactiva el TOTP del propio usuario. Exige un código válido. Los
+// códigos de recuperación se conservan si quedan passkeys registradas.
+func (s *Service) Disable(ctx context.Context, userID uuid.UUID, code string) error {
+	enabled, err := s.totpEnabled(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return ErrNotEnrolled
+	}
+	if err := s.VerifyCode(ctx, userID, code); err != nil {
+		return err
+	}
+
+	if _, err := s.repo.DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+	hasPasskeys, err := s.hasPasskeys(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !hasPasskeys {
+		if _, err := s.repo.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:   audit.EventMFADisabled,
+		UserID: userID.String(),
+	})
+	return nil
+}
+
+// Reset (admin) elimina el MFA del usuario (TOTP, passkeys y códigos de
+// recuperación), por ejemplo si perdió el dispositivo. Vuelve a enrolarse al iniciar sesión.
+func (s *Service) Reset(ctx context.Context, actorID, userID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return err
+	}
+	if _, err := s.users.GetByID(ctx, uid); err != nil {
+		return err
+	}
+	if err := s.removeFactors(ctx, uid); err != nil {
+		return err
+	}
+	if s.passkeys != nil {
+		if _, err := s.passkeys.DeleteUserPasskeys(ctx, uid); err != nil {
+			return err
+		}
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventMFAReset,
+		UserID:   userID,
+		Metadata: map[string]any{"actor_id": actorID},
+	})
+	return nil
+}
+
+// GetStatus devuelve el estado MFA del usuario.
+func (s *Service) GetStatus(ctx context.Context, userID uuid.UUID) (*Status, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.totpEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	hasPasskeys, err := s.hasPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var remaining int64
+	if enabled || hasPasskeys {
+		if remaining, err = s.repo.CountUnusedRecoveryCodes(ctx, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Status{
+		TOTPEnabled:            enabled,
+		PasskeysEnabled:        hasPasskeys,
+		RecoveryCodesRemaining: remaining,
+		Required:               s.tenantRequiresMFA(ctx, user.TenantID),
+	}, nil
+}
+
+// PurgeExpired elimina los desafíos expirados; la ejecuta el scheduler.
+func (s *Service) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredChallenges(ctx, time.Now())
+}
+
+// ----------------------------------------------
+// HELPERS
+// ----------------------------------------------
+
+func (s *Service) totpEnabled(ctx context.Context, userID uuid.UUID) (bool, error) {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return totp.ConfirmedAt.Valid, nil
+}
+
+// methods lista los segundos factores configurados por el usuario. Vacío = sin MFA.
+func (s *Service) methods(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	methods := []string{}
+
+	totp, err := s.totpEnabled(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if totp {
+		methods = append(methods, MethodTOTP)
+	}
+
+	passkeys, err := s.hasPasskeys(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if passkeys {
+		methods = append(methods, MethodPasskey)
+	}
+
+	if len(methods) > 0 {
+		methods = append(methods, MethodRecoveryCode)
+	}
+	return methods, nil
+}
+
+func (s *Service) hasPasskeys(ctx context.Context, userID uuid.UUID) (bool, error) {
+	if s.passkeys == nil {
+		return false, nil
+	}
+	return s.passkeys.HasPasskeys(ctx, userID)
+}
+
+func (s *Service) tenantRequiresMFA(ctx context.Context, tenantID uuid.UUID) bool {
+	cfg, err := s.tenants.GetConfig(ctx, tenantID)
+	if err != nil {
+		return false
+	}
+	return cfg.Bool(tenantMFARequiredKey, false)
+}
+
+func (s *Service) verifyTOTP(ctx context.Context, userID uuid.UUID, code string) error {
+	totp, err := s.repo.GetTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return auth.ErrInvalidMFACode
+		}
+		return err
+	}
+	if !totp.ConfirmedAt.Valid {
+		return auth.ErrInvalidMFACode
+	}
+
+	secret, err := secrets.Open(totp.Secret)
+	if err != nil {
+		return err
+	}
+	step, ok := validateTOTP(string(secret), code, time.Now())
+	if !ok {
+		return auth.ErrInvalidMFACode
+	}
+
+	// Un código ya aceptado no vuelve a servir aunque siga en su ventana
+	used, err := s.repo.UseTOTPStep(ctx, userID, step)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return auth.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *Service) useRecoveryCode(ctx context.Context, userID uuid.UUID, code string) error {
+	normalized := normalizeRecoveryCode(code)
+	if normalized == "" {
+		return auth.ErrInvalidMFACode
+	}
+
+	hash, err := hashRecoveryCode(userID, normalized)
+	if err != nil {
+		return err
+	}
+	used, err := s.repo.UseRecoveryCode(ctx, userID, hash)
+	if err != nil {
+		return err
+	}
+	if !used {
+		return auth.ErrInvalidMFACode
+	}
+	return nil
+}
+
+func (s *Service) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := newRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		hash, err := hashRecoveryCode(userID, normalizeRecoveryCode(code))
+		if err != nil {
+			return nil, err
+		}
+		codes[i], hashes[i] = code, hash
+	}
+
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *Service) removeFactors(ctx context.Context, userID uuid.UUID) error {
+	if _, err := s.repo.DeleteTOTP(ctx, userID); err != nil {
+		return err
+	}
+	if _, err := s.repo.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return err
+	}
+	_, err := s.repo.DeleteChallengesByUser(ctx, userID)
+	return err
+}
+
+func isTOTPCode(code string) bool {
+	if len(code) != totpDigits {
+		return false
+	}
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// newRecoveryCode genera un código de 10 caracteres base32 (50 bits) con
+// formato xxxxx-xxxxx.
+func newRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(b32.EncodeToString(b))[:10]
+	return code[:5] + "-" + code[5:], nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// hashRecoveryCode usa HMAC con una subllave de SECRETS_KEY, como los códigos OTP.
+func hashRecoveryCode(userID uuid.UUID, code string) (string, error) {
+	key, err := secrets.DeriveKey("mfa-recovery-codes")
+	if err != nil {
+		return "", err
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(userID.String() + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,94 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Canales de entrega.
+const (
+	ChannelEmail    = "email"
+	ChannelSMS      = "sms"
+	ChannelWhatsApp = "whatsapp"
+)
+
+var ErrUnsupportedChannel = errors.New("delivery channel is not supported")
+
+// Message es una notificación para un usuario (link de acceso, código, etc.).
+type Message struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Subject string `json:"subject,omitempty"` // solo email
+	Body    string `json:"body"`
+}
+
+// Sender entrega mensajes por un canal concreto (SMTP, proveedor de SMS...).
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// Dispatcher envía cada mensaje con el Sender registrado para su canal.
+// Los proveedores reales (SMTP, SMS, WhatsApp) se añaden con Register.
+type Dispatcher struct {
+	senders map[string]Sender
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{senders: map[string]Sender{}}
+}
+
+// Register asigna el Sender de un canal, reemplazando el anterior.
+func (d *Dispatcher) Register(channel string, s Sender) {
+	d.senders[channel] = s
+}
+
+// Supports indica si hay un Sender para el canal.
+func (d *Dispatcher) Supports(channel string) bool {
+	_, ok := d.senders[channel]
+	return ok
+}
+
+func (d *Dispatcher) Send(ctx context.Context, msg Message) error {
+	s, ok := d.senders[msg.Channel]
+	if !ok {
+		return ErrUnsupportedChannel
+	}
+	return s.Send(ctx, msg)
+}
+
+// DispatcherFromEnv crea el dispatcher de desarrollo según NOTIFY_SENDER:
+// "log" (por defecto) escribe los mensajes en el log y "file" los agrega a
+// NOTIFY_FILE. Todos los canales usan ese mismo Sender.
+func DispatcherFromEnv() (*Dispatcher, error) {
+	var sender Sender
+	switch v := os.Getenv("NOTIFY_SENDER"); v {
+	case "", "log":
+		sender = LogSender{}
+	case "file":
+		path := os.Getenv("NOTIFY_FILE")
+		if path == "" {
+			path = "notifications.log"
+		}
+		sender = NewFileSender(path)
+	default:
+		return nil, fmt.Errorf("unknown NOTIFY_SENDER %q", v)
+	}
+
+	d := NewDispatcher()
+	for _, channel := range []string{ChannelEmail, ChannelSMS, ChannelWhatsApp} {
+		d.Register(channel, sender)
+	}
+	return d, nil
+}
+
+// LogSender escribe los mensajes en el log en lugar de enviarlos. Solo para
+// desarrollo: el cuerpo puede contener credenciales de un solo uso.
+type LogSender struct{}
+
+func (LogSender) Send(ctx context.Context, msg Message) error {
+	log.Printf("✉️  [%s] to=%s subject=%q\n%s", msg.Channel, msg.To, msg.Subject, msg.Body)
+	return nil
+}
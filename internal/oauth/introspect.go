@@ -0,0 +1,171 @@
+package oauth
+
+import (
+	"context"
+	"strings"
+
+	"github.com/fzalvarez/odin-iam/internal/apikeys"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/google/uuid"
+)
+
+// Valores de token_type_hint (RFC 7662 §2.1 / RFC 7009 §2.1). api_key es propio de este IAM.
+const (
+	TokenTypeAccessToken  = "access_token"
+	TokenTypeRefreshToken = "refresh_token"
+	TokenTypeAPIKey       = "api_key"
+)
+
+// ScopeIntrospectAll permite a un cliente o API key del tenant System inspeccionar
+// tokens de cualquier tenant. Es el permiso tokens:introspect_all.
+const ScopeIntrospectAll = "tokens:introspect_all"
+
+// Introspect describe un access token, refresh token o API key (RFC 7662).
+// El llamador es un cliente confidencial o una API key y solo puede inspeccionar
+// tokens de su propio tenant, salvo que sea del tenant System y tenga el scope
+// ScopeIntrospectAll. Cualquier token no válido, expirado o ajeno responde
+// {"active": false}.
+func (s *Service) Introspect(ctx context.Context, callerID, callerSecret, token, hint string) (*IntrospectionResponse, error) {
+	caller, err := s.authenticateMachine(ctx, callerID, callerSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	inactive := &IntrospectionResponse{Active: false}
+	if token == "" {
+		return inactive, nil
+	}
+
+	var res *IntrospectionResponse
+	switch {
+	case strings.HasPrefix(token, apikeys.KeyPrefix):
+		res, err = s.introspectAPIKey(ctx, token)
+	case hint == TokenTypeRefreshToken:
+		res, err = s.introspectRefreshToken(ctx, token)
+		if err == nil && !res.Active {
+			res, err = s.introspectAccessToken(ctx, token)
+		}
+	default:
+		res, err = s.introspectAccessToken(ctx, token)
+		if err == nil && !res.Active {
+			res, err = s.introspectRefreshToken(ctx, token)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !res.Active || !canIntrospect(caller, res.TenantID) {
+		return inactive, nil
+	}
+	return res, nil
+}
+
+func (s *Service) introspectAccessToken(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	claims, err := auth.ValidateAccessToken(token)
+	if err != nil {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	res := &IntrospectionResponse{
+		Active:    true,
+		TokenType: TokenTypeAccessToken,
+		Sub:       claims.Subject,
+		TenantID:  claims.TenantID,
+		ClientID:  claims.ClientID,
+		Scope:     claims.Scope,
+		Iss:       claims.Issuer,
+	}
+	if claims.ExpiresAt != nil {
+		res.Exp = claims.ExpiresAt.Unix()
+	}
+	if claims.IssuedAt != nil {
+		res.Iat = claims.IssuedAt.Unix()
+	}
+
+	if claims.IsClient() {
+		res.Permissions = strings.Fields(claims.Scope)
+		return res, nil
+	}
+
+	return s.withUserPermissions(ctx, res, claims.UserID)
+}
+
+func (s *Service) introspectRefreshToken(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	if err := auth.ValidateRefreshToken(token); err != nil {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	session, err := s.sessions.GetByRefreshToken(ctx, token)
+	if err != nil || !session.IsActive() {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	res := &IntrospectionResponse{
+		Active:    true,
+		TokenType: TokenTypeRefreshToken,
+		Sub:       session.UserID,
+		TenantID:  session.TenantID,
+		Iss:       auth.Issuer(),
+		Exp:       session.ExpiresAt.Unix(),
+		Iat:       session.CreatedAt.Unix(),
+	}
+
+	return s.withUserPermissions(ctx, res, session.UserID)
+}
+
+func (s *Service) introspectAPIKey(ctx context.Context, token string) (*IntrospectionResponse, error) {
+	key, err := s.apikeys.Lookup(ctx, token)
+	if err != nil {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	res := &IntrospectionResponse{
+		Active:      true,
+		TokenType:   TokenTypeAPIKey,
+		Sub:         key.ID,
+		TenantID:    key.TenantID,
+		ClientID:    key.ID,
+		Scope:       strings.Join(key.Scopes, " "),
+		Permissions: key.Scopes,
+		Iss:         auth.Issuer(),
+		Iat:         key.CreatedAt.Unix(),
+	}
+	if key.ExpiresAt != nil {
+		res.Exp = key.ExpiresAt.Unix()
+	}
+	return res, nil
+}
+
+// withUserPermissions completa la respuesta con los permisos del usuario.
+// Un usuario desactivado o eliminado invalida sus tokens.
+func (s *Service) withUserPermissions(ctx context.Context, res *IntrospectionResponse, userID string) (*IntrospectionResponse, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	user, err := s.users.GetByID(ctx, uid)
+	if err != nil || !user.IsActive {
+		return &IntrospectionResponse{Active: false}, nil
+	}
+
+	perms, err := s.permissions.GetUserPermissions(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if perms == nil {
+		perms = []string{}
+	}
+	res.Permissions = perms
+	return res, nil
+}
+
+// canIntrospect: los tokens de otros tenants solo los ve un llamador del tenant
+// System con ScopeIntrospectAll.
+func canIntrospect(caller *machineClient, tokenTenant string) bool {
+	if caller.TenantID == tokenTenant {
+		return true
+	}
+	return caller.TenantID == uuid.Nil.String() && contains(caller.Scopes, ScopeIntrospectAll)
+}
@@ -0,0 +1,95 @@
+package oauth
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// ClientModel representa un cliente OAuth2 / OIDC registrado.
+type ClientModel struct {
+	ID             string    `json:"client_id"`
+	Name           string    `json:"name"`
+	TenantID       string    `json:"tenant_id"`
+	RedirectURIs   []string  `json:"redirect_uris"`
+	GrantTypes     []string  `json:"grant_types"`
+	Scopes         []string  `json:"scopes"`
+	IsConfidential bool      `json:"is_confidential"` // true si tiene client_secret
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+// RegisterClientResult incluye el secreto en claro; solo se devuelve al registrar.
+type RegisterClientResult struct {
+	ClientModel
+	ClientSecret string `json:"client_secret,omitempty"`
+}
+
+// AuthorizeRequest son los parámetros de /oauth/authorize.
+type AuthorizeRequest struct {
+	ResponseType        string
+	ClientID            string
+	RedirectURI         string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// TokenResponse es la respuesta de /oauth/token (RFC 6749 §5.1).
+type TokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// UserInfo es la respuesta de /userinfo.
+type UserInfo struct {
+	Sub      string `json:"sub"`
+	Name     string `json:"name"`
+	Email    string `json:"email"`
+	TenantID string `json:"tenant_id"`
+}
+
+// IntrospectionResponse es la respuesta de /oauth/introspect (RFC 7662 §2.2).
+type IntrospectionResponse struct {
+	Active      bool     `json:"active"`
+	TokenType   string   `json:"token_type,omitempty"`
+	Sub         string   `json:"sub,omitempty"`
+	TenantID    string   `json:"tenant_id,omitempty"`
+	ClientID    string   `json:"client_id,omitempty"`
+	Scope       string   `json:"scope,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+	Iss         string   `json:"iss,omitempty"`
+	Exp         int64    `json:"exp,omitempty"`
+	Iat         int64    `json:"iat,omitempty"`
+}
+
+// Error es un error OAuth2 con su código estándar (RFC 6749 §4.1.2.1 y §5.2).
+type Error struct {
+	Code        string
+	Description string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Description)
+}
+
+// Status devuelve el código HTTP que corresponde al error en /oauth/token.
+func (e *Error) Status() int {
+	switch e.Code {
+	case "invalid_client":
+		return http.StatusUnauthorized
+	case "server_error":
+		return http.StatusInternalServerError
+	default:
+		return http.StatusBadRequest
+	}
+}
+
+func newError(code, description string) *Error {
+	return &Error{Code: code, Description: description}
+}
@@ -0,0 +1,27 @@
+package oauth
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+)
+
+// Solo se acepta S256: "plain" no protege si el código se intercepta.
+const pkceMethodS256 = "S256"
+
+// verifyPKCE comprueba que el code_verifier corresponde al code_challenge (RFC 7636 §4.6).
+func verifyPKCE(verifier, challenge, method string) bool {
+	if method != pkceMethodS256 {
+		return false
+	}
+
+	// RFC 7636 §4.1: 43-128 caracteres
+	if len(verifier) < 43 || len(verifier) > 128 {
+		return false
+	}
+
+	sum := sha256.Sum256([]byte(verifier))
+	computed := base64.RawURLEncoding.EncodeToString(sum[:])
+
+	return subtle.ConstantTimeCompare([]byte(computed), []byte(challenge)) == 1
+}
@@ -0,0 +1,81 @@
+package oauth
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"strings"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+// CreateClient registra un cliente. secretHash vacío = cliente público.
+func (r *Repository) CreateClient(ctx context.Context, name string, tenantID uuid.UUID, secretHash string, redirectURIs, grantTypes, scopes []string) (*gen.OauthClient, error) {
+	uris, err := json.Marshal(redirectURIs)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := r.q.CreateOAuthClient(ctx, gen.CreateOAuthClientParams{
+		ID:           uuid.New(),
+		SecretHash:   sql.NullString{String: secretHash, Valid: secretHash != ""},
+		Name:         name,
+		TenantID:     tenantID,
+		RedirectUris: uris,
+		GrantTypes:   strings.Join(grantTypes, " "),
+		Scopes:       strings.Join(scopes, " "),
+		CreatedAt:    time.Now(),
+		UpdatedAt:    time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *Repository) GetClientByID(ctx context.Context, id uuid.UUID) (*gen.OauthClient, error) {
+	client, err := r.q.GetOAuthClientByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *Repository) ListClients(ctx context.Context) ([]gen.OauthClient, error) {
+	return r.q.ListOAuthClients(ctx)
+}
+
+func (r *Repository) DeleteClient(ctx context.Context, id uuid.UUID) error {
+	return r.q.DeleteOAuthClient(ctx, id)
+}
+
+// CreateAuthorizationCode guarda el hash del código junto con el contexto de la autorización.
+func (r *Repository) CreateAuthorizationCode(ctx context.Context, params gen.CreateAuthorizationCodeParams) error {
+	params.CreatedAt = time.Now()
+	return r.q.CreateAuthorizationCode(ctx, params)
+}
+
+// DeleteExpiredAuthorizationCodes elimina los códigos expirados (usados o no).
+func (r *Repository) DeleteExpiredAuthorizationCodes(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredAuthorizationCodes(ctx)
+}
+
+// ConsumeAuthorizationCode marca el código como usado de forma atómica.
+// Devuelve sql.ErrNoRows si no existe, ya se usó o expiró.
+func (r *Repository) ConsumeAuthorizationCode(ctx context.Context, codeHash string) (*gen.OauthAuthorizationCode, error) {
+	code, err := r.q.ConsumeAuthorizationCode(ctx, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
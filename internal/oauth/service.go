@@ -0,0 +1,651 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/apikeys"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/sessions"
+	"github.com/google/uuid"
+)
+
+const (
+	authorizationCodeTTL = 5 * time.Minute
+	idTokenTTL           = time.Hour
+
+	GrantAuthorizationCode = "authorization_code"
+	GrantRefreshToken      = "refresh_token"
+	GrantClientCredentials = "client_credentials"
+
+	ScopeOpenID  = "openid"
+	ScopeProfile = "profile"
+	ScopeEmail   = "email"
+)
+
+// Errores que impiden redirigir al cliente: se muestran al usuario (RFC 6749 §4.1.2.1).
+var (
+	ErrUnknownClient      = errors.New("unknown client_id")
+	ErrInvalidRedirectURI = errors.New("redirect_uri is not registered for this client")
+)
+
+// Errores al registrar un cliente con más alcance que quien lo registra.
+var (
+	ErrScopeNotAllowed  = errors.New("scope not allowed")
+	ErrTenantNotAllowed = errors.New("only System tenant administrators can register clients for other tenants")
+)
+
+var errInvalidClientSecret = newError("invalid_client", "client authentication failed")
+
+var (
+	SupportedScopes     = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+	SupportedGrantTypes = []string{GrantAuthorizationCode, GrantRefreshToken, GrantClientCredentials}
+	defaultClientScopes = []string{ScopeOpenID, ScopeProfile, ScopeEmail}
+	defaultClientGrants = []string{GrantAuthorizationCode, GrantRefreshToken}
+)
+
+type UsersRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*dbgen.User, error)
+}
+
+type SessionsRepository interface {
+	GetByRefreshToken(ctx context.Context, refreshToken string) (*sessions.SessionModel, error)
+}
+
+type PermissionsService interface {
+	GetUserPermissions(ctx context.Context, userID string) ([]string, error)
+}
+
+type Service struct {
+	repo        *Repository
+	auth        *auth.AuthService
+	users       UsersRepository
+	apikeys     *apikeys.Service
+	sessions    SessionsRepository
+	permissions PermissionsService
+}
+
+func NewService(
+	repo *Repository,
+	authService *auth.AuthService,
+	users UsersRepository,
+	apikeysService *apikeys.Service,
+	sessions SessionsRepository,
+	permissions PermissionsService,
+) *Service {
+	return &Service{
+		repo:        repo,
+		auth:        authService,
+		users:       users,
+		apikeys:     apikeysService,
+		sessions:    sessions,
+		permissions: permissions,
+	}
+}
+
+// ----------------------------------------------
+// CLIENTES
+// ----------------------------------------------
+
+// Registrar es quien registra un cliente: el tenant de su access token y los
+// permisos que tiene en él.
+type Registrar struct {
+	TenantID    string
+	Permissions []string
+}
+
+// RegisterClient registra un cliente. Los confidenciales reciben un client_secret
+// que solo se devuelve en esta llamada; los públicos (SPA, móvil) deben usar PKCE.
+// Sin tenantID el cliente es del tenant de by; solo el tenant System registra
+// clientes de otros tenants. Los scopes que no son de OIDC deben ser permisos de by.
+func (s *Service) RegisterClient(ctx context.Context, by Registrar, name, tenantID string, redirectURIs, grantTypes, scopes []string, confidential bool) (*RegisterClientResult, error) {
+	if strings.TrimSpace(name) == "" {
+		return nil, errors.New("client name cannot be empty")
+	}
+
+	if tenantID == "" {
+		tenantID = by.TenantID
+	}
+	tid, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, errors.New("invalid tenant id")
+	}
+	if tid.String() != by.TenantID && by.TenantID != uuid.Nil.String() {
+		return nil, ErrTenantNotAllowed
+	}
+
+	for _, uri := range redirectURIs {
+		u, err := url.Parse(uri)
+		if err != nil || u.Scheme == "" || u.Host == "" || u.Fragment != "" {
+			return nil, errors.New("invalid redirect_uri: " + uri)
+		}
+	}
+
+	if len(grantTypes) == 0 {
+		grantTypes = defaultClientGrants
+	}
+	for _, g := range grantTypes {
+		if !contains(SupportedGrantTypes, g) {
+			return nil, errors.New("unsupported grant_type: " + g)
+		}
+	}
+	if contains(grantTypes, GrantAuthorizationCode) && len(redirectURIs) == 0 {
+		return nil, errors.New("authorization_code clients need at least one redirect_uri")
+	}
+	if contains(grantTypes, GrantClientCredentials) && !confidential {
+		return nil, errors.New("client_credentials requires a confidential client")
+	}
+
+	// Para clientes de máquina los scopes son códigos de permiso (ej. users:list)
+	if len(scopes) == 0 && contains(grantTypes, GrantAuthorizationCode) {
+		scopes = defaultClientScopes
+	}
+	for _, scope := range scopes {
+		if !contains(SupportedScopes, scope) && !contains(by.Permissions, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrScopeNotAllowed, scope)
+		}
+	}
+
+	var secret, secretHash string
+	if confidential {
+		secret, err = randomToken(32)
+		if err != nil {
+			return nil, err
+		}
+		secret = "cs_" + secret
+		secretHash = hashSecret(secret)
+	}
+
+	client, err := s.repo.CreateClient(ctx, name, tid, secretHash, redirectURIs, grantTypes, scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RegisterClientResult{
+		ClientModel:  toClientModel(client),
+		ClientSecret: secret,
+	}, nil
+}
+
+func (s *Service) GetClient(ctx context.Context, clientID string) (*ClientModel, error) {
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, ErrUnknownClient
+	}
+
+	client, err := s.repo.GetClientByID(ctx, id)
+	if err != nil {
+		return nil, ErrUnknownClient
+	}
+
+	m := toClientModel(client)
+	return &m, nil
+}
+
+func (s *Service) ListClients(ctx context.Context) ([]ClientModel, error) {
+	list, err := s.repo.ListClients(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]ClientModel, 0, len(list))
+	for i := range list {
+		out = append(out, toClientModel(&list[i]))
+	}
+	return out, nil
+}
+
+func (s *Service) DeleteClient(ctx context.Context, clientID string) error {
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return err
+	}
+	return s.repo.DeleteClient(ctx, id)
+}
+
+// AuthenticateClient valida las credenciales del cliente en /oauth/token.
+// Los clientes públicos solo envían client_id; los confidenciales deben enviar su secreto.
+func (s *Service) AuthenticateClient(ctx context.Context, clientID, clientSecret string) (*ClientModel, error) {
+	id, err := uuid.Parse(clientID)
+	if err != nil {
+		return nil, errInvalidClientSecret
+	}
+
+	client, err := s.repo.GetClientByID(ctx, id)
+	if err != nil {
+		return nil, errInvalidClientSecret
+	}
+
+	if client.SecretHash.Valid {
+		given := hashSecret(clientSecret)
+		if subtle.ConstantTimeCompare([]byte(given), []byte(client.SecretHash.String)) != 1 {
+			return nil, errInvalidClientSecret
+		}
+	} else if clientSecret != "" {
+		return nil, errInvalidClientSecret
+	}
+
+	m := toClientModel(client)
+	return &m, nil
+}
+
+// ----------------------------------------------
+// AUTHORIZATION CODE + PKCE
+// ----------------------------------------------
+
+// ValidateAuthorizeRequest valida client_id y redirect_uri (errores no redirigibles,
+// ErrUnknownClient / ErrInvalidRedirectURI) y luego el resto de parámetros
+// (errores *Error, que se devuelven al cliente vía redirect).
+func (s *Service) ValidateAuthorizeRequest(ctx context.Context, req AuthorizeRequest) (*ClientModel, error) {
+	client, err := s.GetClient(ctx, req.ClientID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !contains(client.RedirectURIs, req.RedirectURI) {
+		return nil, ErrInvalidRedirectURI
+	}
+
+	if req.ResponseType != "code" {
+		return client, newError("unsupported_response_type", "only response_type=code is supported")
+	}
+	if !contains(client.GrantTypes, GrantAuthorizationCode) {
+		return client, newError("unauthorized_client", "client is not allowed to use authorization_code")
+	}
+	if req.CodeChallenge == "" || req.CodeChallengeMethod != pkceMethodS256 {
+		return client, newError("invalid_request", "PKCE with code_challenge_method=S256 is required")
+	}
+	for _, scope := range strings.Fields(req.Scope) {
+		if !contains(client.Scopes, scope) {
+			return client, newError("invalid_scope", "scope not allowed for this client: "+scope)
+		}
+	}
+
+	return client, nil
+}
+
+// Authorize autentica al usuario con AuthService y emite un código de autorización.
+// Devuelve la URL a la que redirigir (redirect_uri?code=...&state=...).
+func (s *Service) Authorize(ctx context.Context, req AuthorizeRequest, email, password, mfaCode string, clientInfo auth.ClientInfo) (string, error) {
+	client, err := s.ValidateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	// El formulario pide el código MFA junto con la contraseña
+	user, err := s.auth.AuthenticateWithCode(ctx, email, password, mfaCode, clientInfo)
+	if errors.Is(err, auth.ErrTenantInactive) || errors.Is(err, auth.ErrTenantAccessDenied) {
+		return ErrorRedirect(req.RedirectURI, req.State, newError("access_denied", "user tenant is not active")), nil
+	}
+	if err != nil {
+		return "", err
+	}
+
+	clientTenant, _ := uuid.Parse(client.TenantID)
+
+	// Un cliente de un tenant concreto solo autoriza a usuarios de ese tenant;
+	// los clientes del tenant System sirven a cualquier usuario. El código
+	// lleva siempre el tenant del usuario, así los tokens nunca son del tenant System
+	if clientTenant != uuid.Nil && user.TenantID != clientTenant {
+		return ErrorRedirect(req.RedirectURI, req.State, newError("access_denied", "user does not belong to the client tenant")), nil
+	}
+
+	code, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	scope := req.Scope
+	if scope == "" {
+		scope = ScopeOpenID
+	}
+
+	now := time.Now().UTC()
+	err = s.repo.CreateAuthorizationCode(ctx, dbgen.CreateAuthorizationCodeParams{
+		CodeHash:            hashSecret(code),
+		ClientID:            uuid.MustParse(client.ID),
+		UserID:              user.ID,
+		TenantID:            user.TenantID,
+		RedirectUri:         req.RedirectURI,
+		Scope:               scope,
+		Nonce:               sql.NullString{String: req.Nonce, Valid: req.Nonce != ""},
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+		AuthTime:            now,
+		ExpiresAt:           now.Add(authorizationCodeTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{"code": {code}}
+	if req.State != "" {
+		params.Set("state", req.State)
+	}
+	return appendQuery(req.RedirectURI, params), nil
+}
+
+// PurgeExpiredCodes elimina los códigos de autorización expirados; la ejecuta el scheduler.
+func (s *Service) PurgeExpiredCodes(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredAuthorizationCodes(ctx)
+}
+
+// ExchangeCode canjea un código de autorización por tokens (grant_type=authorization_code).
+// device es quien presenta el código: el que guardará el refresh token.
+func (s *Service) ExchangeCode(ctx context.Context, client *ClientModel, code, redirectURI, codeVerifier string, device auth.ClientInfo) (*TokenResponse, error) {
+	if !contains(client.GrantTypes, GrantAuthorizationCode) {
+		return nil, newError("unauthorized_client", "client is not allowed to use authorization_code")
+	}
+
+	stored, err := s.repo.ConsumeAuthorizationCode(ctx, hashSecret(code))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newError("invalid_grant", "authorization code is invalid, expired or already used")
+		}
+		return nil, err
+	}
+
+	if stored.ClientID.String() != client.ID {
+		return nil, newError("invalid_grant", "authorization code was issued to another client")
+	}
+	if stored.RedirectUri != redirectURI {
+		return nil, newError("invalid_grant", "redirect_uri does not match the authorization request")
+	}
+	if !verifyPKCE(codeVerifier, stored.CodeChallenge, stored.CodeChallengeMethod) {
+		return nil, newError("invalid_grant", "PKCE verification failed")
+	}
+
+	// El usuario pudo desactivarse entre la autorización y el canje
+	user, err := s.users.GetByID(ctx, stored.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, newError("invalid_grant", "user no longer exists")
+		}
+		return nil, err
+	}
+	if !user.IsActive {
+		return nil, newError("invalid_grant", "user is disabled")
+	}
+
+	// La sesión queda ligada al cliente: solo él podrá rotar el refresh token
+	device.OAuthClientID = client.ID
+	tokens, err := s.auth.IssueTokens(ctx, stored.UserID, stored.TenantID, device)
+	if errors.Is(err, auth.ErrTenantInactive) || errors.Is(err, auth.ErrTenantAccessDenied) {
+		return nil, newError("invalid_grant", "tenant is not active or the user no longer belongs to it")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+		Scope:        stored.Scope,
+	}
+
+	scopes := strings.Fields(stored.Scope)
+	if contains(scopes, ScopeOpenID) {
+		idToken, err := s.idToken(ctx, client.ID, stored, scopes)
+		if err != nil {
+			return nil, err
+		}
+		res.IDToken = idToken
+	}
+
+	return res, nil
+}
+
+// RefreshToken rota el refresh token con AuthService (grant_type=refresh_token).
+// Solo se aceptan refresh tokens emitidos a este cliente (RFC 6749 §6): ni los
+// de otros clientes ni los de /auth/login.
+func (s *Service) RefreshToken(ctx context.Context, client *ClientModel, refreshToken string, device auth.ClientInfo) (*TokenResponse, error) {
+	if !contains(client.GrantTypes, GrantRefreshToken) {
+		return nil, newError("unauthorized_client", "client is not allowed to use refresh_token")
+	}
+
+	device.OAuthClientID = client.ID
+	tokens, err := s.auth.Refresh(ctx, refreshToken, device)
+	if err != nil {
+		return nil, newError("invalid_grant", "refresh token is invalid or expired")
+	}
+
+	return &TokenResponse{
+		AccessToken:  tokens.AccessToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(auth.AccessTokenTTL.Seconds()),
+		RefreshToken: tokens.RefreshToken,
+	}, nil
+}
+
+// ClientCredentials emite un access token de máquina (grant_type=client_credentials).
+// El token queda ligado al tenant del cliente o de la API key y lleva los scopes
+// concedidos; no se emite refresh token (RFC 6749 §4.4.3).
+func (s *Service) ClientCredentials(ctx context.Context, clientID, clientSecret, scope string) (*TokenResponse, error) {
+	client, err := s.authenticateMachine(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.apiKey && !contains(client.GrantTypes, GrantClientCredentials) {
+		return nil, newError("unauthorized_client", "client is not allowed to use client_credentials")
+	}
+
+	granted, err := grantScopes(scope, client.Scopes)
+	if err != nil {
+		return nil, err
+	}
+
+	access, err := auth.GenerateClientAccessToken(client.ID, client.TenantID, granted, auth.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken: access,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(auth.AccessTokenTTL.Seconds()),
+		Scope:       granted,
+	}, nil
+}
+
+// machineClient es un cliente confidencial o una API key autenticados.
+type machineClient struct {
+	ID         string
+	TenantID   string
+	Scopes     []string
+	GrantTypes []string
+	apiKey     bool
+}
+
+// authenticateMachine acepta un cliente OAuth confidencial (client_id + client_secret)
+// o una API key enviada como client_secret (client_id opcional, debe ser el id de la key).
+func (s *Service) authenticateMachine(ctx context.Context, clientID, clientSecret string) (*machineClient, error) {
+	if strings.HasPrefix(clientSecret, apikeys.KeyPrefix) {
+		key, err := s.apikeys.Verify(ctx, clientSecret)
+		if err != nil {
+			if errors.Is(err, apikeys.ErrInvalidKey) {
+				return nil, errInvalidClientSecret
+			}
+			return nil, err
+		}
+		if clientID != "" && clientID != key.ID {
+			return nil, errInvalidClientSecret
+		}
+		return &machineClient{ID: key.ID, TenantID: key.TenantID, Scopes: key.Scopes, apiKey: true}, nil
+	}
+
+	client, err := s.AuthenticateClient(ctx, clientID, clientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !client.IsConfidential {
+		return nil, newError("unauthorized_client", "only confidential clients can use this endpoint")
+	}
+	return &machineClient{ID: client.ID, TenantID: client.TenantID, Scopes: client.Scopes, GrantTypes: client.GrantTypes}, nil
+}
+
+// Revoke revoca un access token o refresh token (RFC 7009). El cliente debe
+// autenticarse (los públicos solo con client_id); poseer el token basta para
+// revocarlo. Tokens desconocidos o ya inválidos no son un error.
+func (s *Service) Revoke(ctx context.Context, clientID, clientSecret, token, hint string) error {
+	var err error
+	if strings.HasPrefix(clientSecret, apikeys.KeyPrefix) {
+		_, err = s.authenticateMachine(ctx, clientID, clientSecret)
+	} else {
+		_, err = s.AuthenticateClient(ctx, clientID, clientSecret)
+	}
+	if err != nil {
+		return err
+	}
+
+	if token == "" {
+		return newError("invalid_request", "token is required")
+	}
+	if hint != "" && hint != TokenTypeAccessToken && hint != TokenTypeRefreshToken {
+		return newError("unsupported_token_type", "only access_token and refresh_token can be revoked")
+	}
+
+	return s.auth.RevokeToken(ctx, token, hint)
+}
+
+// UserInfo devuelve los claims del usuario dueño del access token.
+func (s *Service) UserInfo(ctx context.Context, userID, tenantID string) (*UserInfo, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserInfo{
+		Sub:      user.ID.String(),
+		Name:     user.DisplayName,
+		Email:    user.Email,
+		TenantID: tenantID,
+	}, nil
+}
+
+func (s *Service) idToken(ctx context.Context, clientID string, code *dbgen.OauthAuthorizationCode, scopes []string) (string, error) {
+	claims := auth.IDTokenClaims{
+		Nonce:    code.Nonce.String,
+		AuthTime: code.AuthTime.Unix(),
+		TenantID: code.TenantID.String(),
+	}
+
+	if contains(scopes, ScopeProfile) || contains(scopes, ScopeEmail) {
+		user, err := s.users.GetByID(ctx, code.UserID)
+		if err != nil {
+			return "", err
+		}
+		if contains(scopes, ScopeProfile) {
+			claims.Name = user.DisplayName
+		}
+		if contains(scopes, ScopeEmail) {
+			claims.Email = user.Email
+		}
+	}
+
+	return auth.GenerateIDToken(code.UserID.String(), clientID, claims, idTokenTTL)
+}
+
+// ErrorRedirect construye la redirección de error hacia el cliente (RFC 6749 §4.1.2.1).
+func ErrorRedirect(redirectURI, state string, oauthErr *Error) string {
+	params := url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+	}
+	if state != "" {
+		params.Set("state", state)
+	}
+	return appendQuery(redirectURI, params)
+}
+
+// ---- Helpers ----
+
+// grantScopes valida los scopes pedidos contra los permitidos. Sin scope
+// explícito se conceden todos los permitidos.
+func grantScopes(requested string, allowed []string) (string, error) {
+	if strings.TrimSpace(requested) == "" {
+		return strings.Join(allowed, " "), nil
+	}
+
+	for _, scope := range strings.Fields(requested) {
+		if !contains(allowed, scope) {
+			return "", newError("invalid_scope", "scope not allowed for this client: "+scope)
+		}
+	}
+	return strings.Join(strings.Fields(requested), " "), nil
+}
+
+func toClientModel(c *dbgen.OauthClient) ClientModel {
+	var uris []string
+	if len(c.RedirectUris) > 0 {
+		json.Unmarshal(c.RedirectUris, &uris)
+	}
+	if uris == nil {
+		uris = []string{}
+	}
+
+	return ClientModel{
+		ID:             c.ID.String(),
+		Name:           c.Name,
+		TenantID:       c.TenantID.String(),
+		RedirectURIs:   uris,
+		GrantTypes:     strings.Fields(c.GrantTypes),
+		Scopes:         strings.Fields(c.Scopes),
+		IsConfidential: c.SecretHash.Valid,
+		CreatedAt:      c.CreatedAt,
+	}
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashSecret: los códigos y secretos son aleatorios de alta entropía, basta SHA-256.
+func hashSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
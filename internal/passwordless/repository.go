@@ -0,0 +1,114 @@
+package passwordless
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+// CreateMagicLink guarda una solicitud de magic link. userID uuid.Nil = email sin cuenta.
+func (r *Repository) CreateMagicLink(ctx context.Context, tokenHash, email string, userID uuid.UUID, clientIP string, expiresAt time.Time) error {
+	return r.q.CreateMagicLink(ctx, gen.CreateMagicLinkParams{
+		ID:        uuid.New(),
+		TokenHash: tokenHash,
+		Email:     email,
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		ClientIp:  sql.NullString{String: clientIP, Valid: clientIP != ""},
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// CountRecentMagicLinks cuenta las solicitudes para el email desde since.
+func (r *Repository) CountRecentMagicLinks(ctx context.Context, email string, since time.Time) (int64, error) {
+	return r.q.CountRecentMagicLinks(ctx, gen.CountRecentMagicLinksParams{
+		Email:     email,
+		CreatedAt: since,
+	})
+}
+
+// ConsumeMagicLink marca el link como usado de forma atómica.
+// Devuelve sql.ErrNoRows si no existe, ya se usó o expiró.
+func (r *Repository) ConsumeMagicLink(ctx context.Context, tokenHash string) (*gen.MagicLink, error) {
+	link, err := r.q.ConsumeMagicLink(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &link, nil
+}
+
+// DeleteExpiredMagicLinks elimina los links que expiraron antes de before.
+func (r *Repository) DeleteExpiredMagicLinks(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteExpiredMagicLinks(ctx, before)
+}
+
+// CreateOTPCode guarda un código OTP. userID uuid.Nil = email sin cuenta.
+func (r *Repository) CreateOTPCode(ctx context.Context, email string, userID uuid.UUID, codeHash, channel, clientIP string, expiresAt time.Time) error {
+	return r.q.CreateOTPCode(ctx, gen.CreateOTPCodeParams{
+		ID:        uuid.New(),
+		Email:     email,
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		CodeHash:  codeHash,
+		Channel:   channel,
+		ClientIp:  sql.NullString{String: clientIP, Valid: clientIP != ""},
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// CountRecentOTPCodes cuenta los códigos pedidos desde since para el email y para la IP.
+func (r *Repository) CountRecentOTPCodes(ctx context.Context, email, clientIP string, since time.Time) (byEmail, byIP int64, err error) {
+	byEmail, err = r.q.CountRecentOTPCodesByEmail(ctx, gen.CountRecentOTPCodesByEmailParams{
+		Email:     email,
+		CreatedAt: since,
+	})
+	if err != nil || clientIP == "" {
+		return byEmail, 0, err
+	}
+
+	byIP, err = r.q.CountRecentOTPCodesByIP(ctx, gen.CountRecentOTPCodesByIPParams{
+		ClientIp:  sql.NullString{String: clientIP, Valid: true},
+		CreatedAt: since,
+	})
+	return byEmail, byIP, err
+}
+
+// GetActiveOTPCode devuelve el último código vigente del email.
+func (r *Repository) GetActiveOTPCode(ctx context.Context, email string) (*gen.OtpCode, error) {
+	code, err := r.q.GetActiveOTPCode(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// IncrementOTPAttempts registra un intento de verificación. Devuelve
+// sql.ErrNoRows si el código ya agotó maxAttempts o se consumió.
+func (r *Repository) IncrementOTPAttempts(ctx context.Context, id uuid.UUID, maxAttempts int32) (int32, error) {
+	return r.q.IncrementOTPAttempts(ctx, gen.IncrementOTPAttemptsParams{
+		ID:       id,
+		Attempts: maxAttempts,
+	})
+}
+
+// ConsumeOTPCode marca el código como usado. Devuelve false si otro request lo consumió antes.
+func (r *Repository) ConsumeOTPCode(ctx context.Context, id uuid.UUID) (bool, error) {
+	n, err := r.q.ConsumeOTPCode(ctx, id)
+	return n == 1, err
+}
+
+// DeleteExpiredOTPCodes elimina los códigos que expiraron antes de before.
+func (r *Repository) DeleteExpiredOTPCodes(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteExpiredOTPCodes(ctx, before)
+}
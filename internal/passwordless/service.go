@@ -0,0 +1,201 @@
+package passwordless
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/notify"
+	"github.com/fzalvarez/odin-iam/internal/tenants"
+	"github.com/google/uuid"
+)
+
+// Magic links: vida por defecto (el tenant puede cambiarla con "magic_link_ttl")
+// y límite de solicitudes por email.
+const (
+	defaultMagicLinkTTL   = 15 * time.Minute
+	maxMagicLinkTTL       = 24 * time.Hour
+	magicLinkRateLimit    = 5
+	magicLinkRateWindow   = 15 * time.Minute
+	tenantMagicLinkTTLKey = "magic_link_ttl"
+)
+
+var (
+	ErrRateLimited      = errors.New("too many requests, try again later")
+	ErrInvalidMagicLink = errors.New("magic link is invalid or expired")
+)
+
+type UsersRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*dbgen.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*dbgen.User, error)
+}
+
+type Service struct {
+	repo    *Repository
+	auth    *auth.AuthService
+	users   UsersRepository
+	tenants *tenants.Service
+	sender  *notify.Dispatcher
+}
+
+func NewService(
+	repo *Repository,
+	authService *auth.AuthService,
+	users UsersRepository,
+	tenantService *tenants.Service,
+	sender *notify.Dispatcher,
+) *Service {
+	return &Service{
+		repo:    repo,
+		auth:    authService,
+		users:   users,
+		tenants: tenantService,
+		sender:  sender,
+	}
+}
+
+// MagicLinkURL lee MAGIC_LINK_URL: la página del frontend que recibe ?token=
+// y lo canjea con POST /auth/magic-link/verify.
+func MagicLinkURL() string {
+	if v := os.Getenv("MAGIC_LINK_URL"); v != "" {
+		return v
+	}
+	return auth.Issuer() + "/magic-link"
+}
+
+// ----------------------------------------------
+// MAGIC LINK
+// ----------------------------------------------
+
+// RequestMagicLink envía un link de acceso de un solo uso al email. Responde
+// igual exista o no la cuenta; solo ErrRateLimited es visible para el cliente.
+func (s *Service) RequestMagicLink(ctx context.Context, email string, client auth.ClientInfo) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errors.New("email is required")
+	}
+	key := strings.ToLower(email)
+
+	n, err := s.repo.CountRecentMagicLinks(ctx, key, time.Now().Add(-magicLinkRateWindow))
+	if err != nil {
+		return err
+	}
+	if n >= magicLinkRateLimit {
+		return ErrRateLimited
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	// Emails sin cuenta (o desactivada) se registran igual, sin enviar nada
+	userID := uuid.Nil
+	ttl := defaultMagicLinkTTL
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err == nil && user.IsActive {
+		userID = user.ID
+		ttl = s.magicLinkTTL(ctx, user.TenantID)
+	}
+
+	if err := s.repo.CreateMagicLink(ctx, hashToken(token), key, userID, client.IP, time.Now().UTC().Add(ttl)); err != nil {
+		return err
+	}
+	if userID == uuid.Nil {
+		return nil
+	}
+
+	link := appendQuery(MagicLinkURL(), url.Values{"token": {token}})
+	err = s.sender.Send(ctx, notify.Message{
+		Channel: notify.ChannelEmail,
+		To:      user.Email,
+		Subject: "Tu link de acceso",
+		Body:    fmt.Sprintf("Usa este link para iniciar sesión. Caduca en %s y solo sirve una vez:\n\n%s", ttl, link),
+	})
+	if err != nil {
+		// No se devuelve: un error aquí revelaría que la cuenta existe
+		log.Printf("⚠️  Error enviando magic link: %v", err)
+	}
+	return nil
+}
+
+// VerifyMagicLink canjea el token del link por el mismo resultado que Login.
+func (s *Service) VerifyMagicLink(ctx context.Context, token string, client auth.ClientInfo) (*auth.LoginResult, error) {
+	if token == "" {
+		return nil, ErrInvalidMagicLink
+	}
+
+	link, err := s.repo.ConsumeMagicLink(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidMagicLink
+		}
+		return nil, err
+	}
+
+	user, err := s.users.GetByID(ctx, link.UserID.UUID)
+	if err != nil || !user.IsActive {
+		return nil, ErrInvalidMagicLink
+	}
+	// El link llegó al email: queda verificado
+	s.auth.ConfirmEmail(ctx, user)
+
+	// Tenant del usuario, como Login sin tenant; se cambia con /auth/switch-tenant
+	return s.auth.CompleteLogin(ctx, user, user.TenantID, client)
+}
+
+// PurgeExpired elimina los magic links y códigos OTP que ya no pueden canjearse
+// ni cuentan para el rate limit; la ejecuta el scheduler.
+func (s *Service) PurgeExpired(ctx context.Context) (int64, error) {
+	links, err := s.repo.DeleteExpiredMagicLinks(ctx, time.Now().Add(-magicLinkRateWindow))
+	if err != nil {
+		return 0, err
+	}
+	codes, err := s.repo.DeleteExpiredOTPCodes(ctx, time.Now().Add(-otpRateWindow))
+	return links + codes, err
+}
+
+func (s *Service) magicLinkTTL(ctx context.Context, tenantID uuid.UUID) time.Duration {
+	cfg, err := s.tenants.GetConfig(ctx, tenantID)
+	if err != nil {
+		return defaultMagicLinkTTL
+	}
+	ttl := cfg.Duration(tenantMagicLinkTTLKey, defaultMagicLinkTTL)
+	if ttl > maxMagicLinkTTL {
+		return maxMagicLinkTTL
+	}
+	return ttl
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + params.Encode()
+}
@@ -0,0 +1,43 @@
+package passwordpolicy
+
+import (
+	"context"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+// Add guarda un hash en el historial del usuario.
+func (r *Repository) Add(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	return r.q.AddPasswordHistory(ctx, gen.AddPasswordHistoryParams{
+		ID:           uuid.New(),
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now().UTC(),
+	})
+}
+
+// Recent devuelve los últimos n hashes del usuario, del más reciente al más antiguo.
+func (r *Repository) Recent(ctx context.Context, userID uuid.UUID, n int) ([]string, error) {
+	return r.q.ListPasswordHistory(ctx, gen.ListPasswordHistoryParams{
+		UserID: userID,
+		Limit:  int32(n),
+	})
+}
+
+// Prune borra el historial del usuario salvo los keep hashes más recientes.
+func (r *Repository) Prune(ctx context.Context, userID uuid.UUID, keep int) (int64, error) {
+	return r.q.PrunePasswordHistory(ctx, gen.PrunePasswordHistoryParams{
+		UserID: userID,
+		Limit:  int32(keep),
+	})
+}
@@ -0,0 +1,157 @@
+package passwordpolicy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	"github.com/fzalvarez/odin-iam/internal/breached"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/tenants"
+	"github.com/google/uuid"
+)
+
+// Claves del config del tenant. Sin ellas se aplica auth.DefaultPasswordRules,
+// sin historial ni caducidad.
+const (
+	tenantMinLengthKey     = "password_min_length"
+	tenantMaxLengthKey     = "password_max_length"
+	tenantRequireUpperKey  = "password_require_uppercase"
+	tenantRequireLowerKey  = "password_require_lowercase"
+	tenantRequireDigitKey  = "password_require_digit"
+	tenantRequireSymbolKey = "password_require_symbol"
+	tenantBannedWordsKey   = "password_banned_words"
+	tenantHistoryKey       = "password_history"
+	tenantMaxAgeKey        = "password_max_age"
+	tenantRejectBreached   = "password_reject_breached"
+)
+
+const (
+	// Ningún tenant puede bajar del mínimo global
+	minMinLength = 8
+	// Tope de longitud: cada verificación de Argon2 procesa la contraseña entera
+	maxMaxLength = 1024
+	// Hashes que se conservan por usuario; también es el máximo de password_history
+	maxHistory = 24
+)
+
+// Policy es la política de contraseñas de un tenant.
+type Policy struct {
+	auth.PasswordRules
+	History int           // últimas contraseñas (incluida la actual) que no se pueden reutilizar
+	MaxAge  time.Duration // edad máxima de una contraseña (0 = no caducan)
+	// RejectBreached rechaza las contraseñas del corpus de filtraciones (si hay uno instalado)
+	RejectBreached bool
+}
+
+type Service struct {
+	repo     *Repository
+	tenants  *tenants.Service
+	breached *breached.Checker
+}
+
+func NewService(repo *Repository, tenantService *tenants.Service) *Service {
+	return &Service{repo: repo, tenants: tenantService}
+}
+
+// UseBreachedPasswords instala el corpus de contraseñas filtradas. Sin él la
+// regla password_reject_breached no tiene efecto.
+func (s *Service) UseBreachedPasswords(c *breached.Checker) {
+	s.breached = c
+}
+
+// Policy devuelve la política del tenant, o la de por defecto si no se puede leer su config.
+func (s *Service) Policy(ctx context.Context, tenantID uuid.UUID) Policy {
+	def := auth.DefaultPasswordRules
+	cfg, err := s.tenants.GetConfig(ctx, tenantID)
+	if err != nil {
+		return Policy{PasswordRules: def, RejectBreached: true}
+	}
+
+	minLength := max(cfg.Int(tenantMinLengthKey, def.MinLength), minMinLength)
+	maxLength := cfg.Int(tenantMaxLengthKey, def.MaxLength)
+	if maxLength <= 0 || maxLength > maxMaxLength {
+		maxLength = maxMaxLength
+	}
+	return Policy{
+		PasswordRules: auth.PasswordRules{
+			MinLength:     minLength,
+			MaxLength:     max(maxLength, minLength),
+			RequireUpper:  cfg.Bool(tenantRequireUpperKey, false),
+			RequireLower:  cfg.Bool(tenantRequireLowerKey, false),
+			RequireDigit:  cfg.Bool(tenantRequireDigitKey, false),
+			RequireSymbol: cfg.Bool(tenantRequireSymbolKey, false),
+			BannedWords:   cfg.Strings(tenantBannedWordsKey),
+		},
+		History:        min(max(cfg.Int(tenantHistoryKey, 0), 0), maxHistory),
+		MaxAge:         cfg.Duration(tenantMaxAgeKey, 0),
+		RejectBreached: cfg.Bool(tenantRejectBreached, true),
+	}
+}
+
+// Validate aplica la política del tenant del usuario. El historial solo se
+// revisa si la contraseña cumple el resto de reglas: cada hash cuesta una
+// verificación de Argon2.
+func (s *Service) Validate(ctx context.Context, user *dbgen.User, password string) ([]auth.PolicyViolation, error) {
+	policy := s.Policy(ctx, user.TenantID)
+	violations := policy.Check(user, password)
+	if policy.RejectBreached && s.isBreached(password) {
+		violations = append(violations, auth.PolicyViolation{
+			Code:    auth.ViolationBreached,
+			Message: "appears in a known data breach",
+		})
+	}
+	if len(violations) > 0 || policy.History == 0 || user.ID == uuid.Nil {
+		return violations, nil
+	}
+
+	hashes, err := s.repo.Recent(ctx, user.ID, policy.History)
+	if err != nil {
+		return nil, err
+	}
+	for _, hash := range hashes {
+		ok, err := auth.VerifyPassword(ctx, password, hash)
+		// Un hash antiguo ilegible no cuenta, pero el pool saturado sí corta
+		if err != nil && (errors.Is(err, auth.ErrHashPoolBusy) || ctx.Err() != nil) {
+			return nil, err
+		}
+		if ok {
+			return []auth.PolicyViolation{{
+				Code:    auth.ViolationReused,
+				Message: fmt.Sprintf("must not match any of your last %d passwords", policy.History),
+			}}, nil
+		}
+	}
+	return nil, nil
+}
+
+// isBreached consulta el corpus. Un error de lectura solo se registra: no
+// debe impedir que el usuario cambie su contraseña.
+func (s *Service) isBreached(password string) bool {
+	if s.breached == nil {
+		return false
+	}
+	found, err := s.breached.Contains(password)
+	if err != nil {
+		log.Printf("⚠️  Error consultando contraseñas filtradas: %v", err)
+		return false
+	}
+	return found
+}
+
+// Remember guarda el hash de la contraseña nueva y recorta el historial.
+func (s *Service) Remember(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	if err := s.repo.Add(ctx, userID, passwordHash); err != nil {
+		return err
+	}
+	_, err := s.repo.Prune(ctx, userID, maxHistory)
+	return err
+}
+
+// MaxAge devuelve la edad máxima de las contraseñas del tenant.
+func (s *Service) MaxAge(ctx context.Context, tenantID uuid.UUID) time.Duration {
+	return s.Policy(ctx, tenantID).MaxAge
+}
@@ -0,0 +1,78 @@
+package passwordreset
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+// Create guarda una solicitud de recuperación. userID uuid.Nil = email sin cuenta.
+func (r *Repository) Create(ctx context.Context, tokenHash, email string, userID uuid.UUID, clientIP string, expiresAt time.Time) error {
+	return r.q.CreatePasswordReset(ctx, gen.CreatePasswordResetParams{
+		ID:        uuid.New(),
+		TokenHash: tokenHash,
+		Email:     email,
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		ClientIp:  sql.NullString{String: clientIP, Valid: clientIP != ""},
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// CountRecent cuenta las solicitudes hechas desde since para el email y para la IP.
+func (r *Repository) CountRecent(ctx context.Context, email, clientIP string, since time.Time) (byEmail, byIP int64, err error) {
+	byEmail, err = r.q.CountRecentPasswordResetsByEmail(ctx, gen.CountRecentPasswordResetsByEmailParams{
+		Email:     email,
+		CreatedAt: since,
+	})
+	if err != nil || clientIP == "" {
+		return byEmail, 0, err
+	}
+
+	byIP, err = r.q.CountRecentPasswordResetsByIP(ctx, gen.CountRecentPasswordResetsByIPParams{
+		ClientIp:  sql.NullString{String: clientIP, Valid: true},
+		CreatedAt: since,
+	})
+	return byEmail, byIP, err
+}
+
+// GetActive devuelve la solicitud del token sin consumirla.
+// Devuelve sql.ErrNoRows si no existe, ya se usó, expiró o no tiene cuenta.
+func (r *Repository) GetActive(ctx context.Context, tokenHash string) (*gen.PasswordReset, error) {
+	reset, err := r.q.GetActivePasswordReset(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// Consume marca el token como usado de forma atómica.
+// Devuelve sql.ErrNoRows si no existe, ya se usó, expiró o no tiene cuenta.
+func (r *Repository) Consume(ctx context.Context, tokenHash string) (*gen.PasswordReset, error) {
+	reset, err := r.q.ConsumePasswordReset(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &reset, nil
+}
+
+// InvalidateUser anula los tokens pendientes del usuario.
+func (r *Repository) InvalidateUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.InvalidateUserPasswordResets(ctx, uuid.NullUUID{UUID: userID, Valid: true})
+}
+
+// DeleteExpired elimina las solicitudes que expiraron antes de before.
+func (r *Repository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteExpiredPasswordResets(ctx, before)
+}
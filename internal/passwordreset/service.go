@@ -0,0 +1,189 @@
+package passwordreset
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/notify"
+	"github.com/google/uuid"
+)
+
+// Tokens de recuperación: vida corta y límite de solicitudes por email y por IP.
+const (
+	tokenTTL        = 30 * time.Minute
+	emailRateLimit  = 5
+	ipRateLimit     = 20
+	rateLimitWindow = 15 * time.Minute
+)
+
+var (
+	ErrRateLimited  = errors.New("too many requests, try again later")
+	ErrInvalidToken = errors.New("reset token is invalid or expired")
+)
+
+type UsersRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*dbgen.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*dbgen.User, error)
+}
+
+type Service struct {
+	repo   *Repository
+	auth   *auth.AuthService
+	users  UsersRepository
+	sender *notify.Dispatcher
+}
+
+func NewService(
+	repo *Repository,
+	authService *auth.AuthService,
+	users UsersRepository,
+	sender *notify.Dispatcher,
+) *Service {
+	return &Service{
+		repo:   repo,
+		auth:   authService,
+		users:  users,
+		sender: sender,
+	}
+}
+
+// ResetURL lee PASSWORD_RESET_URL: la página del frontend que recibe ?token=,
+// pide la nueva contraseña y la envía a POST /auth/password/reset.
+func ResetURL() string {
+	if v := os.Getenv("PASSWORD_RESET_URL"); v != "" {
+		return v
+	}
+	return auth.Issuer() + "/reset-password"
+}
+
+// RequestReset envía un token de recuperación de un solo uso al email. Responde
+// igual exista o no la cuenta; solo ErrRateLimited es visible para el cliente.
+func (s *Service) RequestReset(ctx context.Context, email string, client auth.ClientInfo) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errors.New("email is required")
+	}
+	key := strings.ToLower(email)
+
+	byEmail, byIP, err := s.repo.CountRecent(ctx, key, client.IP, time.Now().Add(-rateLimitWindow))
+	if err != nil {
+		return err
+	}
+	if byEmail >= emailRateLimit || byIP >= ipRateLimit {
+		return ErrRateLimited
+	}
+
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	// Emails sin cuenta (o desactivada) se registran igual, sin enviar nada
+	userID := uuid.Nil
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err == nil && user.IsActive {
+		userID = user.ID
+	}
+
+	if err := s.repo.Create(ctx, hashToken(token), key, userID, client.IP, time.Now().UTC().Add(tokenTTL)); err != nil {
+		return err
+	}
+	if userID == uuid.Nil {
+		return nil
+	}
+
+	link := appendQuery(ResetURL(), url.Values{"token": {token}})
+	err = s.sender.Send(ctx, notify.Message{
+		Channel: notify.ChannelEmail,
+		To:      user.Email,
+		Subject: "Recupera tu contraseña",
+		Body:    fmt.Sprintf("Usa este link para elegir una nueva contraseña. Caduca en %s y solo sirve una vez:\n\n%s\n\nSi no lo pediste, ignora este mensaje.", tokenTTL, link),
+	})
+	if err != nil {
+		// No se devuelve: un error aquí revelaría que la cuenta existe
+		log.Printf("⚠️  Error enviando recuperación de contraseña: %v", err)
+	}
+	return nil
+}
+
+// ResetPassword canjea el token, cambia la contraseña y cierra todas las
+// sesiones del usuario. Los demás tokens pendientes quedan anulados.
+func (s *Service) ResetPassword(ctx context.Context, token, newPassword string, client auth.ClientInfo) error {
+	if token == "" {
+		return ErrInvalidToken
+	}
+	reset, err := s.repo.GetActive(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	user, err := s.users.GetByID(ctx, reset.UserID.UUID)
+	if err != nil || !user.IsActive {
+		return ErrInvalidToken
+	}
+
+	// La política del tenant se aplica antes de consumir el token para no
+	// gastarlo en un error del usuario
+	if err := s.auth.ValidatePassword(ctx, user, newPassword); err != nil {
+		return err
+	}
+	if _, err := s.repo.Consume(ctx, reset.TokenHash); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	if _, err := s.auth.ResetPassword(ctx, user.ID.String(), newPassword, client); err != nil {
+		return err
+	}
+	if _, err := s.repo.InvalidateUser(ctx, user.ID); err != nil {
+		log.Printf("⚠️  Error anulando tokens de recuperación: %v", err)
+	}
+	// El link llegó al email: queda verificado
+	s.auth.ConfirmEmail(ctx, user)
+	return nil
+}
+
+// PurgeExpired elimina las solicitudes que ya no pueden canjearse ni cuentan
+// para el rate limit; la ejecuta el scheduler.
+func (s *Service) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpired(ctx, time.Now().Add(-rateLimitWindow))
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + params.Encode()
+}
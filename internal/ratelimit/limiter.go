@@ -0,0 +1,67 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+)
+
+// Limit es un token bucket de Burst tokens que se rellena por completo en Per.
+type Limit struct {
+	Burst int
+	Per   time.Duration
+}
+
+// PerMinute y PerHour construyen los límites habituales.
+func PerMinute(n int) Limit { return Limit{Burst: n, Per: time.Minute} }
+func PerHour(n int) Limit   { return Limit{Burst: n, Per: time.Hour} }
+
+// Result es el estado del bucket tras un intento.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // hasta que el bucket vuelva a estar lleno
+	RetryAfter time.Duration // solo si !Allowed
+}
+
+// Store guarda los buckets. Take consume un token de key si hay disponible.
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Result, error)
+}
+
+// StoreFromEnv lee RATE_LIMIT_BACKEND: "memory" (por defecto; cada réplica
+// cuenta por su lado) o "postgres" (compartido entre réplicas).
+func StoreFromEnv(db gen.DBTX) (Store, error) {
+	switch v := os.Getenv("RATE_LIMIT_BACKEND"); v {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "postgres":
+		return NewPostgresStore(db), nil
+	default:
+		return nil, fmt.Errorf("unknown rate limit backend %q", v)
+	}
+}
+
+// Los backends implementan el token bucket con GCRA: en lugar de contar tokens
+// guardan tat, el momento en que el bucket vuelve a estar lleno. Cada petición
+// lo adelanta emission; se rechaza si eso lo deja más de Per en el futuro.
+
+func (l Limit) emission() time.Duration {
+	return l.Per / time.Duration(l.Burst)
+}
+
+// result calcula el estado del bucket para un tat ya actualizado (allowed) o
+// sin cambios (rechazado).
+func (l Limit) result(now, tat time.Time, allowed bool) Result {
+	res := Result{Allowed: allowed, Limit: l.Burst, Reset: max(tat.Sub(now), 0)}
+	if allowed {
+		res.Remaining = int((l.Per - tat.Sub(now)) / l.emission())
+		return res
+	}
+	res.RetryAfter = max(tat.Add(l.emission()).Sub(now)-l.Per, 0)
+	return res
+}
@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Cada cuánto MemoryStore elimina los buckets llenos.
+const memorySweepInterval = time.Minute
+
+// MemoryStore guarda los buckets en memoria. Cada réplica limita por su lado.
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]time.Time // key -> tat
+	lastSweep time.Time
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]time.Time), lastSweep: time.Now()}
+}
+
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sweep(now)
+
+	tat, ok := s.buckets[key]
+	if !ok || tat.Before(now) {
+		tat = now
+	}
+	next := tat.Add(limit.emission())
+	if next.Sub(now) > limit.Per {
+		return limit.result(now, tat, false), nil
+	}
+
+	s.buckets[key] = next
+	return limit.result(now, next, true), nil
+}
+
+// sweep elimina los buckets llenos (tat pasado): equivalen a no tener bucket.
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < memorySweepInterval {
+		return
+	}
+	for key, tat := range s.buckets {
+		if tat.Before(now) {
+			delete(s.buckets, key)
+		}
+	}
+	s.lastSweep = now
+}
@@ -0,0 +1,49 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+)
+
+// PostgresStore guarda los buckets en rate_limit_buckets: todas las réplicas
+// comparten el límite. Cada intento es un único upsert atómico.
+type PostgresStore struct {
+	q *gen.Queries
+}
+
+func NewPostgresStore(db gen.DBTX) *PostgresStore {
+	return &PostgresStore{q: gen.New(db)}
+}
+
+func (s *PostgresStore) Take(ctx context.Context, key string, limit Limit) (Result, error) {
+	now := time.Now()
+
+	tat, err := s.q.TakeRateLimitToken(ctx, gen.TakeRateLimitTokenParams{
+		Key:       key,
+		Now:       now.UnixMicro(),
+		Emission:  limit.emission().Microseconds(),
+		Tolerance: limit.Per.Microseconds(),
+	})
+	if err == nil {
+		return limit.result(now, time.UnixMicro(tat), true), nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, err
+	}
+
+	// Sin fila: el upsert no se aplicó porque el bucket está vacío
+	tat, err = s.q.GetRateLimitBucket(ctx, key)
+	if err != nil {
+		return Result{}, err
+	}
+	return limit.result(now, time.UnixMicro(tat), false), nil
+}
+
+// PurgeExpired elimina los buckets llenos; la ejecuta el scheduler.
+func (s *PostgresStore) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.q.DeleteExpiredRateLimitBuckets(ctx, time.Now().UnixMicro())
+}
@@ -21,6 +21,7 @@ type Repository interface {
 	// Verificación (Core RBAC)
 	CheckUserPermission(ctx context.Context, userID string, permissionCode string) (bool, error)
 	GetUserPermissions(ctx context.Context, userID string) ([]string, error) // Asegurar que este método existe
+	GetUserPermissionsInTenant(ctx context.Context, userID, tenantID string) ([]string, error)
 }
 
 // Service define la lógica de negocio.
@@ -164,3 +164,17 @@ func (r *RepositoryImpl) GetUserPermissions(ctx context.Context, userID string)
 	}
 	return r.q.GetPermissionsByUser(ctx, uid)
 }
+
+// GetUserPermissionsInTenant devuelve los permisos que el usuario tiene por
+// roles del tenant indicado. Los roles sin tenant cuentan como del tenant System.
+func (r *RepositoryImpl) GetUserPermissionsInTenant(ctx context.Context, userID, tenantID string) ([]string, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	tid, err := uuid.Parse(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return r.q.GetPermissionsByUserInTenant(ctx, gen.GetPermissionsByUserInTenantParams{UserID: uid, TenantID: tid})
+}
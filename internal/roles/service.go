@@ -52,6 +52,11 @@ func (s *RoleService) GetUserPermissions(ctx context.Context, userID string) ([]
 	return s.repo.GetUserPermissions(ctx, userID)
 }
 
+// GetUserPermissionsInTenant obtiene los permisos que el usuario tiene en un tenant
+func (s *RoleService) GetUserPermissionsInTenant(ctx context.Context, userID, tenantID string) ([]string, error) {
+	return s.repo.GetUserPermissionsInTenant(ctx, userID, tenantID)
+}
+
 // CheckPermission verifica si un usuario tiene un permiso específico
 func (s *RoleService) CheckPermission(ctx context.Context, userID string, permission string) (bool, error) {
 	return s.repo.CheckUserPermission(ctx, userID, permission)
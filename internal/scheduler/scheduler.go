@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"hash/fnv"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+)
+
+// Cada cuánto una réplica comprueba si le toca a una tarea, como máximo.
+const maxCheckInterval = time.Minute
+
+// Job es una tarea periódica. Run debe ser idempotente: si una réplica muere a
+// mitad de ejecución, otra la repetirá en el siguiente intervalo.
+type Job struct {
+	Name     string
+	Interval time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler ejecuta tareas periódicas dentro del proceso. Todas las réplicas
+// corren el mismo scheduler: un advisory lock de Postgres por tarea y la fecha
+// de la última ejecución (tabla scheduled_jobs) garantizan que cada tarea se
+// ejecute una sola vez por intervalo en todo el cluster.
+type Scheduler struct {
+	db   *sql.DB
+	jobs []Job
+}
+
+func New(db *sql.DB) *Scheduler {
+	return &Scheduler{db: db}
+}
+
+// EnabledFromEnv lee SCHEDULER_ENABLED (por defecto true). Permite dejar
+// réplicas que solo atienden tráfico.
+func EnabledFromEnv() (bool, error) {
+	v := os.Getenv("SCHEDULER_ENABLED")
+	if v == "" {
+		return true, nil
+	}
+	return strconv.ParseBool(v)
+}
+
+// Add registra una tarea. Debe llamarse antes de Start.
+func (s *Scheduler) Add(job Job) {
+	s.jobs = append(s.jobs, job)
+}
+
+// Start lanza las tareas registradas hasta que ctx se cancele.
+func (s *Scheduler) Start(ctx context.Context) {
+	for _, job := range s.jobs {
+		go s.loop(ctx, job)
+	}
+}
+
+func (s *Scheduler) loop(ctx context.Context, job Job) {
+	check := job.Interval
+	if check > maxCheckInterval {
+		check = maxCheckInterval
+	}
+
+	ticker := time.NewTicker(check)
+	defer ticker.Stop()
+
+	for {
+		if err := s.runIfDue(ctx, job); err != nil && ctx.Err() == nil {
+			log.Printf("⚠️  Error en tarea %s: %v", job.Name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// runIfDue ejecuta la tarea si ninguna otra réplica la tiene tomada y ya pasó
+// su intervalo desde la última ejecución.
+func (s *Scheduler) runIfDue(ctx context.Context, job Job) error {
+	// Los advisory locks de sesión pertenecen a una conexión: lock y unlock
+	// tienen que ir por la misma.
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	q := gen.New(conn)
+	key := lockKey(job.Name)
+
+	locked, err := q.TryAdvisoryLock(ctx, key)
+	if err != nil || !locked {
+		return err
+	}
+	defer func() {
+		if _, err := q.AdvisoryUnlock(context.Background(), key); err != nil {
+			log.Printf("⚠️  Error liberando lock de %s: %v", job.Name, err)
+		}
+	}()
+
+	last, err := q.GetScheduledJob(ctx, job.Name)
+	switch {
+	case err == nil:
+		if time.Since(last.LastRunAt) < job.Interval {
+			return nil
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+
+	startedAt := time.Now().UTC()
+	runErr := job.Run(ctx)
+
+	// Se registra también si falla: se reintenta en el siguiente intervalo
+	// en lugar de en cada comprobación.
+	var lastError sql.NullString
+	if runErr != nil {
+		lastError = sql.NullString{String: runErr.Error(), Valid: true}
+	}
+	if err := q.MarkScheduledJobRun(ctx, gen.MarkScheduledJobRunParams{
+		Name:      job.Name,
+		LastRunAt: startedAt,
+		LastError: lastError,
+	}); err != nil {
+		return err
+	}
+
+	return runErr
+}
+
+// lockKey deriva la clave del advisory lock a partir del nombre de la tarea.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("odin-iam/scheduler/" + name))
+	return int64(h.Sum64())
+}
@@ -0,0 +1,106 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"os"
+	"strings"
+)
+
+// Prefijo de versión de los valores cifrados. Permite cambiar el esquema
+// en el futuro sin romper los valores ya almacenados.
+const sealedPrefix = "v1:"
+
+var (
+	ErrKeyNotSet     = errors.New("SECRETS_KEY is not set")
+	ErrInvalidKey    = errors.New("SECRETS_KEY must be 32 bytes encoded as hex or base64")
+	ErrInvalidSealed = errors.New("invalid sealed value")
+)
+
+// masterKey lee SECRETS_KEY (32 bytes en hex o base64).
+func masterKey() ([]byte, error) {
+	raw := strings.TrimSpace(os.Getenv("SECRETS_KEY"))
+	if raw == "" {
+		return nil, ErrKeyNotSet
+	}
+
+	if key, err := hex.DecodeString(raw); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(raw); err == nil && len(key) == 32 {
+		return key, nil
+	}
+	if key, err := base64.RawURLEncoding.DecodeString(raw); err == nil && len(key) == 32 {
+		return key, nil
+	}
+
+	return nil, ErrInvalidKey
+}
+
+// Seal cifra un valor con AES-256-GCM usando SECRETS_KEY.
+// Formato: v1:base64(nonce || ciphertext).
+func Seal(plaintext []byte) (string, error) {
+	key, err := masterKey()
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return sealedPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Open descifra un valor producido por Seal.
+func Open(sealed string) ([]byte, error) {
+	if !strings.HasPrefix(sealed, sealedPrefix) {
+		return nil, ErrInvalidSealed
+	}
+
+	data, err := base64.RawStdEncoding.DecodeString(strings.TrimPrefix(sealed, sealedPrefix))
+	if err != nil {
+		return nil, ErrInvalidSealed
+	}
+
+	key, err := masterKey()
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, ErrInvalidSealed
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, ErrInvalidSealed
+	}
+
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,17 @@
+package secrets
+
+import (
+	"crypto/hkdf"
+	"crypto/sha256"
+)
+
+// DeriveKey deriva de SECRETS_KEY una subllave de 32 bytes para un uso concreto
+// (HKDF-SHA256 con purpose como info). Cada uso tiene su propia llave, así que
+// una llave filtrada de un uso no compromete los demás.
+func DeriveKey(purpose string) ([]byte, error) {
+	key, err := masterKey()
+	if err != nil {
+		return nil, err
+	}
+	return hkdf.Key(sha256.New, key, nil, "odin-iam/"+purpose, 32)
+}
@@ -7,20 +7,39 @@ import (
 )
 
 type SessionModel struct {
-	ID           string    `json:"id"`
-	UserID       string    `json:"user_id"`
-	TenantID     string    `json:"tenant_id"`
-	RefreshToken string    `json:"refresh_token"`
-	UserAgent    string    `json:"user_agent"`
-	ClientIP     string    `json:"client_ip"`
-	ExpiresAt    time.Time `json:"expires_at"`
-	CreatedAt    time.Time `json:"created_at"`
+	ID               string     `json:"id"`
+	FamilyID         string     `json:"family_id"`
+	UserID           string     `json:"user_id"`
+	TenantID         string     `json:"tenant_id"`
+	RefreshTokenHash string     `json:"-"` // HMAC del refresh token, nunca el token
+	UserAgent        string     `json:"user_agent"`
+	ClientIP         string     `json:"client_ip"`
+	ExpiresAt        time.Time  `json:"expires_at"`
+	CreatedAt        time.Time  `json:"created_at"`
+	ConsumedAt       *time.Time `json:"consumed_at,omitempty"` // el refresh token ya se rotó
+	ClientID         string     `json:"client_id,omitempty"`   // cliente OAuth al que se emitió; vacío = /auth/*
+}
+
+// ActiveSession es un dispositivo con sesión abierta, tal como lo ve el usuario.
+// El ID es el de la familia de rotación: no cambia con cada refresh.
+type ActiveSession struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	ClientIP   string    `json:"client_ip"`
+	SignedInAt time.Time `json:"signed_in_at"`
+	LastUsedAt time.Time `json:"last_used_at"` // último login o refresh
+	ExpiresAt  time.Time `json:"expires_at"`
 }
 
 func (s *SessionModel) IsExpired() bool {
 	return time.Now().UTC().After(s.ExpiresAt)
 }
 
+// IsActive indica si el refresh token de la sesión todavía puede usarse.
+func (s *SessionModel) IsActive() bool {
+	return s.ConsumedAt == nil && !s.IsExpired()
+}
+
 // Helper para convertir UUIDs a string de forma segura
 func uuidToString(u uuid.UUID) string {
 	if u == uuid.Nil {
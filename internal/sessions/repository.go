@@ -17,37 +17,144 @@ func NewRepository(db gen.DBTX) *Repository {
 	return &Repository{q: gen.New(db)}
 }
 
-func (r *Repository) CreateSession(ctx context.Context, sessionID, userID, tenantID uuid.UUID, refreshToken, userAgent, clientIP string, expiresAt time.Time) error {
-	_, err := r.q.CreateSession(ctx, gen.CreateSessionParams{
+// CreateSession crea una sesión dentro de la familia de rotación familyID
+// (en un login nuevo familyID es el propio sessionID). Solo se guarda el hash
+// del refresh token. clientID es el cliente OAuth que pidió los tokens
+// (uuid.Nil = login propio por /auth/*).
+func (r *Repository) CreateSession(ctx context.Context, sessionID, familyID, userID, tenantID, clientID uuid.UUID, refreshToken, userAgent, clientIP string, expiresAt time.Time) error {
+	hash, err := HashRefreshToken(refreshToken)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.q.CreateSession(ctx, gen.CreateSessionParams{
 		ID:           sessionID,
+		FamilyID:     familyID,
 		UserID:       userID,
 		TenantID:     tenantID,
-		RefreshToken: refreshToken,
+		RefreshToken: hash,
 		UserAgent:    sql.NullString{String: userAgent, Valid: userAgent != ""},
 		ClientIp:     sql.NullString{String: clientIP, Valid: clientIP != ""},
 		ExpiresAt:    expiresAt,
 		CreatedAt:    time.Now(),
+		ClientID:     uuid.NullUUID{UUID: clientID, Valid: clientID != uuid.Nil},
 	})
 	return err
 }
 
-// GetByRefreshToken busca una sesión por su refresh token
+// GetByRefreshToken busca una sesión por su refresh token (incluidas las ya consumidas)
 func (r *Repository) GetByRefreshToken(ctx context.Context, refreshToken string) (*SessionModel, error) {
-	s, err := r.q.GetSessionByRefreshToken(ctx, refreshToken)
+	hash, err := HashRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := r.q.GetSessionByRefreshToken(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return toModel(s), nil
+}
+
+// ConsumeSession marca la sesión del refresh token como usada de forma atómica.
+// Devuelve sql.ErrNoRows si no existe o ya fue consumida.
+func (r *Repository) ConsumeSession(ctx context.Context, refreshToken string) (*SessionModel, error) {
+	hash, err := HashRefreshToken(refreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := r.q.ConsumeSession(ctx, hash)
+	if err != nil {
+		return nil, err
+	}
+	return toModel(s), nil
+}
+
+// Filas migradas por lote en MigrateLegacyTokens.
+const legacyTokenBatchSize = 500
+
+// MigrateLegacyTokens reemplaza los refresh tokens guardados en claro por su
+// hash. Los clientes siguen usando el mismo token: al presentarlo se hashea y
+// coincide con la fila migrada. Devuelve cuántas sesiones se migraron.
+func (r *Repository) MigrateLegacyTokens(ctx context.Context) (int64, error) {
+	var migrated int64
+	for {
+		rows, err := r.q.ListLegacySessionTokens(ctx, legacyTokenBatchSize)
+		if err != nil {
+			return migrated, err
+		}
+		if len(rows) == 0 {
+			return migrated, nil
+		}
+
+		for _, row := range rows {
+			hash, err := HashRefreshToken(row.RefreshToken)
+			if err != nil {
+				return migrated, err
+			}
+			if err := r.q.SetSessionRefreshToken(ctx, gen.SetSessionRefreshTokenParams{
+				ID:           row.ID,
+				RefreshToken: hash,
+			}); err != nil {
+				return migrated, err
+			}
+			migrated++
+		}
+	}
+}
+
+// DeleteFamily elimina todas las sesiones de una familia de rotación.
+func (r *Repository) DeleteFamily(ctx context.Context, familyID uuid.UUID) (int64, error) {
+	return r.q.DeleteSessionFamily(ctx, familyID)
+}
+
+// DeleteByUser elimina todas las sesiones de un usuario.
+func (r *Repository) DeleteByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.DeleteSessionsByUser(ctx, userID)
+}
+
+// DeleteByTenant elimina las sesiones emitidas para el tenant y las de sus usuarios.
+func (r *Repository) DeleteByTenant(ctx context.Context, tenantID uuid.UUID) (int64, error) {
+	return r.q.DeleteSessionsByTenant(ctx, tenantID)
+}
+
+// ListActiveByUser devuelve la sesión vigente de cada familia del usuario.
+func (r *Repository) ListActiveByUser(ctx context.Context, userID uuid.UUID) ([]ActiveSession, error) {
+	rows, err := r.q.ListActiveSessionsByUser(ctx, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	return &SessionModel{
-		ID:           s.ID.String(),
-		UserID:       s.UserID.String(),
-		TenantID:     s.TenantID.String(),
-		RefreshToken: s.RefreshToken,
-		UserAgent:    s.UserAgent.String,
-		ClientIP:     s.ClientIp.String,
-		ExpiresAt:    s.ExpiresAt,
-		CreatedAt:    s.CreatedAt,
-	}, nil
+	res := make([]ActiveSession, 0, len(rows))
+	for _, row := range rows {
+		res = append(res, ActiveSession{
+			ID:         row.FamilyID.String(),
+			UserAgent:  row.UserAgent.String,
+			ClientIP:   row.ClientIp.String,
+			SignedInAt: row.SignedInAt,
+			LastUsedAt: row.CreatedAt,
+			ExpiresAt:  row.ExpiresAt,
+		})
+	}
+	return res, nil
+}
+
+// DeleteUserFamily elimina una familia de rotación solo si pertenece al usuario.
+func (r *Repository) DeleteUserFamily(ctx context.Context, familyID, userID uuid.UUID) (int64, error) {
+	return r.q.DeleteUserSessionFamily(ctx, gen.DeleteUserSessionFamilyParams{
+		FamilyID: familyID,
+		UserID:   userID,
+	})
+}
+
+// GetByID busca una sesión por ID
+func (r *Repository) GetByID(ctx context.Context, id uuid.UUID) (*SessionModel, error) {
+	s, err := r.q.GetSessionByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return toModel(s), nil
 }
 
 // DeleteSession elimina una sesión por ID
@@ -55,13 +162,27 @@ func (r *Repository) DeleteSession(ctx context.Context, id uuid.UUID) error {
 	return r.q.DeleteSession(ctx, id)
 }
 
-// DeleteExpiredSessions elimina todas las sesiones expiradas
-func (r *Repository) DeleteExpiredSessions(ctx context.Context) error {
-	// TODO: Agregar esta query a internal/db/queries/sessions.sql:
-	// -- name: DeleteExpiredSessions :exec
-	// DELETE FROM sessions WHERE expires_at <= NOW();
-	// Luego ejecutar: sqlc generate
+// DeleteExpiredSessions elimina todas las sesiones expiradas (incluidas las
+// consumidas: pasada su expiración ya no sirven para detectar reuso).
+func (r *Repository) DeleteExpiredSessions(ctx context.Context) (int64, error) {
+	return r.q.DeleteExpiredSessions(ctx)
+}
 
-	// Por ahora retorna nil (sin implementación)
-	return nil
+func toModel(s gen.Session) *SessionModel {
+	m := &SessionModel{
+		ID:               s.ID.String(),
+		FamilyID:         s.FamilyID.String(),
+		UserID:           s.UserID.String(),
+		TenantID:         s.TenantID.String(),
+		RefreshTokenHash: s.RefreshToken,
+		UserAgent:        s.UserAgent.String,
+		ClientIP:         s.ClientIp.String,
+		ExpiresAt:        s.ExpiresAt,
+		CreatedAt:        s.CreatedAt,
+		ClientID:         nullUUIDToString(s.ClientID),
+	}
+	if s.ConsumedAt.Valid {
+		m.ConsumedAt = &s.ConsumedAt.Time
+	}
+	return m
 }
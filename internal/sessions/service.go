@@ -8,6 +8,8 @@ import (
 	"github.com/google/uuid"
 )
 
+var ErrSessionNotFound = errors.New("session not found")
+
 type Service struct {
 	repo *Repository
 }
@@ -44,18 +46,18 @@ func (s *Service) CreateSession(
 	expires := time.Now().UTC().Add(ttl)
 
 	// Repository call - Pasar uuid.UUID directamente
-	err = s.repo.CreateSession(ctx, sessionID, uid, tid, refreshToken, "", "", expires)
+	err = s.repo.CreateSession(ctx, sessionID, sessionID, uid, tid, uuid.Nil, refreshToken, "", "", expires)
 	if err != nil {
 		return nil, err
 	}
 
 	return &SessionModel{
-		ID:           sessionID.String(),
-		UserID:       userID,
-		TenantID:     tenantID,
-		RefreshToken: refreshToken,
-		ExpiresAt:    expires,
-		CreatedAt:    time.Now(),
+		ID:        sessionID.String(),
+		FamilyID:  sessionID.String(),
+		UserID:    userID,
+		TenantID:  tenantID,
+		ExpiresAt: expires,
+		CreatedAt: time.Now(),
 	}, nil
 }
 
@@ -63,14 +65,68 @@ func (s *Service) GetSession(ctx context.Context, refreshToken string) (*Session
 	return s.repo.GetByRefreshToken(ctx, refreshToken)
 }
 
+// GetSessionByID busca una sesión por su ID.
+func (s *Service) GetSessionByID(ctx context.Context, sessionID string) (*SessionModel, error) {
+	sid, err := uuid.Parse(sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetByID(ctx, sid)
+}
+
+// RevokeSession cierra la sesión y toda su familia de rotación, así el refresh
+// token vigente de ese login deja de funcionar.
 func (s *Service) RevokeSession(ctx context.Context, sessionID string) error {
 	sid, err := uuid.Parse(sessionID)
 	if err != nil {
 		return err
 	}
-	return s.repo.DeleteSession(ctx, sid)
+
+	session, err := s.repo.GetByID(ctx, sid)
+	if err != nil {
+		return err
+	}
+
+	familyID, err := uuid.Parse(session.FamilyID)
+	if err != nil {
+		return err
+	}
+	_, err = s.repo.DeleteFamily(ctx, familyID)
+	return err
+}
+
+// ListUserSessions devuelve los dispositivos con sesión abierta del usuario.
+func (s *Service) ListUserSessions(ctx context.Context, userID string) ([]ActiveSession, error) {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, errors.New("invalid user id")
+	}
+	return s.repo.ListActiveByUser(ctx, uid)
+}
+
+// RevokeUserSession cierra uno de los dispositivos del usuario (id de ActiveSession).
+// Si la sesión no existe o es de otro usuario devuelve ErrSessionNotFound.
+func (s *Service) RevokeUserSession(ctx context.Context, userID, sessionID string) error {
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return errors.New("invalid user id")
+	}
+	familyID, err := uuid.Parse(sessionID)
+	if err != nil {
+		return ErrSessionNotFound
+	}
+
+	n, err := s.repo.DeleteUserFamily(ctx, familyID, uid)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
 }
 
-func (s *Service) CleanupExpired(ctx context.Context) error {
+// CleanupExpired elimina las sesiones expiradas; la ejecuta el scheduler.
+func (s *Service) CleanupExpired(ctx context.Context) (int64, error) {
 	return s.repo.DeleteExpiredSessions(ctx)
 }
@@ -0,0 +1,35 @@
+package sessions
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+// Prefijo de los refresh tokens guardados como hash. Las filas sin prefijo son
+// tokens en claro de versiones anteriores (ver MigrateLegacyTokens).
+const tokenHashPrefix = "h1:"
+
+var ErrTokenKeyNotSet = errors.New("refresh token hash key is not set")
+
+// tokenKey es la llave HMAC de los refresh tokens, instalada al arrancar con UseTokenKey.
+var tokenKey []byte
+
+// UseTokenKey installs the key used to hash refresh tokens before storing or looking them up.
+func UseTokenKey(key []byte) {
+	tokenKey = key
+}
+
+// HashRefreshToken devuelve el valor que se guarda en sessions.refresh_token:
+// HMAC-SHA256 del token completo. Sin la llave, un volcado de la base de datos
+// no permite reconstruir ni probar tokens.
+func HashRefreshToken(token string) (string, error) {
+	if len(tokenKey) == 0 {
+		return "", ErrTokenKeyNotSet
+	}
+
+	mac := hmac.New(sha256.New, tokenKey)
+	mac.Write([]byte(token))
+	return tokenHashPrefix + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
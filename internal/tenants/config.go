@@ -0,0 +1,65 @@
+package tenants
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Duration lee una duración del config ("15m", "24h"). Devuelve def si la
+// clave no existe o no es válida.
+func (c TenantConfig) Duration(key string, def time.Duration) time.Duration {
+	v, ok := c[key].(string)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return def
+	}
+	return d
+}
+
+// Int lee un entero del config (los números JSON llegan como float64).
+func (c TenantConfig) Int(key string, def int) int {
+	v, ok := c[key].(float64)
+	if !ok {
+		return def
+	}
+	return int(v)
+}
+
+// Bool lee un flag del config.
+func (c TenantConfig) Bool(key string, def bool) bool {
+	v, ok := c[key].(bool)
+	if !ok {
+		return def
+	}
+	return v
+}
+
+// Strings lee una lista de textos del config; ignora los elementos que no lo son.
+func (c TenantConfig) Strings(key string) []string {
+	v, ok := c[key].([]any)
+	if !ok {
+		return nil
+	}
+	var out []string
+	for _, item := range v {
+		if s, ok := item.(string); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// GetConfig devuelve el config del tenant. El tenant System (uuid.Nil) también
+// tiene fila, así que sirve para usuarios sin tenant.
+func (s *Service) GetConfig(ctx context.Context, tenantID uuid.UUID) (TenantConfig, error) {
+	tenant, err := s.repo.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToTenantConfig(tenant.Config), nil
+}
@@ -107,6 +107,16 @@ func (r *Repository) GetTenantByKey(ctx context.Context, key string) (*gen.Tenan
 	return &tenant, nil
 }
 
+// UserBelongsToTenant indica si el usuario es del tenant o tiene un rol en él.
+func (r *Repository) UserBelongsToTenant(ctx context.Context, userID, tenantID uuid.UUID) (bool, error) {
+	return r.q.UserBelongsToTenant(ctx, gen.UserBelongsToTenantParams{UserID: userID, TenantID: tenantID})
+}
+
+// ExpireTrials suspende los tenants activos cuyo trial terminó y devuelve sus IDs.
+func (r *Repository) ExpireTrials(ctx context.Context) ([]uuid.UUID, error) {
+	return r.q.ExpireTenantTrials(ctx)
+}
+
 // UpdateTenantFullStatus actualiza status completo del tenant
 func (r *Repository) UpdateTenantFullStatus(ctx context.Context, id, status string, isActive bool, disabledAt *time.Time) error {
 	tid, err := uuid.Parse(id)
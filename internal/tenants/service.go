@@ -7,6 +7,7 @@ import (
 	"errors"
 	"time"
 
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
 	"github.com/google/uuid"
 )
 
@@ -187,3 +188,39 @@ func (s *Service) UpdateTenantStatus(ctx context.Context, id, status string) err
 		return errors.New("invalid status")
 	}
 }
+
+// ResolveTenant busca un tenant por su ID o, si ref no es un UUID, por su key.
+// Devuelve sql.ErrNoRows si no existe.
+func (s *Service) ResolveTenant(ctx context.Context, ref string) (id uuid.UUID, active bool, err error) {
+	var tenant *gen.Tenant
+	if tid, perr := uuid.Parse(ref); perr == nil {
+		tenant, err = s.repo.GetTenantByID(ctx, tid)
+	} else {
+		tenant, err = s.repo.GetTenantByKey(ctx, ref)
+	}
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return tenant.ID, tenant.IsActive, nil
+}
+
+// IsMember indica si el usuario pertenece al tenant: es su tenant o tiene un
+// rol del tenant asignado.
+func (s *Service) IsMember(ctx context.Context, userID, tenantID uuid.UUID) (bool, error) {
+	return s.repo.UserBelongsToTenant(ctx, userID, tenantID)
+}
+
+// ExpireTrials suspende los tenants cuyo trial_ends_at ya pasó; la ejecuta el scheduler.
+// Devuelve los IDs de los tenants suspendidos.
+func (s *Service) ExpireTrials(ctx context.Context) ([]string, error) {
+	ids, err := s.repo.ExpireTrials(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]string, 0, len(ids))
+	for _, id := range ids {
+		res = append(res, id.String())
+	}
+	return res, nil
+}
@@ -0,0 +1,77 @@
+package verification
+
+import (
+	"context"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+// GetVerification devuelve el estado de verificación del email.
+// Devuelve sql.ErrNoRows si no está verificado.
+func (r *Repository) GetVerification(ctx context.Context, email string) (*gen.EmailVerification, error) {
+	v, err := r.q.GetEmailVerification(ctx, email)
+	if err != nil {
+		return nil, err
+	}
+	return &v, nil
+}
+
+// MarkVerified registra el email como verificado por el usuario. Si ya lo
+// estaba se conserva la fecha original.
+func (r *Repository) MarkVerified(ctx context.Context, email string, userID uuid.UUID) error {
+	return r.q.MarkEmailVerified(ctx, gen.MarkEmailVerifiedParams{
+		Email:      email,
+		UserID:     userID,
+		VerifiedAt: time.Now(),
+	})
+}
+
+// CreateToken guarda un token de verificación. userID uuid.Nil = no se envió.
+func (r *Repository) CreateToken(ctx context.Context, tokenHash, email string, userID uuid.UUID, expiresAt time.Time) error {
+	return r.q.CreateEmailVerificationToken(ctx, gen.CreateEmailVerificationTokenParams{
+		ID:        uuid.New(),
+		TokenHash: tokenHash,
+		Email:     email,
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// CountRecentTokens cuenta los tokens pedidos para el email desde since.
+func (r *Repository) CountRecentTokens(ctx context.Context, email string, since time.Time) (int64, error) {
+	return r.q.CountRecentEmailVerificationTokens(ctx, gen.CountRecentEmailVerificationTokensParams{
+		Email:     email,
+		CreatedAt: since,
+	})
+}
+
+// ConsumeToken marca el token como usado de forma atómica.
+// Devuelve sql.ErrNoRows si no existe, ya se usó o expiró.
+func (r *Repository) ConsumeToken(ctx context.Context, tokenHash string) (*gen.EmailVerificationToken, error) {
+	token, err := r.q.ConsumeEmailVerificationToken(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// InvalidateTokens anula los tokens pendientes del email.
+func (r *Repository) InvalidateTokens(ctx context.Context, email string) (int64, error) {
+	return r.q.InvalidateEmailVerificationTokens(ctx, email)
+}
+
+// DeleteExpiredTokens elimina los tokens que expiraron antes de before.
+func (r *Repository) DeleteExpiredTokens(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteExpiredEmailVerificationTokens(ctx, before)
+}
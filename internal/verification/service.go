@@ -0,0 +1,280 @@
+package verification
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/audit"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/notify"
+	"github.com/fzalvarez/odin-iam/internal/tenants"
+	"github.com/google/uuid"
+)
+
+// Links de verificación: vida del token y límite de envíos por email (un
+// reenvío por minuto y 5 por hora, contando el del registro).
+const (
+	tokenTTL               = 24 * time.Hour
+	resendCooldown         = time.Minute
+	resendLimit            = 5
+	resendWindow           = time.Hour
+	tenantVerifiedEmailKey = "email_verification_required"
+)
+
+var (
+	ErrRateLimited  = errors.New("too many requests, try again later")
+	ErrInvalidToken = errors.New("verification token is invalid or expired")
+)
+
+type UsersRepository interface {
+	GetUserByEmail(ctx context.Context, email string) (*dbgen.User, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*dbgen.User, error)
+}
+
+type Service struct {
+	repo    *Repository
+	users   UsersRepository
+	tenants *tenants.Service
+	sender  *notify.Dispatcher
+	audit   *audit.Service
+}
+
+func NewService(
+	repo *Repository,
+	users UsersRepository,
+	tenantService *tenants.Service,
+	sender *notify.Dispatcher,
+	auditService *audit.Service,
+) *Service {
+	return &Service{
+		repo:    repo,
+		users:   users,
+		tenants: tenantService,
+		sender:  sender,
+		audit:   auditService,
+	}
+}
+
+// VerifyURL lee EMAIL_VERIFICATION_URL: la página del frontend que recibe
+// ?token= y lo canjea con POST /auth/email/verify.
+func VerifyURL() string {
+	if v := os.Getenv("EMAIL_VERIFICATION_URL"); v != "" {
+		return v
+	}
+	return auth.Issuer() + "/verify-email"
+}
+
+// SendVerification envía un link de verificación al email del usuario. La
+// llama Register; implementa auth.EmailVerifier.
+func (s *Service) SendVerification(ctx context.Context, user *dbgen.User) error {
+	return s.issue(ctx, strings.ToLower(user.Email), user)
+}
+
+// Resend reenvía el link de verificación. Responde igual exista o no la cuenta
+// y esté o no verificada; solo ErrRateLimited es visible para el cliente.
+func (s *Service) Resend(ctx context.Context, email string) error {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return errors.New("email is required")
+	}
+	key := strings.ToLower(email)
+
+	now := time.Now()
+	recent, err := s.repo.CountRecentTokens(ctx, key, now.Add(-resendCooldown))
+	if err != nil {
+		return err
+	}
+	inWindow, err := s.repo.CountRecentTokens(ctx, key, now.Add(-resendWindow))
+	if err != nil {
+		return err
+	}
+	if recent > 0 || inWindow >= resendLimit {
+		return ErrRateLimited
+	}
+
+	// Emails sin cuenta, desactivados o ya verificados se registran sin enviar nada
+	var target *dbgen.User
+	user, err := s.users.GetUserByEmail(ctx, email)
+	if err == nil && user.IsActive {
+		verified, err := s.IsVerified(ctx, user.Email)
+		if err != nil {
+			return err
+		}
+		if !verified {
+			target = user
+		}
+	}
+	return s.issue(ctx, key, target)
+}
+
+// Verify canjea el token del link y marca el email como verificado.
+func (s *Service) Verify(ctx context.Context, token string, client auth.ClientInfo) error {
+	if token == "" {
+		return ErrInvalidToken
+	}
+
+	stored, err := s.repo.ConsumeToken(ctx, hashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+
+	// Si el email del usuario cambió desde el envío, el token ya no vale
+	user, err := s.users.GetByID(ctx, stored.UserID.UUID)
+	if err != nil || !user.IsActive || strings.ToLower(user.Email) != stored.Email {
+		return ErrInvalidToken
+	}
+
+	if err := s.repo.MarkVerified(ctx, stored.Email, user.ID); err != nil {
+		return err
+	}
+	if _, err := s.repo.InvalidateTokens(ctx, stored.Email); err != nil {
+		log.Printf("⚠️  Error anulando tokens de verificación: %v", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:      audit.EventEmailVerified,
+		TenantID:  user.TenantID.String(),
+		UserID:    user.ID.String(),
+		ClientIP:  client.IP,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]any{"method": "link"},
+	})
+	return nil
+}
+
+// MarkVerified registra el email del usuario como verificado cuando otro flujo
+// ya probó que lo controla; implementa auth.EmailVerifier.
+func (s *Service) MarkVerified(ctx context.Context, user *dbgen.User) error {
+	verified, err := s.IsVerified(ctx, user.Email)
+	if err != nil || verified {
+		return err
+	}
+
+	key := strings.ToLower(user.Email)
+	if err := s.repo.MarkVerified(ctx, key, user.ID); err != nil {
+		return err
+	}
+	if _, err := s.repo.InvalidateTokens(ctx, key); err != nil {
+		log.Printf("⚠️  Error anulando tokens de verificación: %v", err)
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventEmailVerified,
+		TenantID: user.TenantID.String(),
+		UserID:   user.ID.String(),
+		Metadata: map[string]any{"method": "sign_in"},
+	})
+	return nil
+}
+
+// IsVerified indica si el email está verificado.
+func (s *Service) IsVerified(ctx context.Context, email string) (bool, error) {
+	_, err := s.repo.GetVerification(ctx, strings.ToLower(email))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckLogin aplica "email_verification_required" del tenant del usuario y del
+// tenant del token; implementa auth.EmailVerifier.
+func (s *Service) CheckLogin(ctx context.Context, user *dbgen.User, tenantID uuid.UUID) error {
+	if !s.tenantRequiresVerifiedEmail(ctx, user.TenantID) &&
+		(tenantID == user.TenantID || !s.tenantRequiresVerifiedEmail(ctx, tenantID)) {
+		return nil
+	}
+
+	verified, err := s.IsVerified(ctx, user.Email)
+	if err != nil {
+		return err
+	}
+	if !verified {
+		return auth.ErrEmailNotVerified
+	}
+	return nil
+}
+
+// PurgeExpired elimina los tokens que ya no pueden canjearse ni cuentan para
+// el límite de reenvíos; la ejecuta el scheduler.
+func (s *Service) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredTokens(ctx, time.Now().Add(-resendWindow))
+}
+
+// issue registra un token para key y, si hay destinatario, envía el link. Los
+// errores de envío solo se registran: revelarían que la cuenta existe.
+func (s *Service) issue(ctx context.Context, key string, user *dbgen.User) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+
+	userID := uuid.Nil
+	if user != nil {
+		userID = user.ID
+	}
+	if err := s.repo.CreateToken(ctx, hashToken(token), key, userID, time.Now().UTC().Add(tokenTTL)); err != nil {
+		return err
+	}
+	if user == nil {
+		return nil
+	}
+
+	link := appendQuery(VerifyURL(), url.Values{"token": {token}})
+	err = s.sender.Send(ctx, notify.Message{
+		Channel: notify.ChannelEmail,
+		To:      user.Email,
+		Subject: "Verifica tu email",
+		Body:    fmt.Sprintf("Confirma que este email es tuyo con el siguiente link. Caduca en %s:\n\n%s", tokenTTL, link),
+	})
+	if err != nil {
+		log.Printf("⚠️  Error enviando verificación de email: %v", err)
+	}
+	return nil
+}
+
+func (s *Service) tenantRequiresVerifiedEmail(ctx context.Context, tenantID uuid.UUID) bool {
+	cfg, err := s.tenants.GetConfig(ctx, tenantID)
+	if err != nil {
+		return false
+	}
+	return cfg.Bool(tenantVerifiedEmailKey, false)
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func appendQuery(rawURL string, params url.Values) string {
+	sep := "?"
+	if strings.Contains(rawURL, "?") {
+		sep = "&"
+	}
+	return rawURL + sep + params.Encode()
+}
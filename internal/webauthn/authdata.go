@@ -0,0 +1,186 @@
+package webauthn
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+)
+
+// Flags de authenticator data.
+const (
+	flagUserPresent  = 0x01
+	flagUserVerified = 0x04
+	flagAttested     = 0x40
+	flagExtensions   = 0x80
+)
+
+var (
+	errInvalidAuthData    = errors.New("invalid authenticator data")
+	errInvalidAttestation = errors.New("invalid attestation")
+)
+
+// authenticatorData es la estructura binaria que firma el autenticador
+// (https://www.w3.org/TR/webauthn-3/#sctn-authenticator-data).
+type authenticatorData struct {
+	raw       []byte
+	rpIDHash  []byte
+	flags     byte
+	signCount uint32
+
+	// Solo en registro (flag AT)
+	aaguid       []byte
+	credentialID []byte
+	publicKey    []byte
+}
+
+func (a *authenticatorData) userPresent() bool  { return a.flags&flagUserPresent != 0 }
+func (a *authenticatorData) userVerified() bool { return a.flags&flagUserVerified != 0 }
+
+// matchesRP comprueba que el autenticador firmó para nuestro RP ID.
+func (a *authenticatorData) matchesRP(rpID string) bool {
+	sum := sha256.Sum256([]byte(rpID))
+	return subtle.ConstantTimeCompare(a.rpIDHash, sum[:]) == 1
+}
+
+// signCountAdvanced aplica la regla del contador de firmas: si el autenticador
+// lo usa, cada firma debe superar a la anterior. Siempre 0 = no lo usa.
+func signCountAdvanced(stored int64, received uint32) bool {
+	return stored == 0 || int64(received) > stored
+}
+
+func parseAuthenticatorData(raw []byte) (*authenticatorData, error) {
+	if len(raw) < 37 {
+		return nil, errInvalidAuthData
+	}
+	a := &authenticatorData{
+		raw:       raw,
+		rpIDHash:  raw[:32],
+		flags:     raw[32],
+		signCount: binary.BigEndian.Uint32(raw[33:37]),
+	}
+	rest := raw[37:]
+
+	if a.flags&flagAttested != 0 {
+		if len(rest) < 18 {
+			return nil, errInvalidAuthData
+		}
+		a.aaguid = rest[:16]
+		idLen := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if idLen == 0 || idLen > 1023 || len(rest) < idLen {
+			return nil, errInvalidAuthData
+		}
+		a.credentialID = rest[:idLen]
+		rest = rest[idLen:]
+
+		// La llave COSE no lleva longitud: se decodifica para saber dónde termina
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errInvalidAuthData
+		}
+		a.publicKey = rest[:len(rest)-len(after)]
+		rest = after
+	}
+
+	if a.flags&flagExtensions != 0 {
+		_, after, err := decodeCBOR(rest)
+		if err != nil {
+			return nil, errInvalidAuthData
+		}
+		rest = after
+	}
+
+	if len(rest) != 0 {
+		return nil, errInvalidAuthData
+	}
+	return a, nil
+}
+
+func (a *authenticatorData) aaguidString() string {
+	if len(a.aaguid) == 0 {
+		return ""
+	}
+	return hex.EncodeToString(a.aaguid)
+}
+
+// parseAttestationObject decodifica el attestationObject del registro y
+// verifica su declaración. Se pide attestation "none": se aceptan "none" y
+// "packed" (self-attestation o x5c, comprobando la firma pero sin validar la
+// cadena de certificados contra un fabricante).
+func parseAttestationObject(raw, clientDataHash []byte) (*authenticatorData, error) {
+	v, rest, err := decodeCBOR(raw)
+	if err != nil || len(rest) != 0 {
+		return nil, errInvalidAttestation
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, errInvalidAttestation
+	}
+
+	format, _ := m["fmt"].(string)
+	stmt, _ := m["attStmt"].(map[any]any)
+	rawAuthData, _ := m["authData"].([]byte)
+	if stmt == nil || rawAuthData == nil {
+		return nil, errInvalidAttestation
+	}
+
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if authData.credentialID == nil {
+		return nil, errInvalidAttestation
+	}
+
+	switch format {
+	case "none":
+		if len(stmt) != 0 {
+			return nil, errInvalidAttestation
+		}
+	case "packed":
+		if err := verifyPackedAttestation(stmt, authData, clientDataHash); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errInvalidAttestation
+	}
+	return authData, nil
+}
+
+func verifyPackedAttestation(stmt map[any]any, authData *authenticatorData, clientDataHash []byte) error {
+	alg, _ := stmt["alg"].(int64)
+	sig, _ := stmt["sig"].([]byte)
+	if sig == nil {
+		return errInvalidAttestation
+	}
+	signed := append(append([]byte(nil), authData.raw...), clientDataHash...)
+
+	// Con certificado: la firma es de la llave de attestation
+	if x5c, ok := stmt["x5c"].([]any); ok {
+		if len(x5c) == 0 {
+			return errInvalidAttestation
+		}
+		der, _ := x5c[0].([]byte)
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return errInvalidAttestation
+		}
+		if verifySignature(cert.PublicKey, int(alg), signed, sig) != nil {
+			return errInvalidAttestation
+		}
+		return nil
+	}
+
+	// Self-attestation: firmada con la propia llave de la credencial
+	pub, keyAlg, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return err
+	}
+	if int(alg) != keyAlg || verifySignature(pub, keyAlg, signed, sig) != nil {
+		return errInvalidAttestation
+	}
+	return nil
+}
@@ -0,0 +1,347 @@
+package webauthn
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Los fixtures de testdata son respuestas de navigator.credentials.create() y
+// get() (credential.toJSON()) para el RP "localhost" con origen
+// http://localhost:8080. assertion.json está firmada con la passkey de
+// registration_packed.json.
+const fixtureRPID = "localhost"
+
+func loadFixture(t *testing.T, name string, v any) {
+	t.Helper()
+	raw, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+}
+
+// buildAuthData arma authenticator data para rpID con los datos de la
+// credencial (flag AT) si credentialID no es nil.
+func buildAuthData(rpID string, flags byte, signCount uint32, credentialID, publicKey []byte) []byte {
+	hash := sha256.Sum256([]byte(rpID))
+	out := append(hash[:], flags)
+	out = binary.BigEndian.AppendUint32(out, signCount)
+	if credentialID != nil {
+		out = append(out, make([]byte, 16)...) // AAGUID
+		out = binary.BigEndian.AppendUint16(out, uint16(len(credentialID)))
+		out = append(out, credentialID...)
+		out = append(out, publicKey...)
+	}
+	return out
+}
+
+func TestParseAttestationObjectFixtures(t *testing.T) {
+	for _, name := range []string{"registration_packed.json", "registration_none.json"} {
+		t.Run(name, func(t *testing.T) {
+			var cred RegistrationCredential
+			loadFixture(t, name, &cred)
+
+			var cd clientData
+			if err := json.Unmarshal(cred.Response.ClientDataJSON, &cd); err != nil || cd.Type != "webauthn.create" {
+				t.Fatalf("clientDataJSON = %s", cred.Response.ClientDataJSON)
+			}
+
+			clientDataHash := sha256.Sum256(cred.Response.ClientDataJSON)
+			authData, err := parseAttestationObject(cred.Response.AttestationObject, clientDataHash[:])
+			if err != nil {
+				t.Fatalf("parseAttestationObject: %v", err)
+			}
+			if !bytes.Equal(authData.credentialID, cred.RawID) {
+				t.Errorf("credentialID = %x, want %x", authData.credentialID, []byte(cred.RawID))
+			}
+			if !authData.matchesRP(fixtureRPID) {
+				t.Error("rpIdHash does not match localhost")
+			}
+			if !authData.userPresent() || !authData.userVerified() {
+				t.Errorf("flags = %#x, want UP and UV", authData.flags)
+			}
+			if authData.signCount != 0 {
+				t.Errorf("signCount = %d, want 0", authData.signCount)
+			}
+			if authData.aaguidString() != "00000000000000000000000000000000" {
+				t.Errorf("aaguid = %s", authData.aaguidString())
+			}
+			if _, alg, err := parseCOSEKey(authData.publicKey); err != nil || alg != algES256 {
+				t.Errorf("parseCOSEKey = %d, %v; want ES256", alg, err)
+			}
+		})
+	}
+}
+
+func TestParseAttestationObjectInvalid(t *testing.T) {
+	var packed, none RegistrationCredential
+	loadFixture(t, "registration_packed.json", &packed)
+	loadFixture(t, "registration_none.json", &none)
+	packedHash := sha256.Sum256(packed.Response.ClientDataJSON)
+	noneHash := sha256.Sum256(none.Response.ClientDataJSON)
+
+	decode := func(raw []byte) map[any]any {
+		v, _, err := decodeCBOR(raw)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return v.(map[any]any)
+	}
+	// modify devuelve el attestationObject con los cambios de fn
+	modify := func(raw []byte, fn func(m map[any]any)) []byte {
+		m := decode(raw)
+		fn(m)
+		return encodeCBOR(m)
+	}
+	packedAuthData := decode(packed.Response.AttestationObject)["authData"].([]byte)
+	packedStmt := decode(packed.Response.AttestationObject)["attStmt"].(map[any]any)
+
+	otherKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+
+	tests := []struct {
+		name string
+		raw  []byte
+		hash []byte
+	}{
+		{"packed with other client data", packed.Response.AttestationObject, noneHash[:]},
+		{"none with trailing bytes", append(append([]byte(nil), none.Response.AttestationObject...), 0x00), noneHash[:]},
+		{"truncated", packed.Response.AttestationObject[:len(packed.Response.AttestationObject)-1], packedHash[:]},
+		{"not a map", encodeCBOR([]any{"none"}), noneHash[:]},
+		{"unknown format", modify(none.Response.AttestationObject, func(m map[any]any) { m["fmt"] = "tpm" }), noneHash[:]},
+		{"missing format", modify(none.Response.AttestationObject, func(m map[any]any) { delete(m, "fmt") }), noneHash[:]},
+		{"missing authData", modify(none.Response.AttestationObject, func(m map[any]any) { delete(m, "authData") }), noneHash[:]},
+		{"missing attStmt", modify(none.Response.AttestationObject, func(m map[any]any) { delete(m, "attStmt") }), noneHash[:]},
+		{"none with statement", modify(none.Response.AttestationObject, func(m map[any]any) {
+			m["attStmt"] = map[any]any{"alg": int64(algES256)}
+		}), noneHash[:]},
+		{"packed declared as none", modify(packed.Response.AttestationObject, func(m map[any]any) { m["fmt"] = "none" }), packedHash[:]},
+		{"packed without signature", modify(packed.Response.AttestationObject, func(m map[any]any) {
+			m["attStmt"] = map[any]any{"alg": int64(algES256)}
+		}), packedHash[:]},
+		{"packed with other alg", modify(packed.Response.AttestationObject, func(m map[any]any) {
+			m["attStmt"] = with(packedStmt, 0, nil)
+			m["attStmt"].(map[any]any)["alg"] = int64(algEdDSA)
+		}), packedHash[:]},
+		{"packed with other key", modify(packed.Response.AttestationObject, func(m map[any]any) {
+			credentialID := make([]byte, 32)
+			m["authData"] = buildAuthData(fixtureRPID, 0x45, 0, credentialID, encodeCBOR(ec2Key(&otherKey.PublicKey)))
+		}), packedHash[:]},
+		{"packed with tampered authData", modify(packed.Response.AttestationObject, func(m map[any]any) {
+			tampered := append([]byte(nil), packedAuthData...)
+			tampered[33+3] = 1 // signCount
+			m["authData"] = tampered
+		}), packedHash[:]},
+		{"packed with empty x5c", modify(packed.Response.AttestationObject, func(m map[any]any) {
+			stmt := with(packedStmt, 0, nil)
+			stmt["x5c"] = []any{}
+			m["attStmt"] = stmt
+		}), packedHash[:]},
+		{"packed with invalid x5c", modify(packed.Response.AttestationObject, func(m map[any]any) {
+			stmt := with(packedStmt, 0, nil)
+			stmt["x5c"] = []any{[]byte{0x30, 0x00}}
+			m["attStmt"] = stmt
+		}), packedHash[:]},
+		{"without attested credential", modify(none.Response.AttestationObject, func(m map[any]any) {
+			m["authData"] = buildAuthData(fixtureRPID, 0x05, 0, nil, nil)
+		}), noneHash[:]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseAttestationObject(tt.raw, tt.hash); err == nil {
+				t.Error("parseAttestationObject succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestParseAuthenticatorData(t *testing.T) {
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	coseKey := encodeCBOR(ec2Key(&key.PublicKey))
+	credentialID := []byte{1, 2, 3, 4}
+	extensions := encodeCBOR(map[any]any{"credProtect": int64(2)})
+
+	tests := []struct {
+		name  string
+		raw   []byte
+		valid bool
+	}{
+		{"assertion", buildAuthData(fixtureRPID, 0x05, 7, nil, nil), true},
+		{"registration", buildAuthData(fixtureRPID, 0x45, 0, credentialID, coseKey), true},
+		{"registration with extensions", append(buildAuthData(fixtureRPID, 0xc5, 0, credentialID, coseKey), extensions...), true},
+		{"assertion with extensions", append(buildAuthData(fixtureRPID, 0x85, 1, nil, nil), extensions...), true},
+
+		{"empty", nil, false},
+		{"truncated header", buildAuthData(fixtureRPID, 0x05, 7, nil, nil)[:36], false},
+		{"trailing bytes", append(buildAuthData(fixtureRPID, 0x05, 7, nil, nil), 0x00), false},
+		{"AT without credential data", buildAuthData(fixtureRPID, 0x45, 0, nil, nil), false},
+		{"AT with truncated AAGUID", buildAuthData(fixtureRPID, 0x45, 0, credentialID, coseKey)[:37+10], false},
+		{"AT with empty credential ID", buildAuthData(fixtureRPID, 0x45, 0, []byte{}, coseKey), false},
+		{"AT with credential ID over 1023 bytes", buildAuthData(fixtureRPID, 0x45, 0, make([]byte, 1024), coseKey), false},
+		{"AT with credential ID over length", buildAuthData(fixtureRPID, 0x45, 0, credentialID, nil)[:37+18+2], false},
+		{"AT with truncated key", buildAuthData(fixtureRPID, 0x45, 0, credentialID, coseKey[:len(coseKey)-1]), false},
+		{"ED without extensions", buildAuthData(fixtureRPID, 0x85, 1, nil, nil), false},
+		{"ED with truncated extensions", append(buildAuthData(fixtureRPID, 0x85, 1, nil, nil), extensions[:len(extensions)-1]...), false},
+		{"extensions without ED", append(buildAuthData(fixtureRPID, 0x05, 1, nil, nil), extensions...), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := parseAuthenticatorData(tt.raw)
+			if !tt.valid {
+				if err == nil {
+					t.Error("parseAuthenticatorData succeeded, want error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parseAuthenticatorData: %v", err)
+			}
+			if tt.raw[32]&flagAttested != 0 {
+				if !bytes.Equal(a.credentialID, credentialID) || !bytes.Equal(a.publicKey, coseKey) {
+					t.Errorf("credential = %x / %x", a.credentialID, a.publicKey)
+				}
+			}
+		})
+	}
+}
+
+func TestAuthenticatorDataFlags(t *testing.T) {
+	tests := []struct {
+		flags        byte
+		userPresent  bool
+		userVerified bool
+	}{
+		{0x00, false, false},
+		{0x01, true, false},
+		{0x04, false, true},
+		{0x05, true, true},
+		{0x1a, false, false}, // bits reservados y BE/BS
+		{0x1d, true, true},
+	}
+	for _, tt := range tests {
+		a, err := parseAuthenticatorData(buildAuthData(fixtureRPID, tt.flags, 0, nil, nil))
+		if err != nil {
+			t.Fatalf("flags %#x: %v", tt.flags, err)
+		}
+		if a.userPresent() != tt.userPresent || a.userVerified() != tt.userVerified {
+			t.Errorf("flags %#x: UP = %v, UV = %v; want %v, %v",
+				tt.flags, a.userPresent(), a.userVerified(), tt.userPresent, tt.userVerified)
+		}
+	}
+}
+
+func TestAuthenticatorDataMatchesRP(t *testing.T) {
+	a, err := parseAuthenticatorData(buildAuthData("login.example.com", 0x05, 0, nil, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		rpID string
+		want bool
+	}{
+		{"login.example.com", true},
+		{"example.com", false},
+		{"evil.login.example.com", false},
+		{"login.example.com.", false},
+		{"LOGIN.EXAMPLE.COM", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := a.matchesRP(tt.rpID); got != tt.want {
+			t.Errorf("matchesRP(%q) = %v, want %v", tt.rpID, got, tt.want)
+		}
+	}
+}
+
+func TestSignCountAdvanced(t *testing.T) {
+	tests := []struct {
+		name     string
+		stored   int64
+		received uint32
+		want     bool
+	}{
+		{"counter not used", 0, 0, true},
+		{"first use of counter", 0, 5, true},
+		{"advanced", 5, 6, true},
+		{"advanced by more than one", 5, 100, true},
+		{"repeated", 5, 5, false},
+		{"regressed", 5, 4, false},
+		{"reset to zero", 5, 0, false},
+		{"max counter", 1<<32 - 2, 1<<32 - 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := signCountAdvanced(tt.stored, tt.received); got != tt.want {
+				t.Errorf("signCountAdvanced(%d, %d) = %v, want %v", tt.stored, tt.received, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAssertionFixture(t *testing.T) {
+	var reg RegistrationCredential
+	loadFixture(t, "registration_packed.json", &reg)
+	regHash := sha256.Sum256(reg.Response.ClientDataJSON)
+	regData, err := parseAttestationObject(reg.Response.AttestationObject, regHash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, alg, err := parseCOSEKey(regData.publicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var cred AssertionCredential
+	loadFixture(t, "assertion.json", &cred)
+	if !bytes.Equal(cred.RawID, reg.RawID) {
+		t.Fatal("assertion.json is not signed by registration_packed.json")
+	}
+
+	authData, err := parseAuthenticatorData(cred.Response.AuthenticatorData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !authData.matchesRP(fixtureRPID) || !authData.userPresent() || !authData.userVerified() {
+		t.Errorf("authData: flags %#x, matches RP %v", authData.flags, authData.matchesRP(fixtureRPID))
+	}
+	if authData.credentialID != nil {
+		t.Error("assertion must not carry attested credential data")
+	}
+	if !signCountAdvanced(int64(regData.signCount), authData.signCount) {
+		t.Errorf("signCount %d did not advance from %d", authData.signCount, regData.signCount)
+	}
+	if signCountAdvanced(int64(authData.signCount), authData.signCount) {
+		t.Error("replaying the same signCount must be rejected")
+	}
+
+	sign := func(authData, clientDataJSON []byte) []byte {
+		hash := sha256.Sum256(clientDataJSON)
+		return append(append([]byte(nil), authData...), hash[:]...)
+	}
+	if err := verifySignature(pub, alg, sign(authData.raw, cred.Response.ClientDataJSON), cred.Response.Signature); err != nil {
+		t.Errorf("verifySignature: %v", err)
+	}
+
+	tamperedClientData := append([]byte(nil), cred.Response.ClientDataJSON...)
+	tamperedClientData[len(tamperedClientData)-2] ^= 1
+	tamperedAuthData := append([]byte(nil), authData.raw...)
+	tamperedAuthData[32] |= 0x40
+	for name, signed := range map[string][]byte{
+		"client data": sign(authData.raw, tamperedClientData),
+		"auth data":   sign(tamperedAuthData, cred.Response.ClientDataJSON),
+	} {
+		if err := verifySignature(pub, alg, signed, cred.Response.Signature); !errors.Is(err, errBadSignature) {
+			t.Errorf("tampered %s: verifySignature = %v, want errBadSignature", name, err)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package webauthn
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string // hex
+		want any
+		rest string // hex
+	}{
+		{"uint inline", "00", int64(0), ""},
+		{"uint 1 byte", "1864", int64(100), ""},
+		{"uint 2 bytes", "1903e8", int64(1000), ""},
+		{"uint 8 bytes", "1b7fffffffffffffff", int64(1<<63 - 1), ""},
+		{"negative inline", "20", int64(-1), ""},
+		{"negative 1 byte", "3863", int64(-100), ""},
+		{"byte string", "43010203", []byte{1, 2, 3}, ""},
+		{"text string", "63616263", "abc", ""},
+		{"array", "83010203", []any{int64(1), int64(2), int64(3)}, ""},
+		{"map", "a201026161f5", map[any]any{int64(1): int64(2), "a": true}, ""},
+		{"false", "f4", false, ""},
+		{"null", "f6", nil, ""},
+		{"float32", "fa3fc00000", float64(1.5), ""},
+		{"float64", "fb3ff8000000000000", float64(1.5), ""},
+		{"tag is skipped", "c24101", []byte{1}, ""},
+		{"returns rest", "0102", int64(1), "02"},
+		{"nested at max depth", strings.Repeat("81", cborMaxDepth) + "00", nest(cborMaxDepth, int64(0)), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(mustHex(t, tt.in))
+			if err != nil {
+				t.Fatalf("decodeCBOR(%s): %v", tt.in, err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("decodeCBOR(%s) = %#v, want %#v", tt.in, got, tt.want)
+			}
+			if !bytes.Equal(rest, mustHex(t, tt.rest)) {
+				t.Errorf("decodeCBOR(%s) rest = %x, want %s", tt.in, rest, tt.rest)
+			}
+		})
+	}
+}
+
+func TestDecodeCBORInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string // hex
+	}{
+		// Truncados
+		{"empty", ""},
+		{"missing 1-byte argument", "18"},
+		{"missing 2-byte argument", "1901"},
+		{"missing 8-byte argument", "1b00000000"},
+		{"short byte string", "430102"},
+		{"short text string", "626a"},
+		{"short array", "830102"},
+		{"map without value", "a101"},
+		{"short float", "fb3ff8"},
+		{"tag without content", "c2"},
+
+		// Longitudes que no caben en los datos
+		{"uint over int64", "1bffffffffffffffff"},
+		{"negative over int64", "3bffffffffffffffff"},
+		{"byte string over length", "5bffffffffffffffff00"},
+		{"text string over length", "7a0000ffff00"},
+		{"array count over length", "9bffffffffffffffff00"},
+		{"map count over length", "bb00000000ffffffff0000"},
+
+		// Formas no admitidas
+		{"indefinite byte string", "5f4101ff"},
+		{"indefinite array", "9f01ff"},
+		{"reserved additional info", "1c"},
+		{"simple value with argument", "f820"},
+		{"byte string map key", "a1410101"},
+		{"array map key", "a1800101"},
+
+		// Anidamiento
+		{"nested arrays over max depth", strings.Repeat("81", cborMaxDepth+1) + "00"},
+		{"nested maps over max depth", strings.Repeat("a100", cborMaxDepth+1) + "00"},
+		{"nested tags over max depth", strings.Repeat("c0", cborMaxDepth+1) + "00"},
+		{"deeply nested arrays", strings.Repeat("81", 10000) + "00"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, _, err := decodeCBOR(mustHex(t, tt.in)); err == nil {
+				t.Errorf("decodeCBOR(%s) = %#v, want error", tt.in, got)
+			}
+		})
+	}
+}
+
+// nest envuelve v en n arrays de un elemento.
+func nest(n int, v any) any {
+	for i := 0; i < n; i++ {
+		v = []any{v}
+	}
+	return v
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatalf("bad hex %q: %v", s, err)
+	}
+	return b
+}
+
+// encodeCBOR es un codificador mínimo para armar los casos de prueba.
+func encodeCBOR(v any) []byte {
+	switch v := v.(type) {
+	case int:
+		return encodeCBOR(int64(v))
+	case int64:
+		if v >= 0 {
+			return cborHead(0, uint64(v))
+		}
+		return cborHead(1, uint64(-1-v))
+	case []byte:
+		return append(cborHead(2, uint64(len(v))), v...)
+	case string:
+		return append(cborHead(3, uint64(len(v))), v...)
+	case []any:
+		out := cborHead(4, uint64(len(v)))
+		for _, item := range v {
+			out = append(out, encodeCBOR(item)...)
+		}
+		return out
+	case map[any]any:
+		out := cborHead(5, uint64(len(v)))
+		for key, value := range v {
+			out = append(out, encodeCBOR(key)...)
+			out = append(out, encodeCBOR(value)...)
+		}
+		return out
+	case bool:
+		if v {
+			return []byte{0xf5}
+		}
+		return []byte{0xf4}
+	}
+	panic("encodeCBOR: unsupported type")
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+	return binary.BigEndian.AppendUint64([]byte{major<<5 | 27}, n)
+}
+
+func TestEncodeCBORRoundTrip(t *testing.T) {
+	in := map[any]any{
+		"fmt":      "none",
+		int64(-2):  []byte{1, 2},
+		int64(300): []any{int64(-300), "x", true},
+	}
+	got, rest, err := decodeCBOR(encodeCBOR(in))
+	if err != nil || len(rest) != 0 {
+		t.Fatalf("decodeCBOR: %v (rest %x)", err, rest)
+	}
+	if !reflect.DeepEqual(got, in) {
+		t.Errorf("round trip = %#v, want %#v", got, in)
+	}
+}
@@ -0,0 +1,70 @@
+package webauthn
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/fzalvarez/odin-iam/internal/auth"
+)
+
+// RelyingParty identifica a este servicio ante los autenticadores. Las
+// passkeys quedan ligadas al ID (un dominio); Origins son las páginas desde
+// las que el navegador puede usarlas.
+type RelyingParty struct {
+	ID      string
+	Name    string
+	Origins []string
+}
+
+// RelyingPartyFromEnv lee WEBAUTHN_RP_ID, WEBAUTHN_RP_NAME y WEBAUTHN_ORIGINS
+// (separados por comas). Por defecto usa el host y el origen de JWT_ISSUER.
+func RelyingPartyFromEnv() (RelyingParty, error) {
+	issuer, err := url.Parse(auth.Issuer())
+	if err != nil {
+		return RelyingParty{}, fmt.Errorf("invalid JWT_ISSUER: %w", err)
+	}
+
+	rp := RelyingParty{
+		ID:      os.Getenv("WEBAUTHN_RP_ID"),
+		Name:    os.Getenv("WEBAUTHN_RP_NAME"),
+		Origins: []string{issuer.Scheme + "://" + issuer.Host},
+	}
+	if rp.ID == "" {
+		rp.ID = issuer.Hostname()
+	}
+	if rp.Name == "" {
+		rp.Name = "Odin IAM"
+	}
+	if v := os.Getenv("WEBAUTHN_ORIGINS"); v != "" {
+		rp.Origins = nil
+		for _, o := range strings.Split(v, ",") {
+			if o = strings.TrimRight(strings.TrimSpace(o), "/"); o != "" {
+				rp.Origins = append(rp.Origins, o)
+			}
+		}
+	}
+
+	// El RP ID debe ser el host de cada origen o un dominio padre
+	for _, o := range rp.Origins {
+		u, err := url.Parse(o)
+		if err != nil || u.Host == "" {
+			return RelyingParty{}, fmt.Errorf("invalid WEBAUTHN_ORIGINS entry %q", o)
+		}
+		host := u.Hostname()
+		if host != rp.ID && !strings.HasSuffix(host, "."+rp.ID) {
+			return RelyingParty{}, fmt.Errorf("origin %q does not belong to WEBAUTHN_RP_ID %q", o, rp.ID)
+		}
+	}
+	return rp, nil
+}
+
+func (rp RelyingParty) allowsOrigin(origin string) bool {
+	for _, o := range rp.Origins {
+		if o == origin {
+			return true
+		}
+	}
+	return false
+}
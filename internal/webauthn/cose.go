@@ -0,0 +1,126 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+)
+
+// Algoritmos COSE admitidos (los que se anuncian en pubKeyCredParams).
+const (
+	algES256 = -7
+	algEdDSA = -8
+	algRS256 = -257
+)
+
+var supportedAlgorithms = []int{algES256, algEdDSA, algRS256}
+
+var (
+	ErrUnsupportedKey = errors.New("unsupported credential public key")
+	errBadSignature   = errors.New("invalid signature")
+)
+
+// Parámetros de las llaves COSE (RFC 9053).
+const (
+	coseKty = 1
+	coseAlg = 3
+	coseCrv = -1 // EC2/OKP: curva; RSA: n
+	coseX   = -2 // EC2/OKP: x;     RSA: e
+	coseY   = -3
+
+	ktyOKP = 1
+	ktyEC2 = 2
+	ktyRSA = 3
+
+	crvP256    = 1
+	crvEd25519 = 6
+)
+
+// parseCOSEKey convierte una llave COSE en una llave pública de Go y devuelve
+// también su algoritmo.
+func parseCOSEKey(raw []byte) (crypto.PublicKey, int, error) {
+	v, rest, err := decodeCBOR(raw)
+	if err != nil || len(rest) != 0 {
+		return nil, 0, ErrUnsupportedKey
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, 0, ErrUnsupportedKey
+	}
+
+	kty, _ := m[int64(coseKty)].(int64)
+	alg, _ := m[int64(coseAlg)].(int64)
+
+	switch {
+	case kty == ktyEC2 && alg == algES256:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		y, _ := m[int64(coseY)].([]byte)
+		if crv != crvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		// Formato sin comprimir para validar que el punto está en la curva
+		point := append([]byte{0x04}, append(x, y...)...)
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return pub, algES256, nil
+
+	case kty == ktyOKP && alg == algEdDSA:
+		crv, _ := m[int64(coseCrv)].(int64)
+		x, _ := m[int64(coseX)].([]byte)
+		if crv != crvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, 0, ErrUnsupportedKey
+		}
+		return ed25519.PublicKey(x), algEdDSA, nil
+
+	case kty == ktyRSA && alg == algRS256:
+		n, _ := m[int64(coseCrv)].([]byte)
+		e, _ := m[int64(coseX)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, 0, ErrUnsupportedKey
+		}
+		exp := new(big.Int).SetBytes(e)
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, algRS256, nil
+	}
+	return nil, 0, ErrUnsupportedKey
+}
+
+// verifySignature comprueba una firma WebAuthn (ECDSA en DER, Ed25519 o PKCS#1 v1.5).
+func verifySignature(pub crypto.PublicKey, alg int, data, sig []byte) error {
+	switch alg {
+	case algES256:
+		key, ok := pub.(*ecdsa.PublicKey)
+		if !ok {
+			return errBadSignature
+		}
+		digest := sha256.Sum256(data)
+		if !ecdsa.VerifyASN1(key, digest[:], sig) {
+			return errBadSignature
+		}
+		return nil
+	case algEdDSA:
+		key, ok := pub.(ed25519.PublicKey)
+		if !ok || !ed25519.Verify(key, data, sig) {
+			return errBadSignature
+		}
+		return nil
+	case algRS256:
+		key, ok := pub.(*rsa.PublicKey)
+		if !ok {
+			return errBadSignature
+		}
+		digest := sha256.Sum256(data)
+		if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) != nil {
+			return errBadSignature
+		}
+		return nil
+	}
+	return errBadSignature
+}
@@ -0,0 +1,192 @@
+package webauthn
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"testing"
+)
+
+func ec2Key(pub *ecdsa.PublicKey) map[any]any {
+	return map[any]any{
+		int64(coseKty): int64(ktyEC2),
+		int64(coseAlg): int64(algES256),
+		int64(coseCrv): int64(crvP256),
+		int64(coseX):   pub.X.FillBytes(make([]byte, 32)),
+		int64(coseY):   pub.Y.FillBytes(make([]byte, 32)),
+	}
+}
+
+func okpKey(pub ed25519.PublicKey) map[any]any {
+	return map[any]any{
+		int64(coseKty): int64(ktyOKP),
+		int64(coseAlg): int64(algEdDSA),
+		int64(coseCrv): int64(crvEd25519),
+		int64(coseX):   []byte(pub),
+	}
+}
+
+func rsaKey(pub *rsa.PublicKey) map[any]any {
+	return map[any]any{
+		int64(coseKty): int64(ktyRSA),
+		int64(coseAlg): int64(algRS256),
+		int64(coseCrv): pub.N.Bytes(),
+		int64(coseX):   big.NewInt(int64(pub.E)).Bytes(),
+	}
+}
+
+// with devuelve una copia de m con key = value (value nil borra la clave).
+func with(m map[any]any, key int, value any) map[any]any {
+	out := make(map[any]any, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	if value == nil {
+		delete(out, int64(key))
+	} else {
+		out[int64(key)] = value
+	}
+	return out
+}
+
+func TestParseCOSEKey(t *testing.T) {
+	ecPriv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	rsaPriv, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	tests := []struct {
+		name    string
+		key     map[any]any
+		wantAlg int
+		wantPub crypto.PublicKey
+	}{
+		{"ES256", ec2Key(&ecPriv.PublicKey), algES256, &ecPriv.PublicKey},
+		{"EdDSA", okpKey(edPub), algEdDSA, edPub},
+		{"RS256", rsaKey(&rsaPriv.PublicKey), algRS256, &rsaPriv.PublicKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pub, alg, err := parseCOSEKey(encodeCBOR(tt.key))
+			if err != nil {
+				t.Fatalf("parseCOSEKey: %v", err)
+			}
+			if alg != tt.wantAlg {
+				t.Errorf("alg = %d, want %d", alg, tt.wantAlg)
+			}
+			if !tt.wantPub.(interface{ Equal(crypto.PublicKey) bool }).Equal(pub) {
+				t.Errorf("public key does not match")
+			}
+		})
+	}
+}
+
+func TestParseCOSEKeyUnsupported(t *testing.T) {
+	ecPriv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	edPub, _, _ := ed25519.GenerateKey(rand.Reader)
+	rsaSmall, _ := rsa.GenerateKey(rand.Reader, 1024)
+	rsaPriv, _ := rsa.GenerateKey(rand.Reader, 2048)
+
+	ec := ec2Key(&ecPriv.PublicKey)
+	okp := okpKey(edPub)
+	rs := rsaKey(&rsaPriv.PublicKey)
+	offCurve := make([]byte, 32)
+	offCurve[31] = 1
+
+	tests := []struct {
+		name string
+		raw  []byte
+	}{
+		{"not cbor", []byte{0xff}},
+		{"not a map", encodeCBOR([]any{int64(1)})},
+		{"trailing bytes", append(encodeCBOR(ec), 0x00)},
+		{"missing kty", encodeCBOR(with(ec, coseKty, nil))},
+		{"missing alg", encodeCBOR(with(ec, coseAlg, nil))},
+
+		// Algoritmos no anunciados en pubKeyCredParams
+		{"ES384", encodeCBOR(with(ec, coseAlg, int64(-35)))},
+		{"PS256", encodeCBOR(with(rs, coseAlg, int64(-37)))},
+		{"RS1", encodeCBOR(with(rs, coseAlg, int64(-65535)))},
+		{"alg as text", encodeCBOR(with(ec, coseAlg, "ES256"))},
+
+		// kty y alg que no corresponden
+		{"EC2 with EdDSA", encodeCBOR(with(ec, coseAlg, int64(algEdDSA)))},
+		{"OKP with ES256", encodeCBOR(with(okp, coseAlg, int64(algES256)))},
+		{"RSA with ES256", encodeCBOR(with(rs, coseAlg, int64(algES256)))},
+
+		// Curvas no admitidas
+		{"EC2 P-384", encodeCBOR(with(ec, coseCrv, int64(2)))},
+		{"EC2 secp256k1", encodeCBOR(with(ec, coseCrv, int64(8)))},
+		{"EC2 missing crv", encodeCBOR(with(ec, coseCrv, nil))},
+		{"OKP X25519", encodeCBOR(with(okp, coseCrv, int64(4)))},
+		{"OKP Ed448", encodeCBOR(with(okp, coseCrv, int64(7)))},
+
+		// Coordenadas inválidas
+		{"EC2 short x", encodeCBOR(with(ec, coseX, make([]byte, 31)))},
+		{"EC2 missing y", encodeCBOR(with(ec, coseY, nil))},
+		{"EC2 point off curve", encodeCBOR(with(with(ec, coseX, offCurve), coseY, offCurve))},
+		{"OKP short x", encodeCBOR(with(okp, coseX, make([]byte, 31)))},
+		{"RSA 1024 bits", encodeCBOR(rsaKey(&rsaSmall.PublicKey))},
+		{"RSA missing exponent", encodeCBOR(with(rs, coseX, nil))},
+		{"RSA long exponent", encodeCBOR(with(rs, coseX, make([]byte, 5)))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := parseCOSEKey(tt.raw); !errors.Is(err, ErrUnsupportedKey) {
+				t.Errorf("parseCOSEKey = %v, want ErrUnsupportedKey", err)
+			}
+		})
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	data := []byte("authenticator data || client data hash")
+	digest := sha256.Sum256(data)
+
+	ecPriv, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	ecSig, _ := ecdsa.SignASN1(rand.Reader, ecPriv, digest[:])
+	edPub, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	edSig := ed25519.Sign(edPriv, data)
+	rsaPriv, _ := rsa.GenerateKey(rand.Reader, 2048)
+	rsaSig, _ := rsa.SignPKCS1v15(rand.Reader, rsaPriv, crypto.SHA256, digest[:])
+
+	tampered := append([]byte(nil), data...)
+	tampered[0] ^= 1
+
+	tests := []struct {
+		name  string
+		pub   crypto.PublicKey
+		alg   int
+		data  []byte
+		sig   []byte
+		valid bool
+	}{
+		{"ES256", &ecPriv.PublicKey, algES256, data, ecSig, true},
+		{"EdDSA", edPub, algEdDSA, data, edSig, true},
+		{"RS256", &rsaPriv.PublicKey, algRS256, data, rsaSig, true},
+		{"ES256 tampered data", &ecPriv.PublicKey, algES256, tampered, ecSig, false},
+		{"EdDSA tampered data", edPub, algEdDSA, tampered, edSig, false},
+		{"RS256 tampered data", &rsaPriv.PublicKey, algRS256, tampered, rsaSig, false},
+		{"ES256 empty signature", &ecPriv.PublicKey, algES256, data, nil, false},
+		{"ES256 with EdDSA key", edPub, algES256, data, ecSig, false},
+		{"EdDSA with RSA key", &rsaPriv.PublicKey, algEdDSA, data, edSig, false},
+		{"RS256 with ECDSA key", &ecPriv.PublicKey, algRS256, data, rsaSig, false},
+		{"unsupported alg", &ecPriv.PublicKey, -35, data, ecSig, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := verifySignature(tt.pub, tt.alg, tt.data, tt.sig)
+			if tt.valid && err != nil {
+				t.Errorf("verifySignature = %v, want nil", err)
+			}
+			if !tt.valid && !errors.Is(err, errBadSignature) {
+				t.Errorf("verifySignature = %v, want errBadSignature", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package webauthn
+
+import (
+	"context"
+	"time"
+
+	gen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/google/uuid"
+)
+
+type Repository struct {
+	q *gen.Queries
+}
+
+func NewRepository(db gen.DBTX) *Repository {
+	return &Repository{q: gen.New(db)}
+}
+
+func (r *Repository) CreateCredential(ctx context.Context, arg gen.CreateWebAuthnCredentialParams) error {
+	return r.q.CreateWebAuthnCredential(ctx, arg)
+}
+
+func (r *Repository) GetByCredentialID(ctx context.Context, credentialID string) (*gen.WebauthnCredential, error) {
+	c, err := r.q.GetWebAuthnCredentialByCredentialID(ctx, credentialID)
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+func (r *Repository) ListByUser(ctx context.Context, userID uuid.UUID) ([]gen.WebauthnCredential, error) {
+	return r.q.ListWebAuthnCredentialsByUser(ctx, userID)
+}
+
+func (r *Repository) CountByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.CountWebAuthnCredentialsByUser(ctx, userID)
+}
+
+// UpdateUsage guarda el contador de firmas y la fecha de uso. Devuelve false
+// si el contador no avanzó: posible autenticador clonado.
+func (r *Repository) UpdateUsage(ctx context.Context, id uuid.UUID, signCount int64) (bool, error) {
+	n, err := r.q.UpdateWebAuthnCredentialUsage(ctx, gen.UpdateWebAuthnCredentialUsageParams{
+		ID:        id,
+		SignCount: signCount,
+	})
+	return n == 1, err
+}
+
+// DeleteCredential elimina una passkey del usuario. Devuelve false si no existe o es de otro usuario.
+func (r *Repository) DeleteCredential(ctx context.Context, id, userID uuid.UUID) (bool, error) {
+	n, err := r.q.DeleteWebAuthnCredential(ctx, gen.DeleteWebAuthnCredentialParams{
+		ID:     id,
+		UserID: userID,
+	})
+	return n == 1, err
+}
+
+func (r *Repository) DeleteByUser(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return r.q.DeleteWebAuthnCredentialsByUser(ctx, userID)
+}
+
+// CreateChallenge guarda el challenge de una ceremonia. userID uuid.Nil = login sin usuario.
+func (r *Repository) CreateChallenge(ctx context.Context, challenge, purpose string, userID uuid.UUID, expiresAt time.Time) error {
+	return r.q.CreateWebAuthnChallenge(ctx, gen.CreateWebAuthnChallengeParams{
+		ID:        uuid.New(),
+		Challenge: challenge,
+		Purpose:   purpose,
+		UserID:    uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	})
+}
+
+// ConsumeChallenge elimina y devuelve el challenge vigente. Devuelve
+// sql.ErrNoRows si no existe, es de otra ceremonia o expiró.
+func (r *Repository) ConsumeChallenge(ctx context.Context, challenge, purpose string) (*gen.WebauthnChallenge, error) {
+	c, err := r.q.ConsumeWebAuthnChallenge(ctx, gen.ConsumeWebAuthnChallengeParams{
+		Challenge: challenge,
+		Purpose:   purpose,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// DeleteExpiredChallenges elimina los challenges que expiraron antes de before.
+func (r *Repository) DeleteExpiredChallenges(ctx context.Context, before time.Time) (int64, error) {
+	return r.q.DeleteExpiredWebAuthnChallenges(ctx, before)
+}
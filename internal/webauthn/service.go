@@ -0,0 +1,496 @@
+package webauthn
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/fzalvarez/odin-iam/internal/audit"
+	"github.com/fzalvarez/odin-iam/internal/auth"
+	dbgen "github.com/fzalvarez/odin-iam/internal/db/gen"
+	"github.com/fzalvarez/odin-iam/internal/mfa"
+	"github.com/google/uuid"
+)
+
+// Propósitos de las ceremonias (webauthn_challenges.purpose).
+const (
+	purposeRegistration = "registration"
+	purposeLogin        = "login"
+	purposeMFA          = "mfa"
+)
+
+const (
+	ceremonyTimeout    = 5 * time.Minute
+	maxPasskeyName     = 64
+	defaultPasskeyName = "Passkey"
+)
+
+var (
+	ErrInvalidCredential = errors.New("passkey verification failed")
+	ErrCredentialExists  = errors.New("passkey is already registered")
+	ErrPasskeyNotFound   = errors.New("passkey not found")
+	ErrNoPasskeys        = errors.New("user has no passkeys")
+)
+
+type UsersRepository interface {
+	GetByID(ctx context.Context, id uuid.UUID) (*dbgen.User, error)
+}
+
+// Passkey es una credencial WebAuthn registrada, tal como la ve su dueño.
+type Passkey struct {
+	ID         uuid.UUID  `json:"id"`
+	Name       string     `json:"name"`
+	Transports []string   `json:"transports"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+type Service struct {
+	repo  *Repository
+	rp    RelyingParty
+	auth  *auth.AuthService
+	users UsersRepository
+	mfa   *mfa.Service
+	audit *audit.Service
+}
+
+func NewService(
+	repo *Repository,
+	rp RelyingParty,
+	authService *auth.AuthService,
+	users UsersRepository,
+	mfaService *mfa.Service,
+	auditService *audit.Service,
+) *Service {
+	return &Service{
+		repo:  repo,
+		rp:    rp,
+		auth:  authService,
+		users: users,
+		mfa:   mfaService,
+		audit: auditService,
+	}
+}
+
+// ----------------------------------------------
+// REGISTRO
+// ----------------------------------------------
+
+// BeginRegistration devuelve las opciones para navigator.credentials.create().
+// Se piden passkeys (credenciales residentes) para permitir login sin email.
+func (s *Service) BeginRegistration(ctx context.Context, userID uuid.UUID) (*CreationOptions, error) {
+	user, err := s.users.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	challenge, err := s.newChallenge(ctx, purposeRegistration, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := make([]CredentialParameter, 0, len(supportedAlgorithms))
+	for _, alg := range supportedAlgorithms {
+		params = append(params, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return &CreationOptions{
+		RP: RelyingPartyEntity{ID: s.rp.ID, Name: s.rp.Name},
+		// El user handle es el UUID: no expone el email en el autenticador
+		User: UserEntity{
+			ID:          user.ID[:],
+			Name:        user.Email,
+			DisplayName: user.DisplayName,
+		},
+		Challenge:          challenge,
+		PubKeyCredParams:   params,
+		Timeout:            int(ceremonyTimeout.Milliseconds()),
+		ExcludeCredentials: descriptors(existing),
+		AuthenticatorSelection: AuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "preferred",
+		},
+		Attestation: "none",
+	}, nil
+}
+
+// FinishRegistration verifica la respuesta del autenticador y guarda la
+// llave pública. Si es el primer segundo factor del usuario devuelve también
+// sus códigos de recuperación (solo esta vez).
+func (s *Service) FinishRegistration(ctx context.Context, userID uuid.UUID, name string, cred RegistrationCredential) (*Passkey, []string, error) {
+	ceremony, err := s.verifyClientData(ctx, cred.Response.ClientDataJSON, "webauthn.create", purposeRegistration)
+	if err != nil {
+		return nil, nil, err
+	}
+	if ceremony.UserID.UUID != userID {
+		return nil, nil, ErrInvalidCredential
+	}
+
+	clientDataHash := sha256.Sum256(cred.Response.ClientDataJSON)
+	authData, err := parseAttestationObject(cred.Response.AttestationObject, clientDataHash[:])
+	if err != nil {
+		return nil, nil, ErrInvalidCredential
+	}
+	if !authData.matchesRP(s.rp.ID) || !authData.userPresent() {
+		return nil, nil, ErrInvalidCredential
+	}
+	if len(cred.RawID) > 0 && !bytes.Equal(cred.RawID, authData.credentialID) {
+		return nil, nil, ErrInvalidCredential
+	}
+
+	_, alg, err := parseCOSEKey(authData.publicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	credentialID := Base64URL(authData.credentialID).String()
+	if _, err := s.repo.GetByCredentialID(ctx, credentialID); err == nil {
+		return nil, nil, ErrCredentialExists
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return nil, nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = defaultPasskeyName
+	}
+	if len(name) > maxPasskeyName {
+		name = name[:maxPasskeyName]
+	}
+
+	now := time.Now()
+	row := dbgen.CreateWebAuthnCredentialParams{
+		ID:           uuid.New(),
+		UserID:       userID,
+		CredentialID: credentialID,
+		PublicKey:    authData.publicKey,
+		Algorithm:    int32(alg),
+		SignCount:    int64(authData.signCount),
+		Transports:   strings.Join(cred.Response.Transports, ","),
+		Aaguid:       authData.aaguidString(),
+		Name:         name,
+		CreatedAt:    now,
+	}
+	if err := s.repo.CreateCredential(ctx, row); err != nil {
+		return nil, nil, err
+	}
+
+	// La passkey también es segundo factor: sin códigos de recuperación,
+	// perderla dejaría la cuenta bloqueada
+	codes, err := s.mfa.EnsureRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventPasskeyRegistered,
+		UserID:   userID.String(),
+		Metadata: map[string]any{"passkey_id": row.ID.String(), "aaguid": row.Aaguid},
+	})
+
+	return &Passkey{
+		ID:         row.ID,
+		Name:       row.Name,
+		Transports: cred.Response.Transports,
+		CreatedAt:  now,
+	}, codes, nil
+}
+
+// ListPasskeys devuelve las passkeys del usuario.
+func (s *Service) ListPasskeys(ctx context.Context, userID uuid.UUID) ([]Passkey, error) {
+	rows, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	list := make([]Passkey, 0, len(rows))
+	for _, row := range rows {
+		p := Passkey{
+			ID:         row.ID,
+			Name:       row.Name,
+			Transports: splitTransports(row.Transports),
+			CreatedAt:  row.CreatedAt,
+		}
+		if row.LastUsedAt.Valid {
+			p.LastUsedAt = &row.LastUsedAt.Time
+		}
+		list = append(list, p)
+	}
+	return list, nil
+}
+
+// DeletePasskey elimina una passkey del usuario.
+func (s *Service) DeletePasskey(ctx context.Context, userID uuid.UUID, id string) error {
+	pid, err := uuid.Parse(id)
+	if err != nil {
+		return ErrPasskeyNotFound
+	}
+
+	deleted, err := s.repo.DeleteCredential(ctx, pid, userID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return ErrPasskeyNotFound
+	}
+
+	s.audit.Record(ctx, audit.Event{
+		Type:     audit.EventPasskeyDeleted,
+		UserID:   userID.String(),
+		Metadata: map[string]any{"passkey_id": id},
+	})
+	return nil
+}
+
+// ----------------------------------------------
+// LOGIN CON PASSKEY
+// ----------------------------------------------
+
+// BeginLogin devuelve las opciones para navigator.credentials.get() sin
+// usuario previo: el navegador ofrece las passkeys del dominio.
+func (s *Service) BeginLogin(ctx context.Context) (*RequestOptions, error) {
+	challenge, err := s.newChallenge(ctx, purposeLogin, uuid.Nil)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		RPID:             s.rp.ID,
+		Timeout:          int(ceremonyTimeout.Milliseconds()),
+		AllowCredentials: []CredentialDescriptor{},
+		UserVerification: "required",
+	}, nil
+}
+
+// FinishLogin verifica la firma de la passkey y emite los mismos tokens que
+// Login. Exige verificación de usuario (PIN o biometría): la passkey ya es
+// multifactor y no se pide un segundo factor aparte.
+func (s *Service) FinishLogin(ctx context.Context, cred AssertionCredential, client auth.ClientInfo) (*auth.LoginResult, error) {
+	if _, err := s.verifyClientData(ctx, cred.Response.ClientDataJSON, "webauthn.get", purposeLogin); err != nil {
+		return nil, err
+	}
+
+	user, err := s.verifyAssertion(ctx, cred, uuid.Nil, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.auth.CheckEmailVerified(ctx, user, user.TenantID); err != nil {
+		return nil, err
+	}
+
+	// Tenant del usuario, como Login sin tenant; se cambia con /auth/switch-tenant
+	return s.auth.IssueTokens(ctx, user.ID, user.TenantID, client)
+}
+
+// ----------------------------------------------
+// PASSKEY COMO SEGUNDO FACTOR
+// ----------------------------------------------
+
+// BeginMFA devuelve las opciones para firmar con una de las passkeys del
+// usuario del desafío MFA.
+func (s *Service) BeginMFA(ctx context.Context, mfaToken string) (*RequestOptions, error) {
+	userID, err := s.mfa.ChallengeUserID(ctx, mfaToken)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) == 0 {
+		return nil, ErrNoPasskeys
+	}
+
+	challenge, err := s.newChallenge(ctx, purposeMFA, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &RequestOptions{
+		Challenge:        challenge,
+		RPID:             s.rp.ID,
+		Timeout:          int(ceremonyTimeout.Milliseconds()),
+		AllowCredentials: descriptors(existing),
+		UserVerification: "preferred",
+	}, nil
+}
+
+// FinishMFA completa el desafío MFA con la firma de una passkey del usuario.
+func (s *Service) FinishMFA(ctx context.Context, mfaToken string, cred AssertionCredential, client auth.ClientInfo) (*auth.LoginResult, error) {
+	return s.mfa.VerifyChallengeWith(ctx, mfaToken, func(ctx context.Context, userID uuid.UUID) error {
+		ceremony, err := s.verifyClientData(ctx, cred.Response.ClientDataJSON, "webauthn.get", purposeMFA)
+		if err != nil {
+			return err
+		}
+		if ceremony.UserID.UUID != userID {
+			return ErrInvalidCredential
+		}
+		_, err = s.verifyAssertion(ctx, cred, userID, false)
+		return err
+	}, client)
+}
+
+// ----------------------------------------------
+// mfa.Passkeys
+// ----------------------------------------------
+
+func (s *Service) HasPasskeys(ctx context.Context, userID uuid.UUID) (bool, error) {
+	n, err := s.repo.CountByUser(ctx, userID)
+	return n > 0, err
+}
+
+func (s *Service) DeleteUserPasskeys(ctx context.Context, userID uuid.UUID) (int64, error) {
+	return s.repo.DeleteByUser(ctx, userID)
+}
+
+// PurgeExpired elimina los challenges de ceremonias abandonadas; la ejecuta el scheduler.
+func (s *Service) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.repo.DeleteExpiredChallenges(ctx, time.Now())
+}
+
+// ----------------------------------------------
+// HELPERS
+// ----------------------------------------------
+
+func (s *Service) newChallenge(ctx context.Context, purpose string, userID uuid.UUID) (Base64URL, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, err
+	}
+	expires := time.Now().UTC().Add(ceremonyTimeout)
+	if err := s.repo.CreateChallenge(ctx, Base64URL(challenge).String(), purpose, userID, expires); err != nil {
+		return nil, err
+	}
+	return challenge, nil
+}
+
+// verifyClientData comprueba tipo y origen del clientDataJSON y consume el
+// challenge: cada ceremonia se puede terminar una sola vez.
+func (s *Service) verifyClientData(ctx context.Context, raw []byte, ceremonyType, purpose string) (*dbgen.WebauthnChallenge, error) {
+	var cd clientData
+	if err := json.Unmarshal(raw, &cd); err != nil {
+		return nil, ErrInvalidCredential
+	}
+	if cd.Type != ceremonyType || cd.CrossOrigin || !s.rp.allowsOrigin(cd.Origin) {
+		return nil, ErrInvalidCredential
+	}
+
+	// Se normaliza por si el navegador añade padding
+	challenge, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(cd.Challenge, "="))
+	if err != nil {
+		return nil, ErrInvalidCredential
+	}
+
+	ceremony, err := s.repo.ConsumeChallenge(ctx, Base64URL(challenge).String(), purpose)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCredential
+		}
+		return nil, err
+	}
+	return ceremony, nil
+}
+
+// verifyAssertion verifica la firma de navigator.credentials.get() con la
+// llave guardada. expectedUser uuid.Nil acepta cualquier usuario (login).
+func (s *Service) verifyAssertion(ctx context.Context, cred AssertionCredential, expectedUser uuid.UUID, requireUV bool) (*dbgen.User, error) {
+	rawID := cred.RawID
+	if len(rawID) == 0 {
+		// Algunos clientes solo envían id (mismo valor en base64url)
+		rawID, _ = base64.RawURLEncoding.DecodeString(cred.ID)
+	}
+	stored, err := s.repo.GetByCredentialID(ctx, rawID.String())
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidCredential
+		}
+		return nil, err
+	}
+	if expectedUser != uuid.Nil && stored.UserID != expectedUser {
+		return nil, ErrInvalidCredential
+	}
+	if len(cred.Response.UserHandle) > 0 && !bytes.Equal(cred.Response.UserHandle, stored.UserID[:]) {
+		return nil, ErrInvalidCredential
+	}
+
+	authData, err := parseAuthenticatorData(cred.Response.AuthenticatorData)
+	if err != nil {
+		return nil, ErrInvalidCredential
+	}
+	if !authData.matchesRP(s.rp.ID) || !authData.userPresent() {
+		return nil, ErrInvalidCredential
+	}
+	if requireUV && !authData.userVerified() {
+		return nil, ErrInvalidCredential
+	}
+
+	pub, alg, err := parseCOSEKey(stored.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	clientDataHash := sha256.Sum256(cred.Response.ClientDataJSON)
+	signed := append(append([]byte(nil), authData.raw...), clientDataHash[:]...)
+	if err := verifySignature(pub, alg, signed, cred.Response.Signature); err != nil {
+		return nil, ErrInvalidCredential
+	}
+
+	// El contador debe avanzar (o ser siempre 0 si el autenticador no lo usa).
+	// UpdateUsage lo vuelve a comprobar en la base de datos contra firmas concurrentes.
+	advanced := signCountAdvanced(stored.SignCount, authData.signCount)
+	if advanced {
+		if advanced, err = s.repo.UpdateUsage(ctx, stored.ID, int64(authData.signCount)); err != nil {
+			return nil, err
+		}
+	}
+	if !advanced {
+		s.audit.Alert(ctx, audit.Event{
+			Type:     audit.EventPasskeyCloned,
+			UserID:   stored.UserID.String(),
+			Metadata: map[string]any{"passkey_id": stored.ID.String(), "sign_count": authData.signCount},
+		})
+		return nil, ErrInvalidCredential
+	}
+
+	user, err := s.users.GetByID(ctx, stored.UserID)
+	if err != nil || !user.IsActive {
+		return nil, ErrInvalidCredential
+	}
+	return user, nil
+}
+
+func descriptors(rows []dbgen.WebauthnCredential) []CredentialDescriptor {
+	list := make([]CredentialDescriptor, 0, len(rows))
+	for _, row := range rows {
+		id, err := base64.RawURLEncoding.DecodeString(row.CredentialID)
+		if err != nil {
+			continue
+		}
+		list = append(list, CredentialDescriptor{
+			Type:       "public-key",
+			ID:         id,
+			Transports: splitTransports(row.Transports),
+		})
+	}
+	return list
+}
+
+func splitTransports(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}